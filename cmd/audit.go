@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/bmc-toolbox/bmcbutler/pkg/audit"
+)
+
+var (
+	auditSerial string
+	auditFile   string
+	auditSince  time.Duration
+	auditFormat string
+)
+
+// auditCmd represents the audit command
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Query the audit journal of changes applied to BMCs.",
+}
+
+// auditShowCmd represents the audit show command
+var auditShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show the changes applied to a BMC, oldest first.",
+	Run: func(cmd *cobra.Command, args []string) {
+		auditShow()
+	},
+}
+
+func init() {
+	auditShowCmd.Flags().StringVarP(&auditSerial, "serial", "", "", "Serial of the asset to show changes for.")
+	auditShowCmd.Flags().StringVarP(&auditFile, "file", "", "", "Audit journal file (default: audit.file in bmcbutler.yml).")
+	auditShowCmd.Flags().DurationVarP(&auditSince, "since", "", 0, "Only show changes applied within this duration (e.g 168h).")
+	auditShowCmd.Flags().StringVarP(&auditFormat, "format", "f", "table", "Output format (table/json).")
+
+	auditCmd.AddCommand(auditShowCmd)
+	rootCmd.AddCommand(auditCmd)
+}
+
+func auditShow() {
+	if auditSerial == "" {
+		log.Error("Expected flag missing --serial (try --help)")
+		os.Exit(1)
+	}
+
+	switch auditFormat {
+	case "table", "json":
+	default:
+		log.Error("Unknown output format: ", auditFormat, " (expected table/json)")
+		os.Exit(1)
+	}
+
+	if auditFile == "" {
+		overrideConfigFromFlags()
+		runConfig.Load(runConfig.CfgFile)
+
+		if runConfig.Audit == nil {
+			log.Error("No audit journal declared in bmcbutler.yml, expected --file.")
+			os.Exit(1)
+		}
+
+		auditFile = runConfig.Audit.File
+	}
+
+	var since time.Time
+	if auditSince > 0 {
+		since = time.Now().Add(-auditSince)
+	}
+
+	entries, err := audit.Read(auditFile, func(e audit.Entry) bool {
+		return strings.EqualFold(e.Serial, auditSerial) && !e.Time.Before(since)
+	})
+	if err != nil {
+		log.Error("Unable to read audit journal: ", err)
+		os.Exit(1)
+	}
+
+	if auditFormat == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		for _, e := range entries {
+			err = encoder.Encode(e)
+			if err != nil {
+				log.Error("Unable to write audit entries: ", err)
+				os.Exit(1)
+			}
+		}
+		return
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(writer, "TIME\tOPERATOR\tHOSTNAME\tACTION\tIP ADDRESS\tCHANGE\tOUTCOME\tCONFIG\tERROR")
+	for _, e := range entries {
+		change := e.Resource
+		if e.Command != "" {
+			change = e.Command
+		}
+
+		hash := e.ConfigHash
+		if len(hash) > 12 {
+			hash = hash[:12]
+		}
+
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			e.Time.Format(time.RFC3339), e.Operator, e.Hostname, e.Action, e.IPAddress, change, e.Outcome, hash, e.Error)
+	}
+
+	err = writer.Flush()
+	if err != nil {
+		log.Error("Unable to write audit entries: ", err)
+		os.Exit(1)
+	}
+}
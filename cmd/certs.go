@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/bmc-toolbox/bmcbutler/pkg/asset"
+	"github.com/bmc-toolbox/bmcbutler/pkg/butler"
+	"github.com/bmc-toolbox/bmcbutler/pkg/resource"
+)
+
+var (
+	certsFormat      string
+	certsRenewBefore time.Duration
+)
+
+// certsCmd represents the certs command
+var certsCmd = &cobra.Command{
+	Use:   "certs",
+	Short: "Report or renew BMC HTTPS certs.",
+}
+
+// certsReportCmd represents the certs report command
+var certsReportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Report the current HTTPS cert of BMCs, and why it fails validation.",
+	Run: func(cmd *cobra.Command, args []string) {
+		certs(false)
+	},
+}
+
+// certsRenewCmd represents the certs renew command
+var certsRenewCmd = &cobra.Command{
+	Use:   "renew",
+	Short: "Renew BMC HTTPS certs that fail validation or expire within the renew window.",
+	Run: func(cmd *cobra.Command, args []string) {
+		certs(true)
+	},
+}
+
+func init() {
+	certsCmd.PersistentFlags().StringVarP(&certsFormat, "format", "f", "json", "Output format (json/csv).")
+	certsCmd.PersistentFlags().DurationVarP(&certsRenewBefore, "renew-before", "", 0, "Certs expiring within this duration fail validation (default: renewBeforeExpiry in configuration.yml, or 720h).")
+
+	certsCmd.AddCommand(certsReportCmd)
+	certsCmd.AddCommand(certsRenewCmd)
+	rootCmd.AddCommand(certsCmd)
+}
+
+func certs(renew bool) {
+	switch certsFormat {
+	case "json", "csv":
+	default:
+		fmt.Printf("Unknown output format: %s (expected json/csv)\n", certsFormat)
+		os.Exit(1)
+	}
+
+	validateConfigureArgs()
+
+	runConfig.CertsFormat = certsFormat
+	runConfig.CertsRenew = renew
+	runConfig.CertsRenewBefore = certsRenewBefore
+
+	// The report is written to stdout, keep logs out of the way.
+	log.Out = os.Stderr
+
+	inventoryChan, butlerChan, stopChan := prepareChannels(nil)
+
+	// The httpsCert configuration is read from configuration.yml and its overlays.
+	assetConfig, err := resource.ReadLayers(runConfig.BmcCfgDir)
+	if err != nil {
+		log.Fatal("Unable to read BMC configuration, Error: ", err)
+	}
+
+	err = butler.WriteCertReportHeader(os.Stdout, runConfig.CertsFormat)
+	if err != nil {
+		log.Fatal("Unable to write report header: ", err)
+	}
+
+	dispatchAssets(inventoryChan, butlerChan, stopChan, nil, asset.ActionCerts, butler.Msg{AssetConfig: assetConfig})
+
+	post(butlerChan)
+}
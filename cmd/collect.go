@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/bmc-toolbox/bmcbutler/pkg/asset"
+	"github.com/bmc-toolbox/bmcbutler/pkg/butler"
+)
+
+var collectDir string
+
+// collectCmd represents the collect command
+var collectCmd = &cobra.Command{
+	Use:   "collect",
+	Short: "Collect hardware facts from BMCs.",
+	Run: func(cmd *cobra.Command, args []string) {
+		collect()
+	},
+}
+
+func init() {
+	collectCmd.Flags().StringVarP(&collectDir, "output-dir", "o", "", "Write one JSON document per asset to this directory (default: JSON lines on stdout).")
+
+	rootCmd.AddCommand(collectCmd)
+}
+
+func collect() {
+	validateConfigureArgs()
+
+	runConfig.CollectDir = collectDir
+
+	if runConfig.CollectDir != "" {
+		err := os.MkdirAll(runConfig.CollectDir, 0755)
+		if err != nil {
+			log.Error("Unable to create output directory (", runConfig.CollectDir, "), Error: ", err)
+			os.Exit(1)
+		}
+	} else {
+		// Facts are written to stdout, keep logs out of the way.
+		log.Out = os.Stderr
+	}
+
+	inventoryChan, butlerChan, stopChan := prepareChannels(nil)
+
+	dispatchAssets(inventoryChan, butlerChan, stopChan, nil, asset.ActionCollect, butler.Msg{})
+
+	post(butlerChan)
+}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
@@ -8,26 +9,231 @@ import (
 	"sync"
 	"syscall"
 
+	"github.com/spf13/cobra"
+
 	"github.com/bmc-toolbox/bmcbutler/pkg/asset"
+	"github.com/bmc-toolbox/bmcbutler/pkg/audit"
 	"github.com/bmc-toolbox/bmcbutler/pkg/butler"
+	"github.com/bmc-toolbox/bmcbutler/pkg/checkpoint"
+	"github.com/bmc-toolbox/bmcbutler/pkg/config"
 	"github.com/bmc-toolbox/bmcbutler/pkg/inventory"
+	"github.com/bmc-toolbox/bmcbutler/pkg/metrics"
+	"github.com/bmc-toolbox/bmcbutler/pkg/notify"
+	"github.com/bmc-toolbox/bmcbutler/pkg/report"
 	"github.com/bmc-toolbox/bmcbutler/pkg/secrets"
-	metrics "github.com/bmc-toolbox/gin-go-metrics"
+	"github.com/bmc-toolbox/bmcbutler/pkg/tracing"
 )
 
 var (
 	butlers   *butler.Butler
 	commandWG sync.WaitGroup
 	interrupt bool
+	runSpan   *tracing.Span // The root span of the run, nil unless tracing is declared.
+
+	reportFile   string
+	reportFormat string
+	runLimits    report.Limits
+
+	checkpointFile string
+	resumeFile     string
+	retryFailed    bool
 )
 
 // post handles clean up actions
 // - closes the butler channel
 // - Waits for all go routines in commandWG to finish.
+// - Closes the audit journal, waits for notifications to be sent.
 func post(butlerChan chan butler.Msg) {
 	close(butlerChan)
 	commandWG.Wait()
+	closeSinks()
+	metrics.Close(true)
+}
+
+// addRunFlags adds the run report, failure limit and checkpoint flags to the command.
+func addRunFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&checkpointFile, "checkpoint", "", "", "Record completed, failed and pending assets to this file as the run progresses.")
+	cmd.Flags().StringVarP(&resumeFile, "resume", "", "", "Resume the run recorded in this checkpoint file, skipping assets done, the file is updated as the run progresses.")
+	cmd.Flags().BoolVarP(&retryFailed, "retry-failed", "", false, "With --resume, dispatch assets that failed again.")
+	cmd.Flags().StringVarP(&reportFile, "report", "", "", "Write a report of the run with the outcome for each asset to this file.")
+	cmd.Flags().StringVarP(&reportFormat, "report-format", "", "json", "Run report format (json/junit).")
+	cmd.Flags().IntVarP(&runLimits.MaxFailures, "max-failures", "", 0, "Stop dispatching assets once more than this number of assets failed.")
+	cmd.Flags().Float64VarP(&runLimits.MaxFailureRatio, "max-failure-ratio", "", 0, "Stop dispatching assets once the ratio of failed assets is above this (e.g 0.1), enforced after 10 assets.")
+}
+
+// validateRunArgs exits if the run report format or the failure limits are invalid.
+func validateRunArgs() {
+	switch reportFormat {
+	case report.FormatJSON, report.FormatJUnit:
+	default:
+		log.Error("Unknown report format: ", reportFormat, " (expected json/junit)")
+		os.Exit(1)
+	}
+
+	if runLimits.MaxFailures < 0 || runLimits.MaxFailureRatio < 0 || runLimits.MaxFailureRatio > 1 {
+		log.Error("Expected --max-failures >= 0, --max-failure-ratio between 0 and 1.")
+		os.Exit(1)
+	}
+
+	if checkpointFile != "" && resumeFile != "" {
+		log.Error("--checkpoint --resume are mutually exclusive args.")
+		os.Exit(1)
+	}
+
+	if retryFailed && resumeFile == "" {
+		log.Error("--retry-failed requires --resume.")
+		os.Exit(1)
+	}
+}
+
+// loadCheckpoint returns the checkpoint declared with --checkpoint, or loaded from the file declared with --resume,
+// nil if neither was declared, exits if the checkpoint file can't be loaded,
+// or was written by a run of another action, command or filters.
+func loadCheckpoint(action string) *checkpoint.Checkpoint {
+	run := checkpointRun(action)
+
+	switch {
+	case checkpointFile != "":
+		return checkpoint.New(checkpointFile, run, log)
+	case resumeFile != "":
+		c, err := checkpoint.Load(resumeFile, run, log)
+		if err != nil {
+			log.Error("Unable to load checkpoint: ", err)
+			os.Exit(1)
+		}
+
+		c.RetryFailed = retryFailed
+		counts := c.Counts()
+		log.Info(fmt.Sprintf("Resuming run, %d assets done, %d failed, %d pending.",
+			counts[checkpoint.StatusDone], counts[checkpoint.StatusFailed], counts[checkpoint.StatusPending]))
+
+		return c
+	default:
+		return nil
+	}
+}
+
+// checkpointRun returns the run recorded in checkpoints, the action with the command and filters passed on the command line.
+func checkpointRun(action string) checkpoint.Run {
+	run := checkpoint.Run{
+		Action:  action,
+		All:     runConfig.FilterParams.All,
+		Servers: runConfig.FilterParams.Servers,
+		Chassis: runConfig.FilterParams.Chassis,
+		Serials: runConfig.FilterParams.Serials,
+		IPs:     runConfig.FilterParams.Ips,
+	}
+
+	if action == asset.ActionExecute {
+		run.Command = execCommand
+	}
+
+	if resources != "" {
+		run.Resources = strings.Split(resources, ",")
+	}
+
+	if locations != "" {
+		run.Locations = strings.Split(locations, ",")
+	}
+
+	return run
+}
+
+// finishRun waits for the butlers to be done, prints the run summary and sends the summary notification,
+// writes the run report to the file declared with --report, and the run checkpoint,
+// and exits with the run exit code.
+func finishRun(butlerChan chan butler.Msg, resultChan chan butler.Result, collector *report.Collector) {
+	close(butlerChan)
+	commandWG.Wait()
+	close(resultChan)
+
+	run := collector.Wait()
+
+	butlers.Notifier.Notify(runSummary(run))
+	endRunSpan(run)
+	closeSinks()
 	metrics.Close(true)
+
+	err := run.WriteSummary(os.Stdout)
+	if err != nil {
+		log.Error("Unable to write run summary: ", err)
+	}
+
+	if c := collector.Checkpoint(); c != nil {
+		err = c.Save()
+		if err != nil {
+			log.Error("Unable to write checkpoint (", c.File, "): ", err)
+			os.Exit(1)
+		}
+	}
+
+	if reportFile != "" {
+		err = run.WriteFile(reportFile, reportFormat)
+		if err != nil {
+			log.Error("Unable to write run report (", reportFile, "): ", err)
+			os.Exit(1)
+		}
+	}
+
+	os.Exit(run.ExitCode(interrupt))
+}
+
+// dispatchAssets sends the msg to butlers for each asset received over the inventory channel,
+// with the asset action set, until the inventory is drained, an interrupt is received,
+// or the run is halted if a collector is declared,
+// assets the run checkpoint lists as done are not dispatched.
+// Returns the number of assets dispatched.
+func dispatchAssets(inventoryChan <-chan []asset.Asset, butlerChan chan<- butler.Msg, stopChan <-chan struct{}, collector *report.Collector, action string, msg butler.Msg) (dispatched int) {
+	var haltChan <-chan struct{}
+	var runCheckpoint *checkpoint.Checkpoint
+	if collector != nil {
+		haltChan = collector.Halt()
+		runCheckpoint = collector.Checkpoint()
+	}
+
+	var resumed int
+	defer func() {
+		if resumed > 0 {
+			log.Info(fmt.Sprintf("%d assets recorded in the checkpoint were not dispatched again.", resumed))
+		}
+	}()
+
+	for {
+		select {
+		case assetList, ok := <-inventoryChan:
+			if !ok {
+				return
+			}
+			for _, a := range assetList {
+				if interrupt {
+					return
+				}
+
+				select {
+				case <-haltChan:
+					log.Warn("Run halted, no new assets will be dispatched.")
+					return
+				default:
+				}
+
+				if runCheckpoint != nil {
+					if runCheckpoint.Skip(a.Key()) {
+						resumed++
+						continue
+					}
+
+					runCheckpoint.Dispatched(a.Key())
+				}
+
+				a.Action = action
+				msg.Asset = a
+				butlerChan <- msg
+				dispatched++
+			}
+		case <-stopChan:
+			interrupt = true
+		}
+	}
 }
 
 // Any flags to override configuration goes here.
@@ -51,89 +257,220 @@ func overrideConfigFromFlags() {
 	}
 }
 
-// Sets up required plumbing and returns three channels.
-// - Spawn a Go routine to listen to interrupt signals
-// - Setup metrics channel
-// - Spawn the metrics forwarder Go routine
-// - Setup the inventory channel over which to receive assets
-// - Based on the inventory source (dora/csv), spawn the asset retriever Go routine
-// - Spawn butlers
-// - Return inventory channel, butler channel
-func prepareChannels() (inventoryChan chan []asset.Asset, butlerChan chan butler.Msg, stopChan chan struct{}) {
-	overrideConfigFromFlags()
-	runConfig.Load(runConfig.CfgFile)
-
-	// Used to indicate Go routines to exit.
-	stopChan = make(chan struct{})
-
-	err := metrics.Setup(
-		runConfig.Metrics.Client,
-		runConfig.Metrics.Graphite.Host,
-		runConfig.Metrics.Graphite.Port,
-		runConfig.Metrics.Graphite.Prefix,
-		runConfig.Metrics.Graphite.FlushInterval,
-	)
+// setupMetrics sets up the metrics emitter declared in the configuration.
+func setupMetrics() {
+	err := metrics.Setup(runConfig.Metrics)
 	if err != nil {
 		fmt.Printf("Failed to set up monitoring: %s", err)
 		os.Exit(1)
 	}
+}
 
-	// A channel to receive inventory assets.
-	inventoryChan = make(chan []asset.Asset, 5)
+// loadSecrets loads secrets from vault if declared in the configuration,
+// and resolves any credentials, signer token declared as lookup_secret::.
+func loadSecrets() *secrets.Store {
+	if !runConfig.SecretsFromVault {
+		return nil
+	}
 
-	// Determine inventory to fetch asset data.
-	inventorySource := runConfig.Inventory.Source
+	store, err := secrets.Load(*runConfig.Vault)
+	if err != nil {
+		log.Fatalf("[Error] loading secrets from vault: %s", err.Error())
+	}
+
+	runConfig.Credentials, err = store.SetCredentials(runConfig.Credentials)
+	if err != nil {
+		log.Fatalf("[Error] loading secrets from vault: %s", err.Error())
+	}
+
+	if runConfig.CertSigner != nil && runConfig.CertSigner.LemurSigner != nil {
+		runConfig.CertSigner.LemurSigner.Key, err = store.GetSignerToken(runConfig.CertSigner.LemurSigner.Key)
+		if err != nil {
+			log.Fatalf("[Error] loading secrets from vault: %s", err.Error())
+		}
+	}
+
+	return store
+}
+
+// openJournal opens the audit journal declared in the configuration, nil if none is declared,
+// the credential passwords, vault token and signer key are redacted from its entries.
+func openJournal() *audit.Journal {
+	if runConfig.Audit == nil {
+		return nil
+	}
+
+	var secrets []string
+	for _, credential := range runConfig.Credentials {
+		for _, password := range credential {
+			secrets = append(secrets, password)
+		}
+	}
+
+	if runConfig.Vault != nil {
+		secrets = append(secrets, runConfig.Vault.Token)
+	}
+
+	if runConfig.CertSigner != nil && runConfig.CertSigner.LemurSigner != nil {
+		secrets = append(secrets, runConfig.CertSigner.LemurSigner.Key)
+	}
+
+	journal, err := audit.Open(runConfig.Audit, secrets, log)
+	if err != nil {
+		log.Fatalf("[Error] %s", err.Error())
+	}
+
+	return journal
+}
+
+// newNotifier returns the notifier sending to the webhooks declared in the configuration, nil if none are declared.
+func newNotifier() *notify.Notifier {
+	notifier, err := notify.New(runConfig.Notifications, log)
+	if err != nil {
+		log.Fatalf("[Error] %s", err.Error())
+	}
+
+	return notifier
+}
+
+// runSummary returns the summary notification event of the run.
+func runSummary(run *report.Run) notify.Event {
+	summary := &notify.Summary{
+		Assets:      run.Assets,
+		Succeeded:   run.Succeeded,
+		Failed:      run.Failed,
+		LoginFailed: run.LoginFailed,
+		Skipped:     run.Skipped,
+		Halted:      run.Halted,
+		Interrupted: interrupt,
+		Duration:    run.End.Sub(run.Start).Seconds(),
+	}
+
+	if done := run.Succeeded + run.Failed; done > 0 {
+		summary.FailureRatio = float64(run.Failed) / float64(done)
+	}
+
+	if !runConfig.IgnoreLocation {
+		summary.Locations = runConfig.Locations
+	}
+
+	return notify.Event{Event: notify.EventSummary, Action: run.Action, Summary: summary}
+}
+
+// newTracer returns the tracer exporting spans as declared in the configuration, nil if tracing isn't declared.
+func newTracer() *tracing.Tracer {
+	tracer, err := tracing.New(runConfig.Tracing, log)
+	if err != nil {
+		log.Fatalf("[Error] %s", err.Error())
+	}
+
+	return tracer
+}
+
+// endRunSpan ends the root span of the run, with the run outcome.
+func endRunSpan(run *report.Run) {
+	runSpan.SetAttribute("action", run.Action)
+	runSpan.SetAttribute("assets", run.Assets)
+	runSpan.SetAttribute("succeeded", run.Succeeded)
+	runSpan.SetAttribute("failed", run.Failed)
+	runSpan.SetAttribute("skipped", run.Skipped)
+	runSpan.SetAttribute("interrupted", interrupt)
+
+	var err error
+	if run.Failed > 0 {
+		err = fmt.Errorf("%d assets failed", run.Failed)
+	}
+
+	runSpan.End(err)
+}
+
+// closeSinks closes the audit journal, waits for notifications to be sent and spans to be exported, once butlers are done.
+func closeSinks() {
+	err := butlers.Journal.Close()
+	if err != nil {
+		log.Error("Unable to close audit journal: ", err)
+	}
+
+	butlers.Notifier.Close()
+
+	runSpan.End(nil)
+	butlers.Tracer.Close()
+}
+
+// assetRetriever returns the method that sends assets from the configured inventory source,
+// over the given inventory channel, an error is returned if no known inventory source is declared.
+// The method closes the channel once done, and returns an error if the inventory query failed.
+func assetRetriever(config *config.Params, inventoryChan chan []asset.Asset, stopChan chan struct{}) (func() error, error) {
+	if config.Inventory == nil {
+		return nil, errors.New("no inventory source declared in cfg")
+	}
 
-	// Based on inventory source, invoke assetRetriever:
-	var assetRetriever func()
+	// Determine inventory to fetch asset data.
+	inventorySource := config.Inventory.Source
 
 	switch inventorySource {
 	case "enc":
 		inventoryInstance := inventory.Enc{
-			Config:     runConfig,
+			Config:     config,
 			Log:        log,
 			BatchSize:  10,
 			AssetsChan: inventoryChan,
 			StopChan:   stopChan,
 		}
 
-		assetRetriever = inventoryInstance.AssetRetrieve()
+		return inventoryInstance.AssetRetrieve(), nil
 	case "csv":
 		inventoryInstance := inventory.Csv{
-			Config:     runConfig,
+			Config:     config,
 			Log:        log,
 			AssetsChan: inventoryChan,
 		}
 
-		assetRetriever = inventoryInstance.AssetRetrieve()
+		return inventoryInstance.AssetRetrieve(), nil
 	case "dora":
 		inventoryInstance := inventory.Dora{
-			Config:     runConfig,
+			Config:     config,
 			Log:        log,
 			BatchSize:  10,
 			AssetsChan: inventoryChan,
 		}
 
-		assetRetriever = inventoryInstance.AssetRetrieve()
+		return inventoryInstance.AssetRetrieve(), nil
 	case "iplist":
 		inventoryInstance := inventory.IPList{
 			Channel:   inventoryChan,
-			Config:    runConfig,
+			Config:    config,
 			BatchSize: 1,
 			Log:       log,
 		}
 
-		assetRetriever = inventoryInstance.AssetRetrieve()
+		return inventoryInstance.AssetRetrieve(), nil
 	default:
-		fmt.Println("Unknown/no inventory source declared in cfg: ", inventorySource)
+		return nil, fmt.Errorf("unknown/no inventory source declared in cfg: %q", inventorySource)
+	}
+}
+
+// retrieveAssets spawns the routine that sends assets from the configured inventory source,
+// over the given inventory channel, exits if no known inventory source is declared, or the inventory query failed.
+func retrieveAssets(config *config.Params, inventoryChan chan []asset.Asset, stopChan chan struct{}) {
+	retrieve, err := assetRetriever(config, inventoryChan, stopChan)
+	if err != nil {
+		fmt.Println(err)
 		os.Exit(1)
 	}
 
-	// This routine returns assets over the inventoryChan.
-	go assetRetriever()
+	go func() {
+		err := retrieve()
+		if err != nil {
+			log.Fatal("Unable to retrieve assets from the inventory: ", err)
+		}
+	}()
+}
 
-	// Spawn butlers to work
-	butlerChan = make(chan butler.Msg, 2)
+// spawnButlers spawns butlers to work on assets received over the returned butler channel,
+// the result for each asset is sent over the resultChan if declared.
+func spawnButlers(stopChan chan struct{}, resultChan chan butler.Result) chan butler.Msg {
+	butlerChan := make(chan butler.Msg, 2)
 
 	butlers = &butler.Butler{
 		ButlerChan: butlerChan,
@@ -143,28 +480,49 @@ func prepareChannels() (inventoryChan chan []asset.Asset, butlerChan chan butler
 		SyncWG:     &commandWG,
 	}
 
-	if runConfig.SecretsFromVault {
-		store, err := secrets.Load(*runConfig.Vault)
-		if err != nil {
-			log.Fatalf("[Error] loading secrets from vault: %s", err.Error())
-		}
-
-		runConfig.Credentials, err = store.SetCredentials(runConfig.Credentials)
-		if err != nil {
-			log.Fatalf("[Error] loading secrets from vault: %s", err.Error())
-		}
-
-		runConfig.CertSigner.LemurSigner.Key, err = store.GetSignerToken(runConfig.CertSigner.LemurSigner.Key)
-		if err != nil {
-			log.Fatalf("[Error] loading secrets from vault: %s", err.Error())
-		}
-
-		butlers.Secrets = store
+	// A nil resultChan is not assigned, since a typed nil would not compare to nil.
+	if resultChan != nil {
+		butlers.ResultChan = resultChan
 	}
 
+	butlers.Secrets = loadSecrets()
+	butlers.Journal = openJournal()
+	butlers.Notifier = newNotifier()
+	butlers.Tracer = newTracer()
+
 	go butlers.Runner()
 	commandWG.Add(1)
 
+	return butlerChan
+}
+
+// Sets up required plumbing and returns three channels.
+// - Spawn a Go routine to listen to interrupt signals
+// - Setup metrics channel
+// - Spawn the metrics forwarder Go routine
+// - Setup the inventory channel over which to receive assets
+// - Based on the inventory source (dora/csv), spawn the asset retriever Go routine
+// - Spawn butlers, sending the result for each asset over the resultChan if declared
+// - Return inventory channel, butler channel
+func prepareChannels(resultChan chan butler.Result) (inventoryChan chan []asset.Asset, butlerChan chan butler.Msg, stopChan chan struct{}) {
+	overrideConfigFromFlags()
+	runConfig.Load(runConfig.CfgFile)
+
+	// Used to indicate Go routines to exit.
+	stopChan = make(chan struct{})
+
+	setupMetrics()
+
+	// A channel to receive inventory assets.
+	inventoryChan = make(chan []asset.Asset, 5)
+
+	// This routine returns assets over the inventoryChan.
+	retrieveAssets(runConfig, inventoryChan, stopChan)
+
+	// Spawn butlers to work
+	butlerChan = spawnButlers(stopChan, resultChan)
+	runSpan = butlers.StartRun("run")
+
 	signalsChan := make(chan os.Signal, 1)
 	signal.Notify(signalsChan, syscall.SIGINT, syscall.SIGTERM)
 	go func() {
@@ -15,13 +15,13 @@
 package cmd
 
 import (
-	"fmt"
 	"os"
 
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 
+	"github.com/bmc-toolbox/bmcbutler/pkg/asset"
 	"github.com/bmc-toolbox/bmcbutler/pkg/butler"
+	"github.com/bmc-toolbox/bmcbutler/pkg/report"
 	"github.com/bmc-toolbox/bmcbutler/pkg/resource"
 )
 
@@ -35,6 +35,9 @@ var configureCmd = &cobra.Command{
 }
 
 func init() {
+	addRunFlags(configureCmd)
+	addRolloutFlags(configureCmd)
+
 	rootCmd.AddCommand(configureCmd)
 }
 
@@ -59,44 +62,31 @@ func validateConfigureArgs() {
 func configure() {
 	runConfig.Configure = true
 	validateConfigureArgs()
+	validateRunArgs()
+	validateRolloutArgs()
 
-	inventoryChan, butlerChan, stopChan := prepareChannels()
+	resultChan := make(chan butler.Result, 10)
+	collector := report.Collect(asset.ActionConfigure, resultChan, runLimits, loadCheckpoint(asset.ActionConfigure))
 
-	// Read BMC configuration data.
-	assetConfigDir := viper.GetString("bmcCfgDir")
-	assetConfigFile := fmt.Sprintf("%s/%s", assetConfigDir, "configuration.yml")
+	inventoryChan, butlerChan, stopChan := prepareChannels(resultChan)
 
-	// Read the file as a slice of bytes.
-	// It may contain templated values.
-	assetConfig, err := resource.ReadYamlTemplate(assetConfigFile)
+	// Read BMC configuration data, configuration.yml and its overlays.
+	// They may contain templated values.
+	assetConfig, err := resource.ReadLayers(runConfig.BmcCfgDir)
 	if err != nil {
-		log.Fatal("Unable to read BMC configuration file (", assetConfigFile, "), Error: ", err)
+		log.Fatal("Unable to read BMC configuration, Error: ", err)
 		os.Exit(1)
 	}
 
 	// Iterate over the inventory channel for assets.
 	// Create a butler message for each asset along with the configuration.
 	// At this point, templated values in the config are not yet rendered.
-loop:
-	for {
-		select {
-		case assetList, ok := <-inventoryChan:
-			if !ok {
-				break loop
-			}
-			for _, asset := range assetList {
-				asset.Configure = true
-				butlerMsg := butler.Msg{Asset: asset, AssetConfig: assetConfig}
-				if interrupt {
-					break loop
-				}
-
-				butlerChan <- butlerMsg
-			}
-		case <-stopChan:
-			interrupt = true
-		}
+	msg := butler.Msg{AssetConfig: assetConfig}
+	if rolloutEnabled() {
+		rolloutAssets(inventoryChan, butlerChan, stopChan, collector, asset.ActionConfigure, msg)
+	} else {
+		dispatchAssets(inventoryChan, butlerChan, stopChan, collector, asset.ActionConfigure, msg)
 	}
 
-	post(butlerChan)
+	finishRun(butlerChan, resultChan, collector)
 }
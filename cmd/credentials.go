@@ -0,0 +1,256 @@
+package cmd
+
+import (
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/bmc-toolbox/bmcbutler/pkg/asset"
+	"github.com/bmc-toolbox/bmcbutler/pkg/butler"
+	"github.com/bmc-toolbox/bmcbutler/pkg/metrics"
+	"github.com/bmc-toolbox/bmcbutler/pkg/resource"
+	"github.com/bmc-toolbox/bmcbutler/pkg/secrets"
+)
+
+var (
+	rotateUser      string
+	rotatePerAsset  bool
+	rotateVaultPath string
+	rotateLength    int
+)
+
+// credentialsCmd represents the credentials command
+var credentialsCmd = &cobra.Command{
+	Use:   "credentials",
+	Short: "Manage BMC user account credentials.",
+}
+
+// credentialsRotateCmd represents the credentials rotate command
+var credentialsRotateCmd = &cobra.Command{
+	Use:   "rotate",
+	Short: "Rotate the password of a BMC user account, and write it back to Vault once verified.",
+	Run: func(cmd *cobra.Command, args []string) {
+		credentialsRotate()
+	},
+}
+
+func init() {
+	credentialsRotateCmd.Flags().StringVarP(&rotateUser, "user", "u", "", "The BMC user account to rotate the password of, as declared in configuration.yml.")
+	credentialsRotateCmd.Flags().BoolVarP(&rotatePerAsset, "per-asset", "", false, "Generate a unique password for each asset, written to <vault path>/<serial>.")
+	credentialsRotateCmd.Flags().StringVarP(&rotateVaultPath, "vault-path", "", "", "Vault path the password is written to (default: vault.rotatePath or vault.secretsPath in bmcbutler.yml).")
+	credentialsRotateCmd.Flags().IntVarP(&rotateLength, "length", "", 20, "Length of the generated password.")
+
+	credentialsCmd.AddCommand(credentialsRotateCmd)
+	rootCmd.AddCommand(credentialsCmd)
+}
+
+// credentialsRotate rotates the password of the user account on assets.
+//
+// With --per-asset, butlers generate a password for each asset,
+// and write it to vault under <vault path>/<serial> once a login with it is verified on the asset.
+//
+// Otherwise a single password is generated and set on all assets in the inventory,
+// it's written to vault only if it was set and verified on all of them,
+// if not, assets it was set on are rolled back to the current password in vault.
+// Assets left with a password other than the one in vault have it written under <vault path>/<serial>.
+func credentialsRotate() {
+	component := "credentialsRotate"
+
+	if rotateUser == "" {
+		log.Error("Expected flag missing --user (try --help)")
+		os.Exit(1)
+	}
+
+	validateConfigureArgs()
+
+	overrideConfigFromFlags()
+	runConfig.Load(runConfig.CfgFile)
+
+	if !runConfig.SecretsFromVault {
+		log.Error("Credential rotation requires secretsFromVault declared in bmcbutler.yml.")
+		os.Exit(1)
+	}
+
+	// A shared password written to vault has to be set on all assets using it.
+	if !rotatePerAsset {
+		filtered := !runConfig.FilterParams.All || runConfig.FilterParams.Servers || runConfig.FilterParams.Chassis ||
+			(len(runConfig.Locations) > 0 && !runConfig.IgnoreLocation)
+		if filtered {
+			log.Error("A shared password is rotated on the whole inventory, expected --all without --servers/--chassis/--locations, or --per-asset.")
+			os.Exit(1)
+		}
+	}
+
+	if rotateVaultPath != "" {
+		runConfig.Vault.RotatePath = rotateVaultPath
+	}
+
+	runConfig.RotateUser = rotateUser
+	runConfig.RotatePerAsset = rotatePerAsset
+	runConfig.RotateLength = rotateLength
+
+	// Validated before any asset is touched.
+	_, err := butler.GeneratePassword(runConfig.RotateLength)
+	if err != nil {
+		log.Error("Unable to generate password: ", err)
+		os.Exit(1)
+	}
+
+	assetConfig, err := resource.ReadLayers(runConfig.BmcCfgDir)
+	if err != nil {
+		log.Fatal("Unable to read BMC configuration, Error: ", err)
+	}
+
+	// Used to indicate Go routines to exit.
+	stopChan := make(chan struct{})
+
+	setupMetrics()
+
+	resultChan := make(chan butler.Result, 10)
+	butlerChan := spawnButlers(stopChan, resultChan)
+	runSpan = butlers.StartRun("rotate")
+
+	// On an interrupt, assets butlers have not started on are skipped,
+	// the ones in progress are finished so the results can be acted upon.
+	cancelChan := make(chan struct{})
+	signalsChan := make(chan os.Signal, 1)
+	signal.Notify(signalsChan, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-signalsChan
+		log.Warn("Interrupt SIGINT/SIGTERM received, finishing assets in progress.")
+		close(cancelChan)
+	}()
+
+	inventoryChan := make(chan []asset.Asset, 5)
+	retrieveAssets(runConfig, inventoryChan, stopChan)
+
+	var assets []asset.Asset
+	for assetList := range inventoryChan {
+		assets = append(assets, assetList...)
+	}
+
+	// The password is written back to the vault key the credential was loaded from.
+	var found bool
+	runConfig.RotateKey, found = butlers.Secrets.CredentialKey(runConfig.RotateUser)
+	if !found {
+		log.Error("Expected the credentials of user ", runConfig.RotateUser, " declared as lookup_secret:: in bmcbutler.yml.")
+		os.Exit(1)
+	}
+
+	// The current password is required to rollback assets in case the rotation fails on some.
+	var current, password string
+	if !runConfig.RotatePerAsset {
+		current, err = butlers.Secrets.Get(runConfig.RotateKey)
+		if err != nil {
+			log.Error("Unable to rollback a failed rotation without the current password: ", err)
+			os.Exit(1)
+		}
+
+		password, err = butler.GeneratePassword(runConfig.RotateLength)
+		if err != nil {
+			log.Error("Unable to generate password: ", err)
+			os.Exit(1)
+		}
+	}
+
+	results := rotateAssets(assets, assetConfig, password, butlerChan, resultChan, cancelChan)
+
+	var rotated []asset.Asset
+	var failed []string
+	for _, result := range results {
+		switch {
+		case result.Success:
+			rotated = append(rotated, result.Asset)
+		case !result.Skipped || result.Error == butler.ErrCancelled.Error():
+			failed = append(failed, result.Asset.Serial+"/"+strings.Join(result.Asset.IPAddresses, ","))
+		}
+	}
+
+	log.WithFields(logrus.Fields{
+		"component": component,
+		"User":      runConfig.RotateUser,
+		"Assets":    len(assets),
+		"Rotated":   len(rotated),
+		"Failed":    len(failed),
+	}).Info("Rotation done.")
+
+	switch {
+	case runConfig.DryRun || runConfig.RotatePerAsset:
+	case len(rotated) > 0 && len(failed) == 0:
+		err = secrets.Write(*runConfig.Vault, runConfig.Vault.RotatePath, runConfig.RotateKey, password)
+		if err != nil {
+			log.WithFields(logrus.Fields{
+				"component": component,
+				"VaultPath": runConfig.Vault.RotatePath,
+				"Error":     err,
+			}).Error("Password set and verified on all assets, but not written to vault!")
+			failed = append(failed, "vault")
+			break
+		}
+
+		log.WithFields(logrus.Fields{
+			"component": component,
+			"VaultPath": runConfig.Vault.RotatePath,
+		}).Info("Password written to vault.")
+	case len(rotated) > 0:
+		log.WithFields(logrus.Fields{
+			"component": component,
+			"Failed":    strings.Join(failed, ","),
+			"Rotated":   len(rotated),
+		}).Warn("Rotation failed on some assets, password not written to vault, rolling back rotated assets.")
+
+		// Butlers are idle at this point, rotated assets only accept the new password.
+		runConfig.Credentials = append([]map[string]string{{runConfig.RotateUser: password}}, runConfig.Credentials...)
+
+		for _, result := range rotateAssets(rotated, assetConfig, current, butlerChan, resultChan, nil) {
+			if result.Success {
+				continue
+			}
+
+			log.WithFields(logrus.Fields{
+				"component": component,
+				"Serial":    result.Asset.Serial,
+				"IPAddress": result.Asset.IPAddress,
+				"Error":     result.Error,
+			}).Error("Rollback failed, asset password is not the one in vault!")
+
+			// Unless the rollback password was set, the asset is left with the rotated password,
+			// an unverified rollback password has been stored by the butler.
+			if !strings.HasPrefix(result.Error, butler.ErrUnverified.Error()) {
+				butlers.StoreAssetPassword(&result.Asset, password)
+			}
+		}
+	}
+
+	close(stopChan)
+	commandWG.Wait()
+	close(resultChan)
+	closeSinks()
+	metrics.Close(true)
+
+	if len(failed) > 0 {
+		os.Exit(1)
+	}
+}
+
+// rotateAssets dispatches the assets to butlers to rotate their password, and returns their results.
+func rotateAssets(assets []asset.Asset, assetConfig *resource.Layers, password string, butlerChan chan<- butler.Msg, resultChan <-chan butler.Result, cancelChan <-chan struct{}) []butler.Result {
+	go func() {
+		for _, a := range assets {
+			a.Action = asset.ActionRotate
+			butlerChan <- butler.Msg{Asset: a, AssetConfig: assetConfig, AssetPassword: password, Cancel: cancelChan}
+		}
+	}()
+
+	// Butlers send a result for each asset, including the ones skipped.
+	results := make([]butler.Result, 0, len(assets))
+	for len(results) < len(assets) {
+		results = append(results, <-resultChan)
+	}
+
+	return results
+}
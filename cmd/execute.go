@@ -17,7 +17,9 @@ package cmd
 import (
 	"github.com/spf13/cobra"
 
+	"github.com/bmc-toolbox/bmcbutler/pkg/asset"
 	"github.com/bmc-toolbox/bmcbutler/pkg/butler"
+	"github.com/bmc-toolbox/bmcbutler/pkg/report"
 )
 
 // configureCmd represents the configure command
@@ -30,23 +32,21 @@ var executeCmd = &cobra.Command{
 }
 
 func init() {
+	addRunFlags(executeCmd)
+
 	rootCmd.AddCommand(executeCmd)
 }
 
 func execute() {
 	runConfig.Execute = true
-	inventoryChan, butlerChan, _ := prepareChannels()
-
-	// Iterate over the inventory channel for assets.
-	// Create a butler message for each asset along with the configuration.
-	// At this point, templated values in the config are not yet rendered.
-	for assetList := range inventoryChan {
-		for _, asset := range assetList {
-			asset.Execute = true
-			butlerMsg := butler.Msg{Asset: asset, AssetExecute: execCommand}
-			butlerChan <- butlerMsg
-		}
-	}
-
-	post(butlerChan)
+	validateRunArgs()
+
+	resultChan := make(chan butler.Result, 10)
+	collector := report.Collect(asset.ActionExecute, resultChan, runLimits, loadCheckpoint(asset.ActionExecute))
+
+	inventoryChan, butlerChan, stopChan := prepareChannels(resultChan)
+
+	dispatchAssets(inventoryChan, butlerChan, stopChan, collector, asset.ActionExecute, butler.Msg{AssetExecute: execCommand})
+
+	finishRun(butlerChan, resultChan, collector)
 }
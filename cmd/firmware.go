@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/bmc-toolbox/bmcbutler/pkg/asset"
+	"github.com/bmc-toolbox/bmcbutler/pkg/butler"
+)
+
+var (
+	firmwareFormat  string
+	firmwareListAll bool
+)
+
+// firmwareCmd represents the firmware command
+var firmwareCmd = &cobra.Command{
+	Use:   "firmware",
+	Short: "Inspect BMC firmware against the firmware catalog.",
+}
+
+// firmwareComplianceCmd represents the firmware compliance command
+var firmwareComplianceCmd = &cobra.Command{
+	Use:   "compliance",
+	Short: "List assets running a firmware version other than the one declared in the catalog.",
+	Run: func(cmd *cobra.Command, args []string) {
+		firmwareCompliance()
+	},
+}
+
+func init() {
+	firmwareComplianceCmd.Flags().StringVarP(&firmwareFormat, "format", "f", "json", "Output format (json/csv).")
+	firmwareComplianceCmd.Flags().BoolVarP(&firmwareListAll, "list-compliant", "", false, "List compliant assets too.")
+
+	firmwareCmd.AddCommand(firmwareComplianceCmd)
+	rootCmd.AddCommand(firmwareCmd)
+}
+
+func firmwareCompliance() {
+	switch firmwareFormat {
+	case "json", "csv":
+	default:
+		fmt.Printf("Unknown output format: %s (expected json/csv)\n", firmwareFormat)
+		os.Exit(1)
+	}
+
+	validateConfigureArgs()
+
+	runConfig.FirmwareFormat = firmwareFormat
+	runConfig.FirmwareListAll = firmwareListAll
+
+	// The report is written to stdout, keep logs out of the way.
+	log.Out = os.Stderr
+
+	inventoryChan, butlerChan, stopChan := prepareChannels(nil)
+
+	if runConfig.Firmware == nil || len(runConfig.Firmware.Catalog) == 0 {
+		log.Fatal("No firmware catalog declared in bmcbutler.yml.")
+	}
+
+	err := butler.WriteFirmwareReportHeader(os.Stdout, runConfig.FirmwareFormat)
+	if err != nil {
+		log.Fatal("Unable to write report header: ", err)
+	}
+
+	dispatchAssets(inventoryChan, butlerChan, stopChan, nil, asset.ActionFirmware, butler.Msg{})
+
+	post(butlerChan)
+}
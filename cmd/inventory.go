@@ -0,0 +1,197 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/bmc-toolbox/bmcbutler/pkg/asset"
+	"github.com/bmc-toolbox/bmcbutler/pkg/butler"
+)
+
+var inventoryFormat string
+
+// inventoryCmd represents the inventory command
+var inventoryCmd = &cobra.Command{
+	Use:   "inventory",
+	Short: "Inspect assets in the inventory.",
+}
+
+// inventoryListCmd represents the inventory list command
+var inventoryListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List assets butlers would action, based on the given filters.",
+	Run: func(cmd *cobra.Command, args []string) {
+		inventoryList()
+	},
+}
+
+func init() {
+	inventoryListCmd.Flags().StringVarP(&inventoryFormat, "format", "f", "table", "Output format (table/json/csv).")
+
+	inventoryCmd.AddCommand(inventoryListCmd)
+	rootCmd.AddCommand(inventoryCmd)
+}
+
+// inventoryRecord is an asset as listed by the inventory list command.
+type inventoryRecord struct {
+	Serial       string            `json:"serial"`
+	Type         string            `json:"type"`
+	Vendor       string            `json:"vendor"`
+	HardwareType string            `json:"hardware_type"`
+	Location     string            `json:"location"`
+	IPAddresses  []string          `json:"ip_addresses"`
+	Extra        map[string]string `json:"extra"`
+}
+
+func inventoryList() {
+	switch inventoryFormat {
+	case "table", "json", "csv":
+	default:
+		fmt.Printf("Unknown output format: %s (expected table/json/csv)\n", inventoryFormat)
+		os.Exit(1)
+	}
+
+	// The listing is written to stdout, keep logs out of the way.
+	log.Out = os.Stderr
+
+	overrideConfigFromFlags()
+	runConfig.Load(runConfig.CfgFile)
+	setupMetrics()
+
+	inventoryChan := make(chan []asset.Asset, 5)
+	stopChan := make(chan struct{})
+
+	retrieveAssets(runConfig, inventoryChan, stopChan)
+
+	records := make([]inventoryRecord, 0)
+	for assetList := range inventoryChan {
+		for _, a := range assetList {
+			// Skip assets the same way butlers do.
+			if err := butler.Manageable(&a, runConfig); err != nil {
+				log.WithFields(logrus.Fields{
+					"component": "inventoryList",
+					"Serial":    a.Serial,
+					"IPAddress": strings.Join(a.IPAddresses, ","),
+					"Location":  a.Location,
+					"Reason":    err,
+				}).Info("Asset skipped.")
+				continue
+			}
+
+			records = append(records, inventoryRecord{
+				Serial:       a.Serial,
+				Type:         a.Type,
+				Vendor:       a.Vendor,
+				HardwareType: a.HardwareType,
+				Location:     a.Location,
+				IPAddresses:  a.IPAddresses,
+				Extra:        a.Extra,
+			})
+		}
+	}
+
+	var err error
+	switch inventoryFormat {
+	case "json":
+		err = writeInventoryJSON(os.Stdout, records)
+	case "csv":
+		err = writeInventoryCSV(os.Stdout, records)
+	default:
+		err = writeInventoryTable(os.Stdout, records)
+	}
+
+	if err != nil {
+		log.Error("Unable to write inventory: ", err)
+		os.Exit(1)
+	}
+}
+
+// extraKeys returns the sorted set of Extra attribute keys across records.
+func extraKeys(records []inventoryRecord) (keys []string) {
+	seen := make(map[string]bool)
+	for _, r := range records {
+		for k := range r.Extra {
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+func writeInventoryJSON(w io.Writer, records []inventoryRecord) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(records)
+}
+
+func writeInventoryCSV(w io.Writer, records []inventoryRecord) error {
+	keys := extraKeys(records)
+
+	writer := csv.NewWriter(w)
+
+	header := []string{"serial", "type", "vendor", "hardware_type", "location", "ip_addresses"}
+	for _, k := range keys {
+		header = append(header, "extra."+k)
+	}
+
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	for _, r := range records {
+		row := []string{r.Serial, r.Type, r.Vendor, r.HardwareType, r.Location, strings.Join(r.IPAddresses, ",")}
+		for _, k := range keys {
+			row = append(row, r.Extra[k])
+		}
+
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func writeInventoryTable(w io.Writer, records []inventoryRecord) error {
+	keys := extraKeys(records)
+
+	writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "SERIAL\tTYPE\tVENDOR\tHARDWARETYPE\tLOCATION\tIPADDRESSES\tEXTRA")
+
+	for _, r := range records {
+		extra := make([]string, 0, len(keys))
+		for _, k := range keys {
+			if v, exists := r.Extra[k]; exists {
+				extra = append(extra, k+"="+v)
+			}
+		}
+
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			r.Serial,
+			r.Type,
+			r.Vendor,
+			r.HardwareType,
+			r.Location,
+			strings.Join(r.IPAddresses, ","),
+			strings.Join(extra, ","),
+		)
+	}
+
+	fmt.Fprintf(writer, "\n%d asset(s).\n", len(records))
+
+	return writer.Flush()
+}
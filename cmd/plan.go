@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/bmc-toolbox/bmcbutler/pkg/asset"
+	"github.com/bmc-toolbox/bmcbutler/pkg/butler"
+	"github.com/bmc-toolbox/bmcbutler/pkg/resource"
+)
+
+// planCmd represents the plan command
+var planCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "List changes configure would apply to bmcs, without applying them.",
+	Run: func(cmd *cobra.Command, args []string) {
+		plan()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(planCmd)
+}
+
+func plan() {
+	runConfig.Configure = true
+	validateConfigureArgs()
+
+	// The plan is written to stdout, keep logs out of the way.
+	log.Out = os.Stderr
+
+	inventoryChan, butlerChan, stopChan := prepareChannels(nil)
+
+	// Read BMC configuration data, configuration.yml and its overlays.
+	assetConfig, err := resource.ReadLayers(runConfig.BmcCfgDir)
+	if err != nil {
+		log.Fatal("Unable to read BMC configuration, Error: ", err)
+		os.Exit(1)
+	}
+
+	dispatchAssets(inventoryChan, butlerChan, stopChan, nil, asset.ActionPlan, butler.Msg{AssetConfig: assetConfig})
+
+	post(butlerChan)
+}
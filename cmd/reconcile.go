@@ -0,0 +1,249 @@
+package cmd
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/bmc-toolbox/bmcbutler/pkg/asset"
+	"github.com/bmc-toolbox/bmcbutler/pkg/butler"
+	"github.com/bmc-toolbox/bmcbutler/pkg/metrics"
+	"github.com/bmc-toolbox/bmcbutler/pkg/resource"
+)
+
+var (
+	reconcileInterval  time.Duration
+	reconcileJitter    time.Duration
+	reconcileStateFile string
+	reconcileOnce      bool
+)
+
+// reconcileCmd represents the reconcile command
+var reconcileCmd = &cobra.Command{
+	Use:   "reconcile",
+	Short: "Periodically re-apply configuration to BMCs where it changed or drifted.",
+	Run: func(cmd *cobra.Command, args []string) {
+		reconcile()
+	},
+}
+
+func init() {
+	reconcileCmd.Flags().DurationVarP(&reconcileInterval, "interval", "", 30*time.Minute, "Interval between walks of the inventory.")
+	reconcileCmd.Flags().DurationVarP(&reconcileJitter, "jitter", "", 0, "Spread out each walk, by delaying each location by a random duration up to this value.")
+	reconcileCmd.Flags().StringVarP(&reconcileStateFile, "state-file", "", "", "Persist the rendered configuration state of assets to this file, to carry it across restarts.")
+	reconcileCmd.Flags().BoolVarP(&reconcileOnce, "once", "", false, "Walk the inventory once and exit.")
+
+	rootCmd.AddCommand(reconcileCmd)
+}
+
+func reconcile() {
+	component := "reconcile"
+
+	validateConfigureArgs()
+
+	overrideConfigFromFlags()
+	runConfig.Load(runConfig.CfgFile)
+
+	state, err := butler.LoadReconcileState(reconcileStateFile)
+	if err != nil {
+		log.Fatal("Unable to load reconcile state: ", err)
+	}
+
+	// Used to indicate Go routines to exit.
+	stopChan := make(chan struct{})
+
+	setupMetrics()
+
+	// Butlers are spawned once and kept alive across walks,
+	// results indicate when a walk is done.
+	resultChan := make(chan butler.Result, 10)
+	butlerChan := spawnButlers(stopChan, resultChan)
+	butlers.ReconcileState = state
+
+	signalsChan := make(chan os.Signal, 1)
+	signal.Notify(signalsChan, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-signalsChan
+		interrupt = true
+		log.Warn("Interrupt SIGINT/SIGTERM received.")
+		close(stopChan)
+	}()
+
+	random := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	for walk := 1; !interrupt; walk++ {
+		start := time.Now()
+
+		reconcileWalk(walk, butlerChan, resultChan, stopChan, random)
+
+		err := state.Save()
+		if err != nil {
+			log.WithFields(logrus.Fields{
+				"component": component,
+				"StateFile": reconcileStateFile,
+				"Error":     err,
+			}).Warn("Unable to save reconcile state.")
+		}
+
+		if reconcileOnce {
+			break
+		}
+
+		log.WithFields(logrus.Fields{
+			"component": component,
+			"Walk":      walk,
+			"Next":      start.Add(reconcileInterval).Format(time.RFC3339),
+		}).Debug("Waiting for next walk.")
+
+		select {
+		case <-time.After(time.Until(start.Add(reconcileInterval))):
+		case <-stopChan:
+		}
+	}
+
+	// Let the butler Runner return and drain results from butlers still working.
+	if !interrupt {
+		close(stopChan)
+	}
+
+	go func() {
+		for range resultChan {
+		}
+	}()
+
+	commandWG.Wait()
+	close(resultChan)
+	closeSinks()
+
+	metrics.Close(true)
+}
+
+// reconcileWalk dispatches assets from the inventory to butlers,
+// each location delayed by a random duration up to the jitter, and waits for their results.
+func reconcileWalk(walk int, butlerChan chan<- butler.Msg, resultChan <-chan butler.Result, stopChan chan struct{}, random *rand.Rand) {
+	component := "reconcileWalk"
+	start := time.Now()
+	walkID := fmt.Sprintf("reconcile-%d", walk)
+
+	span := butlers.StartRun("reconcile_walk")
+	span.SetAttribute("walk", walk)
+	defer span.End(nil)
+
+	// The configuration is read for each walk, changes are picked up without a restart.
+	assetConfig, err := resource.ReadLayers(runConfig.BmcCfgDir)
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"component": component,
+			"Walk":      walk,
+			"Error":     err,
+		}).Warn("Unable to read BMC configuration, walk skipped.")
+		span.End(err)
+		return
+	}
+
+	inventoryChan := make(chan []asset.Asset, 5)
+	retrieveAssets(runConfig, inventoryChan, stopChan)
+
+	byLocation := make(map[string][]asset.Asset)
+	var count int
+	for assetList := range inventoryChan {
+		for _, a := range assetList {
+			byLocation[a.Location] = append(byLocation[a.Location], a)
+			count++
+		}
+	}
+
+	log.WithFields(logrus.Fields{
+		"component": component,
+		"Walk":      walk,
+		"Assets":    count,
+		"Locations": len(byLocation),
+	}).Info("Walking inventory.")
+
+	// Delays are drawn here, since rand.Rand is not safe for concurrent use.
+	for location, assets := range byLocation {
+		var delay time.Duration
+		if reconcileJitter > 0 {
+			delay = time.Duration(random.Int63n(int64(reconcileJitter)))
+		}
+
+		go func(location string, assets []asset.Asset, delay time.Duration) {
+			select {
+			case <-time.After(delay):
+			case <-stopChan:
+				return
+			}
+
+			log.WithFields(logrus.Fields{
+				"component": component,
+				"Walk":      walk,
+				"Location":  location,
+				"Assets":    len(assets),
+				"Delay":     delay.String(),
+			}).Debug("Dispatching location assets.")
+
+			for _, a := range assets {
+				a.Action = asset.ActionReconcile
+				select {
+				case butlerChan <- butler.Msg{Asset: a, AssetConfig: assetConfig, JobID: walkID}:
+				case <-stopChan:
+					return
+				}
+			}
+		}(location, assets, delay)
+	}
+
+	// Butlers send a result for each asset, including the ones skipped.
+	var received, failed int
+	for received < count {
+		select {
+		case result := <-resultChan:
+			received++
+			if !result.Success && !result.Skipped {
+				failed++
+			}
+		case <-stopChan:
+			return
+		}
+	}
+
+	outcomes := make(map[string]int)
+	drifted := make(map[string]int)
+	for _, s := range butlers.ReconcileState.Since(start) {
+		outcomes[s.Outcome]++
+		for _, r := range s.Drifted {
+			drifted[r]++
+		}
+	}
+
+	metrics.UpdateGauge([]string{"reconcile", "assets"}, int64(count))
+	metrics.UpdateGauge([]string{"reconcile", "assets_failed"}, int64(failed))
+	metrics.UpdateGauge([]string{"reconcile", "assets_drifted"}, int64(outcomes[butler.ReconcileDrifted]))
+	metrics.UpdateGauge([]string{"reconcile", "assets_config_changed"}, int64(outcomes[butler.ReconcileConfigChanged]))
+	metrics.UpdateGauge([]string{"reconcile", "assets_retried"}, int64(outcomes[butler.ReconcileRetried]))
+	metrics.UpdateGauge([]string{"reconcile", "assets_in_sync"}, int64(outcomes[butler.ReconcileInSync]))
+	metrics.UpdateTimer([]string{"reconcile", "walk_runtime"}, time.Since(start))
+
+	span.SetAttribute("assets", count)
+	span.SetAttribute("failed", failed)
+	span.SetAttribute("drifted", outcomes[butler.ReconcileDrifted])
+
+	log.WithFields(logrus.Fields{
+		"component":     component,
+		"Walk":          walk,
+		"Assets":        count,
+		"Failed":        failed,
+		"Drifted":       outcomes[butler.ReconcileDrifted],
+		"ConfigChanged": outcomes[butler.ReconcileConfigChanged],
+		"Retried":       outcomes[butler.ReconcileRetried],
+		"InSync":        outcomes[butler.ReconcileInSync],
+		"Resources":     drifted,
+		"Duration":      time.Since(start).String(),
+	}).Info("Walk done.")
+}
@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+
+	"github.com/bmc-toolbox/bmcbutler/pkg/asset"
+	"github.com/bmc-toolbox/bmcbutler/pkg/resource"
+	"github.com/bmc-toolbox/bmcbutler/pkg/secrets"
+)
+
+var (
+	renderAsset    asset.Asset
+	renderLookup   bool
+	renderRedact   bool
+	renderSources  bool
+	renderTemplate string
+)
+
+// renderCmd represents the render command
+var renderCmd = &cobra.Command{
+	Use:   "render",
+	Short: "Render the BMC configuration for an asset, without connecting to it.",
+	Run: func(cmd *cobra.Command, args []string) {
+		render()
+	},
+}
+
+func init() {
+	renderAsset.Extra = make(map[string]string)
+
+	renderCmd.Flags().StringVarP(&renderAsset.Serial, "serial", "", "", "Serial of the asset to render configuration for.")
+	renderCmd.Flags().StringVarP(&renderAsset.Vendor, "vendor", "", "", "Vendor of the asset (e.g HP, Dell, Supermicro).")
+	renderCmd.Flags().StringVarP(&renderAsset.HardwareType, "hardwaretype", "", "", "Hardware type of the asset (e.g idrac9, ilo5, m1000e).")
+	renderCmd.Flags().StringVarP(&renderAsset.Type, "assettype", "", "server", "Type of the asset (server/chassis).")
+	renderCmd.Flags().StringVarP(&renderAsset.Location, "location", "", "", "Location of the asset.")
+	renderCmd.Flags().StringVarP(&renderAsset.IPAddress, "ipaddress", "", "", "IP Address of the asset.")
+	renderCmd.Flags().StringToStringVarP(&renderAsset.Extra, "extra", "", map[string]string{}, "Extra asset attributes (e.g --extra state=live,company=acme).")
+	renderCmd.Flags().BoolVarP(&renderLookup, "lookup", "", false, "Look up assets in the inventory by --serials/--ips instead of declaring attributes.")
+	renderCmd.Flags().BoolVarP(&renderRedact, "redact", "", false, "Render lookup_secret values as placeholders instead of loading secrets from vault.")
+	renderCmd.Flags().StringVarP(&renderTemplate, "template", "", "", "BMC configuration template to render (default: <bmcCfgDir>/configuration.yml and its overlays).")
+	renderCmd.Flags().BoolVarP(&renderSources, "sources", "", false, "List the configuration file each rendered value came from.")
+
+	rootCmd.AddCommand(renderCmd)
+}
+
+func render() {
+	// The rendered configuration is written to stdout, keep logs out of the way.
+	log.Out = os.Stderr
+
+	overrideConfigFromFlags()
+	runConfig.Load(runConfig.CfgFile)
+
+	var assetConfig *resource.Layers
+	if renderTemplate != "" {
+		template, err := resource.ReadYamlTemplate(renderTemplate)
+		if err != nil {
+			log.Error("Unable to read BMC configuration file (", renderTemplate, "), Error: ", err)
+			os.Exit(1)
+		}
+
+		assetConfig = resource.NewLayers(renderTemplate, template)
+	} else {
+		var err error
+		assetConfig, err = resource.ReadLayers(runConfig.BmcCfgDir)
+		if err != nil {
+			log.Error("Unable to read BMC configuration, Error: ", err)
+			os.Exit(1)
+		}
+	}
+
+	var store *secrets.Store
+	if !renderRedact {
+		store = loadSecrets()
+	}
+
+	assets := []asset.Asset{renderAsset}
+	if renderLookup {
+		assets = lookupAssets()
+	}
+
+	var failed bool
+	for idx := range assets {
+		a := &assets[idx]
+		if a.IPAddress == "" && len(a.IPAddresses) > 0 {
+			a.IPAddress = a.IPAddresses[0]
+		}
+
+		fmt.Printf("# Serial: %s, IP: %s, Vendor: %s, HardwareType: %s, AssetType: %s, Location: %s\n",
+			a.Serial, a.IPAddress, a.Vendor, a.HardwareType, a.Type, a.Location)
+
+		err := renderAssetConfig(a, assetConfig, store)
+		if err != nil {
+			log.Error(err)
+			failed = true
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// renderAssetConfig prints the rendered configuration for the asset, merged from the files that apply to it,
+// the configuration resources it unmarshals into, and with --sources the file each value came from.
+func renderAssetConfig(a *asset.Asset, assetConfig *resource.Layers, store *secrets.Store) error {
+	resourceInstance := resource.Resource{Log: log, Asset: a, Secrets: store, RedactSecrets: renderRedact}
+
+	rendered, sources, err := resourceInstance.MergeLayers(assetConfig)
+	if err != nil {
+		return err
+	}
+
+	var files []string
+	for _, layer := range assetConfig.For(a) {
+		files = append(files, layer.File)
+	}
+
+	fmt.Printf("# Rendered configuration, merged from: %s\n", strings.Join(files, ", "))
+	fmt.Println(string(rendered))
+
+	if renderSources {
+		paths := make([]string, 0, len(sources))
+		for path := range sources {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+
+		fmt.Println("# Sources")
+		for _, path := range paths {
+			fmt.Printf("%s: %s\n", path, sources[path])
+		}
+		fmt.Println()
+	}
+
+	config, err := resourceInstance.LoadLayers(assetConfig)
+	if err != nil {
+		return err
+	}
+
+	unmarshalled, err := yaml.Marshal(config)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("# Unmarshalled cfgresources.ResourcesConfig")
+	fmt.Println(string(unmarshalled))
+
+	return nil
+}
+
+// lookupAssets returns the assets from the inventory source,
+// based on the --serials/--ips filter params.
+func lookupAssets() (assets []asset.Asset) {
+	if runConfig.FilterParams.Serials == "" && runConfig.FilterParams.Ips == "" {
+		log.Error("--lookup expects --serials or --ips (try --help)")
+		os.Exit(1)
+	}
+
+	setupMetrics()
+
+	inventoryChan := make(chan []asset.Asset, 5)
+	stopChan := make(chan struct{})
+
+	retrieveAssets(runConfig, inventoryChan, stopChan)
+
+	for assetList := range inventoryChan {
+		assets = append(assets, assetList...)
+	}
+
+	return assets
+}
@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/bmc-toolbox/bmcbutler/pkg/asset"
+	"github.com/bmc-toolbox/bmcbutler/pkg/butler"
+	"github.com/bmc-toolbox/bmcbutler/pkg/report"
+	"github.com/bmc-toolbox/bmcbutler/pkg/rollout"
+)
+
+var (
+	rolloutStages          string
+	rolloutLocations       string
+	rolloutMaxFailureRatio float64
+	rolloutConfirm         bool
+	rolloutSteps           []rollout.Step
+)
+
+// addRolloutFlags adds the staged rollout flags to the command.
+func addRolloutFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&rolloutStages, "stages", "", "", "Roll out in stages of a number or percentage of assets, separated by commas (e.g 1,5%,25%,100%).")
+	cmd.Flags().StringVarP(&rolloutLocations, "stage-locations", "", "", "Roll out in a stage per location, in the order listed, separated by commas.")
+	cmd.Flags().Float64VarP(&rolloutMaxFailureRatio, "stage-max-failure-ratio", "", 0, "Halt the rollout once the ratio of failed assets in a stage is above this (e.g 0.1), by default a single failed asset halts the rollout.")
+	cmd.Flags().BoolVarP(&rolloutConfirm, "stage-confirm", "", false, "Wait for confirmation before each stage after the first.")
+}
+
+// validateRolloutArgs exits if the staged rollout flags are invalid.
+func validateRolloutArgs() {
+	if rolloutStages != "" && rolloutLocations != "" {
+		log.Error("--stages --stage-locations are mutually exclusive args.")
+		os.Exit(1)
+	}
+
+	if rolloutMaxFailureRatio < 0 || rolloutMaxFailureRatio > 1 {
+		log.Error("Expected --stage-max-failure-ratio between 0 and 1.")
+		os.Exit(1)
+	}
+
+	if rolloutStages != "" {
+		var err error
+		rolloutSteps, err = rollout.ParseSteps(rolloutStages)
+		if err != nil {
+			log.Error(err)
+			os.Exit(1)
+		}
+	}
+}
+
+// rolloutEnabled returns true if a staged rollout was asked for.
+func rolloutEnabled() bool {
+	return rolloutStages != "" || rolloutLocations != ""
+}
+
+// rolloutAssets retrieves all assets from the inventory, splits them into stages,
+// and dispatches the assets of each stage once the stage before it is done,
+// the run is halted if the failure ratio of a stage is above --stage-max-failure-ratio,
+// or the next stage was not confirmed with --stage-confirm.
+func rolloutAssets(inventoryChan <-chan []asset.Asset, butlerChan chan<- butler.Msg, stopChan <-chan struct{}, collector *report.Collector, action string, msg butler.Msg) {
+	var assets []asset.Asset
+retrieve:
+	for {
+		select {
+		case assetList, ok := <-inventoryChan:
+			if !ok {
+				break retrieve
+			}
+			assets = append(assets, assetList...)
+		case <-stopChan:
+			interrupt = true
+			return
+		}
+	}
+
+	var stages []rollout.Stage
+	if rolloutLocations != "" {
+		var unlisted []asset.Asset
+		stages, unlisted = rollout.ByLocation(assets, strings.Split(rolloutLocations, ","))
+		if len(unlisted) > 0 {
+			log.Warn(fmt.Sprintf("%d assets in locations not listed in --stage-locations will not be rolled out to.", len(unlisted)))
+		}
+	} else {
+		stages = rollout.ByStep(assets, rolloutSteps)
+	}
+
+	log.Info(fmt.Sprintf("Rolling out to %d assets in %d stages.", len(assets), len(stages)))
+
+	for i, stage := range stages {
+		if interrupt {
+			return
+		}
+
+		select {
+		case <-collector.Halt():
+			return
+		default:
+		}
+
+		if i > 0 && rolloutConfirm && !confirmStage(i, stages, stopChan) {
+			if interrupt {
+				return
+			}
+
+			log.Warn("Rollout not confirmed, halting.")
+			collector.Stop()
+			return
+		}
+
+		log.Info(fmt.Sprintf("Stage %d/%d (%s): rolling out to %d assets.", i+1, len(stages), stage.Name, len(stage.Assets)))
+
+		stageChan := make(chan []asset.Asset, 1)
+		stageChan <- stage.Assets
+		close(stageChan)
+
+		from := collector.Results()
+		dispatched := dispatchAssets(stageChan, butlerChan, stopChan, collector, action, msg)
+		results := collector.WaitResults(from, from+dispatched, stopChan)
+
+		var done, failed int
+		for _, r := range results {
+			switch {
+			case r.Skipped:
+			case r.Success:
+				done++
+			default:
+				done++
+				failed++
+			}
+		}
+
+		log.Info(fmt.Sprintf("Stage %d/%d (%s): %d assets done, %d failed.", i+1, len(stages), stage.Name, done, failed))
+
+		if done > 0 && float64(failed)/float64(done) > rolloutMaxFailureRatio {
+			log.Error(fmt.Sprintf("Stage %d/%d (%s): failure ratio above %g, halting the rollout.", i+1, len(stages), stage.Name, rolloutMaxFailureRatio))
+			collector.Stop()
+			return
+		}
+	}
+}
+
+// confirmStage asks for confirmation on stdin before rolling out the next stage,
+// returns false unless confirmed.
+func confirmStage(next int, stages []rollout.Stage, stopChan <-chan struct{}) bool {
+	fmt.Printf("Stage %d/%d (%s, %d assets) is next, continue? [y/N]: ", next+1, len(stages), stages[next].Name, len(stages[next].Assets))
+
+	answer := make(chan string, 1)
+	go func() {
+		line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		answer <- strings.ToLower(strings.TrimSpace(line))
+	}()
+
+	select {
+	case a := <-answer:
+		return a == "y" || a == "yes"
+	case <-stopChan:
+		fmt.Println()
+		return false
+	}
+}
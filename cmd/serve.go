@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/bmc-toolbox/bmcbutler/pkg/api"
+	"github.com/bmc-toolbox/bmcbutler/pkg/asset"
+	"github.com/bmc-toolbox/bmcbutler/pkg/butler"
+	"github.com/bmc-toolbox/bmcbutler/pkg/config"
+	"github.com/bmc-toolbox/bmcbutler/pkg/metrics"
+)
+
+var serveListen string
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Run butlers as a daemon, accepting configure/execute jobs over an HTTP API.",
+	Run: func(cmd *cobra.Command, args []string) {
+		serve()
+	},
+}
+
+func init() {
+	serveCmd.Flags().StringVarP(&serveListen, "listen", "", "127.0.0.1:8080", "Address the HTTP API listens on, the API is unauthenticated.")
+
+	rootCmd.AddCommand(serveCmd)
+}
+
+func serve() {
+	component := "serve"
+
+	overrideConfigFromFlags()
+	runConfig.Load(runConfig.CfgFile)
+
+	// Used to indicate Go routines to exit.
+	stopChan := make(chan struct{})
+
+	setupMetrics()
+
+	// Butlers are spawned once and kept alive for all jobs.
+	resultChan := make(chan butler.Result, 10)
+	butlerChan := spawnButlers(stopChan, resultChan)
+
+	manager := &api.Manager{
+		Config:     runConfig,
+		Log:        log,
+		ButlerChan: butlerChan,
+		ResultChan: resultChan,
+		StopChan:   stopChan,
+		AssetRetriever: func(config *config.Params, inventoryChan chan []asset.Asset) (func() error, error) {
+			return assetRetriever(config, inventoryChan, stopChan)
+		},
+	}
+
+	go manager.Run()
+
+	server := &http.Server{
+		Addr:    serveListen,
+		Handler: (&api.Server{Manager: manager, Log: log}).Handler(),
+	}
+
+	go func() {
+		log.WithFields(logrus.Fields{
+			"component": component,
+			"Listen":    serveListen,
+		}).Info("Serving job API.")
+
+		err := server.ListenAndServe()
+		if err != nil && err != http.ErrServerClosed {
+			log.WithFields(logrus.Fields{
+				"component": component,
+				"Error":     err,
+			}).Fatal("Unable to serve job API.")
+		}
+	}()
+
+	signalsChan := make(chan os.Signal, 1)
+	signal.Notify(signalsChan, syscall.SIGINT, syscall.SIGTERM)
+	<-signalsChan
+
+	log.Warn("Interrupt SIGINT/SIGTERM received.")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	err := server.Shutdown(ctx)
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"component": component,
+			"Error":     err,
+		}).Warn("Job API shutdown returned error.")
+	}
+
+	// Butlers finish the assets they started on, pending assets are abandoned.
+	close(stopChan)
+	commandWG.Wait()
+	close(resultChan)
+	closeSinks()
+
+	metrics.Close(true)
+}
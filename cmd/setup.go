@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/bmc-toolbox/bmcbutler/pkg/asset"
+	"github.com/bmc-toolbox/bmcbutler/pkg/butler"
+	"github.com/bmc-toolbox/bmcbutler/pkg/resource"
+)
+
+// setupCmd represents the setup command
+var setupCmd = &cobra.Command{
+	Use:   "setup",
+	Short: "Apply one time setup configuration to BMCs.",
+	Run: func(cmd *cobra.Command, args []string) {
+		setup()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(setupCmd)
+}
+
+func setup() {
+	runConfig.Setup = true
+	validateConfigureArgs()
+
+	inventoryChan, butlerChan, stopChan := prepareChannels(nil)
+
+	// Read BMC one time setup configuration data.
+	assetSetupFile := fmt.Sprintf("%s/%s", runConfig.BmcCfgDir, "setup.yml")
+
+	// Read the file as a slice of bytes.
+	// It may contain templated values.
+	assetSetup, err := resource.ReadYamlTemplate(assetSetupFile)
+	if err != nil {
+		log.Fatal("Unable to read BMC setup configuration file (", assetSetupFile, "), Error: ", err)
+		os.Exit(1)
+	}
+
+	// Iterate over the inventory channel for assets.
+	// Create a butler message for each asset along with the setup configuration.
+	// At this point, templated values in the config are not yet rendered.
+	dispatchAssets(inventoryChan, butlerChan, stopChan, nil, asset.ActionSetup, butler.Msg{AssetSetup: assetSetup})
+
+	post(butlerChan)
+}
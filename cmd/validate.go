@@ -0,0 +1,282 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bmc-toolbox/bmclib/cfgresources"
+	"github.com/bmc-toolbox/bmclib/devices"
+	"github.com/bmc-toolbox/bmclib/providers/dell"
+	"github.com/bmc-toolbox/bmclib/providers/dell/idrac8"
+	"github.com/bmc-toolbox/bmclib/providers/dell/idrac9"
+	"github.com/bmc-toolbox/bmclib/providers/dell/m1000e"
+	"github.com/bmc-toolbox/bmclib/providers/hp"
+	"github.com/bmc-toolbox/bmclib/providers/hp/c7000"
+	"github.com/bmc-toolbox/bmclib/providers/hp/ilo"
+	"github.com/bmc-toolbox/bmclib/providers/supermicro"
+	"github.com/bmc-toolbox/bmclib/providers/supermicro/supermicrox"
+	"github.com/bmc-toolbox/bmclib/providers/supermicro/supermicrox11"
+	"github.com/spf13/cobra"
+
+	"github.com/bmc-toolbox/bmcbutler/pkg/asset"
+	"github.com/bmc-toolbox/bmcbutler/pkg/resource"
+	"github.com/bmc-toolbox/bmcbutler/pkg/secrets"
+)
+
+// validateProvider declares an asset kind the configuration template is rendered for.
+type validateProvider struct {
+	vendor       string
+	hardwareType string
+	assetType    string
+	configure    devices.Configure
+}
+
+// The vendor/hardwareType/assetType combinations supported by the bmclib Configure providers.
+var validateProviders = []validateProvider{
+	{vendor: dell.VendorID, hardwareType: idrac8.BMCType, assetType: "server", configure: &idrac8.IDrac8{}},
+	{vendor: dell.VendorID, hardwareType: idrac9.BMCType, assetType: "server", configure: &idrac9.IDrac9{}},
+	{vendor: dell.VendorID, hardwareType: m1000e.BMCType, assetType: "chassis", configure: &m1000e.M1000e{}},
+	{vendor: hp.VendorID, hardwareType: ilo.Ilo4, assetType: "server", configure: &ilo.Ilo{}},
+	{vendor: hp.VendorID, hardwareType: ilo.Ilo5, assetType: "server", configure: &ilo.Ilo{}},
+	{vendor: hp.VendorID, hardwareType: c7000.BMCType, assetType: "chassis", configure: &c7000.C7000{}},
+	{vendor: supermicro.VendorID, hardwareType: supermicrox.X10, assetType: "server", configure: &supermicrox.SupermicroX{}},
+	{vendor: supermicro.VendorID, hardwareType: supermicrox.X11, assetType: "server", configure: &supermicrox11.SupermicroX{}},
+}
+
+var validateExtra map[string]string
+
+// validateCmd represents the validate command
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validate bmcbutler.yml and the BMC configuration template.",
+	Run: func(cmd *cobra.Command, args []string) {
+		validate()
+	},
+}
+
+func init() {
+	validateCmd.Flags().StringToStringVarP(&validateExtra, "extra", "", map[string]string{}, "Extra asset attributes to render the template with (e.g --extra state=live,company=acme).")
+
+	rootCmd.AddCommand(validateCmd)
+}
+
+// validate reports problems in bmcbutler.yml, configuration.yml, its overlays and setup.yml,
+// and exits non-zero if any were found.
+func validate() {
+	var problems int
+
+	// The validation report is written to stdout, keep logs out of the way.
+	log.Out = os.Stderr
+
+	report := func(file string, errs ...error) {
+		for _, err := range errs {
+			fmt.Printf("%s: %s\n", file, err)
+			problems++
+		}
+	}
+
+	overrideConfigFromFlags()
+
+	errs, err := runConfig.Validate(runConfig.CfgFile)
+	report(runConfig.CfgFile, errs...)
+
+	// The BMC configuration can't be located without a parsed config.
+	if err == nil && runConfig.BmcCfgDir == "" {
+		err = errors.New("bmcCfgDir not declared, the BMC configuration was not validated")
+	}
+
+	if err != nil {
+		report(runConfig.CfgFile, err)
+		fmt.Printf("%d problem(s) found.\n", problems)
+		os.Exit(1)
+	}
+
+	var store *secrets.Store
+	if runConfig.SecretsFromVault && runConfig.Vault != nil && runConfig.Vault.Token != "" {
+		store, err = secrets.Load(*runConfig.Vault)
+		if err != nil {
+			report(runConfig.CfgFile, fmt.Errorf("unable to load secrets from vault, lookup_secret keys not validated: %s", err))
+			store = nil
+		}
+	}
+
+	if store != nil {
+		report(runConfig.CfgFile, validateCredentialLookups(store)...)
+	}
+
+	// configuration.yml and its overlays are each validated on their own.
+	assetConfig, err := resource.ReadLayers(runConfig.BmcCfgDir)
+	if err != nil {
+		report(runConfig.BmcCfgDir, err)
+		os.Exit(1)
+	}
+
+	for _, file := range assetConfig.Files() {
+		report(filepath.Join(runConfig.BmcCfgDir, file), validateTemplate(assetConfig.Template(file), store, false)...)
+	}
+
+	// setup.yml is optional, it's only read by the setup action.
+	assetSetupFile := fmt.Sprintf("%s/%s", runConfig.BmcCfgDir, "setup.yml")
+	if _, err := os.Stat(assetSetupFile); err == nil {
+		assetSetup, err := resource.ReadYamlTemplate(assetSetupFile)
+		if err != nil {
+			report(assetSetupFile, err)
+		} else {
+			report(assetSetupFile, validateTemplate(assetSetup, store, true)...)
+		}
+	}
+
+	if problems > 0 {
+		fmt.Printf("%d problem(s) found.\n", problems)
+		os.Exit(1)
+	}
+
+	fmt.Println("No problems found.")
+}
+
+// validateTemplate renders the template for each supported asset kind,
+// errors are returned once, along with the asset kinds they were found for.
+// Without a secret store the lookup_secret keys can't be validated, they are listed instead.
+// If setup is set, the template is validated as a one time setup template.
+func validateTemplate(template []byte, store *secrets.Store, setup bool) (errs []error) {
+	locations := runConfig.Locations
+	if len(locations) == 0 {
+		locations = []string{""}
+	}
+
+	found := make(map[string][]string)
+	var order []string
+	var kinds int
+
+	looked := make(map[string]bool)
+	var lookups []string
+
+	for _, provider := range validateProviders {
+		for _, location := range locations {
+			a := &asset.Asset{
+				Serial:       "validate",
+				IPAddress:    "192.0.2.1",
+				IPAddresses:  []string{"192.0.2.1"},
+				Vendor:       provider.vendor,
+				HardwareType: provider.hardwareType,
+				Type:         provider.assetType,
+				Location:     location,
+				Extra:        validateExtra,
+			}
+
+			kinds++
+			kind := fmt.Sprintf("%s/%s/%s", provider.vendor, provider.hardwareType, provider.assetType)
+			if location != "" {
+				kind += "@" + location
+			}
+
+			r := resource.Resource{Log: log, Asset: a, Secrets: store, RedactSecrets: store == nil}
+			config, templateLookups, templateErrs := r.ValidateConfigResources(template)
+			if config != nil && config.SetupChassis != nil && !setup {
+				templateErrs = append(templateErrs, errors.New("setupChassis is ignored by configure, declare it in setup.yml"))
+			}
+
+			for _, err := range templateErrs {
+				if _, exists := found[err.Error()]; !exists {
+					order = append(order, err.Error())
+				}
+				found[err.Error()] = append(found[err.Error()], kind)
+			}
+
+			for _, lookup := range templateLookups {
+				if !looked[lookup] {
+					looked[lookup] = true
+					lookups = append(lookups, lookup)
+				}
+			}
+
+			if config != nil && !setup {
+				fmt.Printf("# %s: %s\n", kind, strings.Join(declaredResources(config, provider.configure.Resources()), ", "))
+			}
+		}
+	}
+
+	if store == nil && len(lookups) > 0 {
+		fmt.Printf("# lookup_secret keys not validated without vault: %s\n", strings.Join(lookups, ", "))
+	}
+
+	for _, e := range order {
+		if len(found[e]) == kinds {
+			errs = append(errs, fmt.Errorf("%s [all asset kinds]", e))
+			continue
+		}
+
+		errs = append(errs, fmt.Errorf("%s [%s]", e, strings.Join(found[e], ", ")))
+	}
+
+	return errs
+}
+
+// validateCredentialLookups returns an error for each lookup_secret:: key
+// declared in bmcbutler.yml that is missing from the secret store.
+func validateCredentialLookups(store *secrets.Store) (errs []error) {
+	lookupPrefix := "lookup_secret::"
+
+	values := []string{}
+	for _, c := range runConfig.Credentials {
+		for _, v := range c {
+			values = append(values, v)
+		}
+	}
+
+	if runConfig.CertSigner != nil && runConfig.CertSigner.LemurSigner != nil {
+		values = append(values, runConfig.CertSigner.LemurSigner.Key)
+	}
+
+	for _, v := range values {
+		if !strings.HasPrefix(v, lookupPrefix) {
+			continue
+		}
+
+		if _, err := store.Get(strings.TrimPrefix(v, lookupPrefix)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errs
+}
+
+// declaredResources returns the provider resources that have configuration declared,
+// these are the resources configure would apply.
+func declaredResources(config *cfgresources.ResourcesConfig, supported []string) (declared []string) {
+	for _, resource := range supported {
+		var isDeclared bool
+
+		switch resource {
+		case "user":
+			isDeclared = config.User != nil
+		case "syslog":
+			isDeclared = config.Syslog != nil
+		case "ntp":
+			isDeclared = config.Ntp != nil
+		case "ldap":
+			isDeclared = config.Ldap != nil
+		case "ldap_group":
+			isDeclared = config.LdapGroups != nil && config.Ldap != nil
+		case "license":
+			isDeclared = config.License != nil
+		case "network":
+			isDeclared = config.Network != nil
+		case "bios":
+			isDeclared = config.Bios != nil
+		case "https_cert":
+			isDeclared = config.HTTPSCert != nil
+		case "power":
+			isDeclared = config.Power != nil
+		}
+
+		if isDeclared {
+			declared = append(declared, resource)
+		}
+	}
+
+	return declared
+}
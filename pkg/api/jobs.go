@@ -0,0 +1,450 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/bmc-toolbox/bmcbutler/pkg/asset"
+	"github.com/bmc-toolbox/bmcbutler/pkg/butler"
+	"github.com/bmc-toolbox/bmcbutler/pkg/butler/configure"
+	"github.com/bmc-toolbox/bmcbutler/pkg/config"
+	"github.com/bmc-toolbox/bmcbutler/pkg/resource"
+)
+
+// Job status values.
+const (
+	StatusRunning    = "running"
+	StatusDone       = "done"
+	StatusCancelling = "cancelling"
+	StatusCancelled  = "cancelled"
+	StatusFailed     = "failed" // The job assets could not all be retrieved from the inventory.
+)
+
+// The number of jobs kept around, finished jobs beyond this are forgotten oldest first.
+const maxJobs = 1000
+
+var (
+	// ErrJobNotFound is returned for unknown job IDs.
+	ErrJobNotFound = errors.New("job not found")
+	// ErrJobFinished is returned when cancelling a job that has finished.
+	ErrJobFinished = errors.New("job has finished")
+)
+
+// JobRequest declares the action to carry out and the assets to carry it out on,
+// the filters are the same as the configure/execute command flags.
+type JobRequest struct {
+	Action  string   `json:"action"` // configure or execute.
+	Serials []string `json:"serials,omitempty"`
+	IPs     []string `json:"ips,omitempty"`
+	All     bool     `json:"all,omitempty"`
+	Servers bool     `json:"servers,omitempty"`
+	Chassis bool     `json:"chassis,omitempty"`
+	Command string   `json:"command,omitempty"` // The command to execute, for execute jobs.
+}
+
+// Validate returns an error if the job request is incomplete.
+func (r *JobRequest) Validate() error {
+	switch r.Action {
+	case asset.ActionConfigure:
+	case asset.ActionExecute:
+		if r.Command == "" {
+			return errors.New("execute jobs expect a command")
+		}
+	default:
+		return fmt.Errorf("unknown action %q, expected configure or execute", r.Action)
+	}
+
+	if !r.All && !r.Servers && !r.Chassis && len(r.Serials) == 0 && len(r.IPs) == 0 {
+		return errors.New("expected one of all/servers/chassis/serials/ips")
+	}
+
+	if r.All && (len(r.Serials) > 0 || len(r.IPs) > 0) {
+		return errors.New("all, serials, ips are mutually exclusive")
+	}
+
+	return nil
+}
+
+// AssetResult is the result of the job action on an asset.
+type AssetResult struct {
+	Serial       string                     `json:"serial"`
+	IPAddress    string                     `json:"ip_address"`
+	Vendor       string                     `json:"vendor"`
+	HardwareType string                     `json:"hardware_type"`
+	Type         string                     `json:"type"`
+	Location     string                     `json:"location"`
+	Success      bool                       `json:"success"`
+	Skipped      bool                       `json:"skipped"`
+	Error        string                     `json:"error,omitempty"`
+	Output       string                     `json:"output,omitempty"`    // The command output, for execute jobs.
+	User         string                     `json:"user,omitempty"`      // The user of the credentials the login worked with.
+	Resources    []configure.ResourceResult `json:"resources,omitempty"` // The outcome of each configuration resource, for configure jobs.
+	Reset        bool                       `json:"reset"`
+	Attempts     int                        `json:"attempts"`
+	Start        time.Time                  `json:"start"`
+	End          time.Time                  `json:"end"`
+}
+
+// Job is a request submitted to the API, its assets are dispatched to butlers.
+type Job struct {
+	ID        string        `json:"id"`
+	Request   JobRequest    `json:"request"`
+	Status    string        `json:"status"`
+	Assets    int           `json:"assets"` // Assets dispatched to butlers so far.
+	Succeeded int           `json:"succeeded"`
+	Failed    int           `json:"failed"`
+	Skipped   int           `json:"skipped"`
+	Created   time.Time     `json:"created"`
+	Finished  *time.Time    `json:"finished,omitempty"`
+	Error     string        `json:"error,omitempty"` // The inventory error, for failed jobs.
+	Results   []AssetResult `json:"results,omitempty"`
+
+	cancel     chan struct{}
+	cancelled  bool
+	dispatched bool // All assets from the inventory were dispatched.
+	retrieved  bool // The asset retriever returned, Error is set if it failed.
+}
+
+// Manager dispatches job assets to butlers and tracks their results.
+type Manager struct {
+	Config     *config.Params
+	Log        *logrus.Logger
+	ButlerChan chan<- butler.Msg
+	ResultChan <-chan butler.Result
+	StopChan   <-chan struct{}
+	// AssetRetriever returns the method that sends the assets matching the config FilterParams
+	// over the inventory channel and closes it once done, or an error if the inventory can't be retrieved.
+	// The method returns an error if the inventory query failed, the job is then marked failed.
+	AssetRetriever func(config *config.Params, inventoryChan chan []asset.Asset) (func() error, error)
+
+	mu   sync.Mutex
+	jobs map[string]*Job
+}
+
+// Run records results received from butlers, until the ResultChan is closed.
+func (m *Manager) Run() {
+	for result := range m.ResultChan {
+		m.record(result)
+	}
+}
+
+// Submit validates the job request and dispatches its assets to butlers.
+func (m *Manager) Submit(request JobRequest) (Job, error) {
+	component := "Submit"
+
+	err := request.Validate()
+	if err != nil {
+		return Job{}, err
+	}
+
+	msg := butler.Msg{AssetExecute: request.Command}
+
+	// The configuration is read for each job, changes are picked up without a restart.
+	if request.Action == asset.ActionConfigure {
+		msg.AssetConfig, err = resource.ReadLayers(m.Config.BmcCfgDir)
+		if err != nil {
+			return Job{}, fmt.Errorf("unable to read BMC configuration: %s", err)
+		}
+	}
+
+	jobConfig := *m.Config
+	jobConfig.FilterParams = &config.FilterParams{
+		All:     request.All,
+		Chassis: request.Chassis,
+		Servers: request.Servers,
+		Serials: strings.Join(request.Serials, ","),
+		Ips:     strings.Join(request.IPs, ","),
+	}
+
+	// A job that can't retrieve its assets is refused, instead of being dispatched.
+	inventoryChan := make(chan []asset.Asset, 5)
+	retrieve, err := m.AssetRetriever(&jobConfig, inventoryChan)
+	if err != nil {
+		return Job{}, fmt.Errorf("unable to retrieve assets: %s", err)
+	}
+
+	id, err := newJobID()
+	if err != nil {
+		return Job{}, err
+	}
+
+	job := &Job{
+		ID:      id,
+		Request: request,
+		Status:  StatusRunning,
+		Created: time.Now(),
+		cancel:  make(chan struct{}),
+	}
+
+	msg.JobID = job.ID
+	msg.Cancel = job.cancel
+
+	m.mu.Lock()
+	if m.jobs == nil {
+		m.jobs = make(map[string]*Job)
+	}
+	m.jobs[job.ID] = job
+	m.prune()
+	snapshot := job.snapshot(false)
+	m.mu.Unlock()
+
+	m.Log.WithFields(logrus.Fields{
+		"component": component,
+		"JobID":     job.ID,
+		"Action":    request.Action,
+		"Serials":   strings.Join(request.Serials, ","),
+		"IPs":       strings.Join(request.IPs, ","),
+	}).Info("Job submitted.")
+
+	go m.retrieve(job, retrieve)
+	go m.dispatch(job, msg, inventoryChan)
+
+	return snapshot, nil
+}
+
+// retrieve runs the job asset retriever, the job is marked failed if the inventory query failed,
+// assets already dispatched are carried out.
+func (m *Manager) retrieve(job *Job, retrieve func() error) {
+	err := retrieve()
+	if err != nil {
+		m.Log.WithFields(logrus.Fields{
+			"component": "retrieve",
+			"JobID":     job.ID,
+			"Error":     err,
+		}).Error("Unable to retrieve job assets from the inventory.")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err != nil {
+		job.Error = err.Error()
+	}
+
+	job.retrieved = true
+	job.updateStatus()
+}
+
+// dispatch sends the job assets received over the inventory channel to butlers.
+func (m *Manager) dispatch(job *Job, msg butler.Msg, inventoryChan <-chan []asset.Asset) {
+	request := job.Request
+
+	var stopped bool
+	for assetList := range inventoryChan {
+		// The inventory channel is drained, so the retriever returns.
+		if stopped {
+			continue
+		}
+
+		for _, a := range assetList {
+			a.Action = request.Action
+			msg.Asset = a
+
+			m.mu.Lock()
+			job.Assets++
+			m.mu.Unlock()
+
+			select {
+			case m.ButlerChan <- msg:
+				continue
+			case <-job.cancel:
+			case <-m.StopChan:
+			}
+
+			m.mu.Lock()
+			job.Assets--
+			m.mu.Unlock()
+
+			stopped = true
+			break
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job.dispatched = true
+	job.updateStatus()
+}
+
+// record adds a butler result to its job.
+func (m *Manager) record(result butler.Result) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, exists := m.jobs[result.JobID]
+	if !exists {
+		return
+	}
+
+	job.Results = append(job.Results, AssetResult{
+		Serial:       result.Asset.Serial,
+		IPAddress:    result.Asset.IPAddress,
+		Vendor:       result.Asset.Vendor,
+		HardwareType: result.Asset.HardwareType,
+		Type:         result.Asset.Type,
+		Location:     result.Asset.Location,
+		Success:      result.Success,
+		Skipped:      result.Skipped,
+		Error:        result.Error,
+		Output:       result.Output,
+		User:         result.User,
+		Resources:    result.Resources,
+		Reset:        result.Reset,
+		Attempts:     result.Attempts,
+		Start:        result.Start,
+		End:          result.End,
+	})
+
+	switch {
+	case result.Skipped:
+		job.Skipped++
+	case result.Success:
+		job.Succeeded++
+	default:
+		job.Failed++
+	}
+
+	job.updateStatus()
+}
+
+// Get returns the job with its results.
+func (m *Manager) Get(id string) (Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, exists := m.jobs[id]
+	if !exists {
+		return Job{}, ErrJobNotFound
+	}
+
+	return job.snapshot(true), nil
+}
+
+// List returns all jobs without their results, most recent first.
+func (m *Manager) List() []Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	jobs := make([]Job, 0, len(m.jobs))
+	for _, job := range m.jobs {
+		jobs = append(jobs, job.snapshot(false))
+	}
+
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].Created.After(jobs[j].Created) })
+
+	return jobs
+}
+
+// Cancel cancels the job, assets butlers have not started on are skipped.
+func (m *Manager) Cancel(id string) (Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, exists := m.jobs[id]
+	if !exists {
+		return Job{}, ErrJobNotFound
+	}
+
+	if job.Finished != nil {
+		return Job{}, ErrJobFinished
+	}
+
+	if !job.cancelled {
+		job.cancelled = true
+		close(job.cancel)
+
+		m.Log.WithFields(logrus.Fields{
+			"component": "Cancel",
+			"JobID":     job.ID,
+		}).Info("Job cancelled.")
+	}
+
+	job.updateStatus()
+
+	return job.snapshot(false), nil
+}
+
+// prune forgets the oldest finished jobs, beyond maxJobs.
+// The caller is expected to hold the lock.
+func (m *Manager) prune() {
+	if len(m.jobs) <= maxJobs {
+		return
+	}
+
+	finished := make([]*Job, 0)
+	for _, job := range m.jobs {
+		if job.Finished != nil {
+			finished = append(finished, job)
+		}
+	}
+
+	sort.Slice(finished, func(i, j int) bool { return finished[i].Created.Before(finished[j].Created) })
+
+	for _, job := range finished {
+		if len(m.jobs) <= maxJobs {
+			return
+		}
+
+		delete(m.jobs, job.ID)
+	}
+}
+
+// updateStatus updates the job status based on the results received so far.
+// The caller is expected to hold the lock.
+func (j *Job) updateStatus() {
+	if j.Finished != nil {
+		return
+	}
+
+	if j.retrieved && j.dispatched && len(j.Results) >= j.Assets {
+		finished := time.Now()
+		j.Finished = &finished
+
+		switch {
+		case j.Error != "":
+			j.Status = StatusFailed
+		case j.cancelled:
+			j.Status = StatusCancelled
+		default:
+			j.Status = StatusDone
+		}
+
+		return
+	}
+
+	if j.cancelled {
+		j.Status = StatusCancelling
+	}
+}
+
+// snapshot returns a copy of the job, safe to use without holding the lock.
+func (j *Job) snapshot(withResults bool) Job {
+	s := *j
+	s.Results = nil
+
+	if withResults {
+		s.Results = append([]AssetResult{}, j.Results...)
+	}
+
+	return s
+}
+
+func newJobID() (string, error) {
+	b := make([]byte, 8)
+
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
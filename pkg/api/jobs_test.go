@@ -0,0 +1,269 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/bmc-toolbox/bmcbutler/pkg/asset"
+	"github.com/bmc-toolbox/bmcbutler/pkg/butler"
+	"github.com/bmc-toolbox/bmcbutler/pkg/config"
+)
+
+// newTestManager returns a Manager with an inventory of the given serials,
+// butlers are faked by handle, which returns the result for each msg received.
+// The inventory query fails for jobs on the serial "error", once the other assets were sent.
+func newTestManager(serials []string, handle func(butler.Msg) butler.Result) *Manager {
+	butlerChan := make(chan butler.Msg)
+	resultChan := make(chan butler.Result)
+
+	m := &Manager{
+		Config:     &config.Params{},
+		Log:        logrus.New(),
+		ButlerChan: butlerChan,
+		ResultChan: resultChan,
+		StopChan:   make(chan struct{}),
+		AssetRetriever: func(c *config.Params, inventoryChan chan []asset.Asset) (func() error, error) {
+			if serials == nil {
+				return nil, errors.New("no inventory source declared in cfg")
+			}
+
+			return func() error {
+				defer close(inventoryChan)
+				for _, serial := range serials {
+					if c.FilterParams.All || strings.Contains(c.FilterParams.Serials, serial) {
+						inventoryChan <- []asset.Asset{{Serial: serial, IPAddresses: []string{"192.0.2.1"}}}
+					}
+				}
+
+				if strings.Contains(c.FilterParams.Serials, "error") {
+					return errors.New("inventory query failed")
+				}
+
+				return nil
+			}, nil
+		},
+	}
+
+	go func() {
+		for msg := range butlerChan {
+			resultChan <- handle(msg)
+		}
+	}()
+
+	go m.Run()
+
+	return m
+}
+
+func waitFinished(t *testing.T, m *Manager, id string) Job {
+	for i := 0; i < 100; i++ {
+		job, err := m.Get(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if job.Finished != nil {
+			return job
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatal("Expected job to finish.")
+	return Job{}
+}
+
+func TestJobRequestValidate(t *testing.T) {
+	cases := []struct {
+		request JobRequest
+		valid   bool
+	}{
+		{JobRequest{Action: "configure", Serials: []string{"a"}}, true},
+		{JobRequest{Action: "execute", All: true, Command: "bmc reset"}, true},
+		{JobRequest{Action: "execute", All: true}, false},
+		{JobRequest{Action: "configure"}, false},
+		{JobRequest{Action: "configure", All: true, IPs: []string{"192.0.2.1"}}, false},
+		{JobRequest{Action: "reboot", All: true}, false},
+	}
+
+	for _, c := range cases {
+		err := c.request.Validate()
+		if (err == nil) != c.valid {
+			t.Errorf("Expected request %+v valid: %t, got error: %v", c.request, c.valid, err)
+		}
+	}
+}
+
+func TestSubmit(t *testing.T) {
+	m := newTestManager([]string{"a", "b", "c"}, func(msg butler.Msg) butler.Result {
+		if msg.Asset.Action != asset.ActionExecute || msg.AssetExecute != "bmc reset" {
+			t.Errorf("Expected execute msg with command, got %+v", msg)
+		}
+
+		return butler.Result{JobID: msg.JobID, Asset: msg.Asset, Success: msg.Asset.Serial != "b"}
+	})
+
+	submitted, err := m.Submit(JobRequest{Action: "execute", Serials: []string{"a", "b"}, Command: "bmc reset"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	job := waitFinished(t, m, submitted.ID)
+	if job.Status != StatusDone || job.Assets != 2 || job.Succeeded != 1 || job.Failed != 1 || len(job.Results) != 2 {
+		t.Fatalf("Unexpected job state: %+v", job)
+	}
+}
+
+// Test a job is refused if its assets can't be retrieved, without affecting other jobs.
+func TestSubmitInventoryError(t *testing.T) {
+	m := newTestManager(nil, func(msg butler.Msg) butler.Result {
+		t.Errorf("Unexpected msg dispatched: %+v", msg)
+		return butler.Result{}
+	})
+
+	_, err := m.Submit(JobRequest{Action: "execute", All: true, Command: "bmc-reset"})
+	if err == nil {
+		t.Fatal("Expected the job to be refused without an inventory source.")
+	}
+
+	if len(m.List()) != 0 {
+		t.Fatalf("Expected no jobs to be recorded, got: %+v", m.List())
+	}
+}
+
+// Test a job is marked failed if the inventory query fails, assets already dispatched are carried out,
+// and later jobs are served.
+func TestRetrieveError(t *testing.T) {
+	m := newTestManager([]string{"a", "b"}, func(msg butler.Msg) butler.Result {
+		return butler.Result{JobID: msg.JobID, Asset: msg.Asset, Success: true}
+	})
+
+	submitted, err := m.Submit(JobRequest{Action: "execute", Serials: []string{"a", "error"}, Command: "bmc reset"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	job := waitFinished(t, m, submitted.ID)
+	if job.Status != StatusFailed || job.Error != "inventory query failed" || job.Succeeded != 1 || len(job.Results) != 1 {
+		t.Fatalf("Unexpected job state: %+v", job)
+	}
+
+	submitted, err = m.Submit(JobRequest{Action: "execute", Serials: []string{"b"}, Command: "bmc reset"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	job = waitFinished(t, m, submitted.ID)
+	if job.Status != StatusDone || job.Succeeded != 1 {
+		t.Fatalf("Unexpected job state: %+v", job)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	release := make(chan struct{})
+
+	m := newTestManager([]string{"a", "b", "c"}, func(msg butler.Msg) butler.Result {
+		<-release
+
+		// Butlers skip assets of cancelled jobs.
+		select {
+		case <-msg.Cancel:
+			return butler.Result{JobID: msg.JobID, Asset: msg.Asset, Skipped: true, Error: butler.ErrCancelled.Error()}
+		default:
+			return butler.Result{JobID: msg.JobID, Asset: msg.Asset, Success: true}
+		}
+	})
+
+	submitted, err := m.Submit(JobRequest{Action: "execute", All: true, Command: "bmc reset"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	job, err := m.Cancel(submitted.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if job.Status != StatusCancelling {
+		t.Fatalf("Expected job to be cancelling, got %s", job.Status)
+	}
+
+	close(release)
+
+	job = waitFinished(t, m, submitted.ID)
+	if job.Status != StatusCancelled || job.Succeeded != 0 || job.Skipped != job.Assets {
+		t.Fatalf("Unexpected job state: %+v", job)
+	}
+
+	if _, err := m.Cancel(submitted.ID); err != ErrJobFinished {
+		t.Fatalf("Expected ErrJobFinished, got %v", err)
+	}
+}
+
+func TestServer(t *testing.T) {
+	m := newTestManager([]string{"a"}, func(msg butler.Msg) butler.Result {
+		return butler.Result{JobID: msg.JobID, Asset: msg.Asset, Success: true}
+	})
+
+	server := httptest.NewServer((&Server{Manager: m, Log: m.Log}).Handler())
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/jobs", "application/json", bytes.NewBufferString(`{"action": "execute", "serials": ["a"], "command": "bmc reset"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("Expected status %d, got %d", http.StatusAccepted, resp.StatusCode)
+	}
+
+	var job Job
+	if err := json.NewDecoder(resp.Body).Decode(&job); err != nil {
+		t.Fatal(err)
+	}
+
+	waitFinished(t, m, job.ID)
+
+	resp, err = http.Get(server.URL + "/jobs/" + job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(&job); err != nil {
+		t.Fatal(err)
+	}
+
+	if job.Status != StatusDone || len(job.Results) != 1 || job.Results[0].Serial != "a" {
+		t.Fatalf("Unexpected job state: %+v", job)
+	}
+
+	resp, err = http.Post(server.URL+"/jobs", "application/json", bytes.NewBufferString(`{"action": "configure"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("Expected status %d for an invalid request, got %d", http.StatusBadRequest, resp.StatusCode)
+	}
+
+	resp, err = http.Get(server.URL + "/jobs/unknown")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("Expected status %d for an unknown job, got %d", http.StatusNotFound, resp.StatusCode)
+	}
+}
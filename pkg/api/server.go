@@ -0,0 +1,127 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Server exposes the job Manager over HTTP.
+//
+//	POST /jobs              submit a JobRequest
+//	GET  /jobs              list jobs
+//	GET  /jobs/<id>         job status with per asset results
+//	POST /jobs/<id>/cancel  cancel a job
+type Server struct {
+	Manager *Manager
+	Log     *logrus.Logger
+}
+
+// Handler returns the http.Handler serving the API.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/jobs", s.jobs)
+	mux.HandleFunc("/jobs/", s.job)
+
+	return mux
+}
+
+// jobs handles /jobs
+func (s *Server) jobs(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.respond(w, http.StatusOK, s.Manager.List())
+	case http.MethodPost:
+		var request JobRequest
+
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+
+		err := decoder.Decode(&request)
+		if err != nil {
+			s.respondError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		err = request.Validate()
+		if err != nil {
+			s.respondError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		job, err := s.Manager.Submit(request)
+		if err != nil {
+			s.respondError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, http.StatusAccepted, job)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		s.respondError(w, http.StatusMethodNotAllowed, nil)
+	}
+}
+
+// job handles /jobs/<id> and /jobs/<id>/cancel
+func (s *Server) job(w http.ResponseWriter, r *http.Request) {
+	path := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/jobs/"), "/"), "/")
+
+	switch {
+	case len(path) == 1 && path[0] != "":
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET")
+			s.respondError(w, http.StatusMethodNotAllowed, nil)
+			return
+		}
+
+		job, err := s.Manager.Get(path[0])
+		if err != nil {
+			s.respondError(w, http.StatusNotFound, err)
+			return
+		}
+
+		s.respond(w, http.StatusOK, job)
+	case len(path) == 2 && path[1] == "cancel":
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST")
+			s.respondError(w, http.StatusMethodNotAllowed, nil)
+			return
+		}
+
+		job, err := s.Manager.Cancel(path[0])
+		switch err {
+		case nil:
+			s.respond(w, http.StatusOK, job)
+		case ErrJobNotFound:
+			s.respondError(w, http.StatusNotFound, err)
+		default:
+			s.respondError(w, http.StatusConflict, err)
+		}
+	default:
+		s.respondError(w, http.StatusNotFound, nil)
+	}
+}
+
+func (s *Server) respond(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	err := json.NewEncoder(w).Encode(body)
+	if err != nil {
+		s.Log.WithFields(logrus.Fields{
+			"component": "apiServer",
+			"Error":     err,
+		}).Warn("Unable to write response.")
+	}
+}
+
+func (s *Server) respondError(w http.ResponseWriter, status int, err error) {
+	message := http.StatusText(status)
+	if err != nil {
+		message = err.Error()
+	}
+
+	s.respond(w, status, map[string]string{"error": message})
+}
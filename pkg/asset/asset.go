@@ -14,6 +14,23 @@
 
 package asset
 
+import (
+	"strings"
+)
+
+// Actions butlers carry out on assets.
+const (
+	ActionConfigure = "configure" // Apply the configuration.
+	ActionExecute   = "execute"   // Execute a command.
+	ActionSetup     = "setup"     // Apply the one time setup configuration.
+	ActionPlan      = "plan"      // List the configuration changes.
+	ActionCollect   = "collect"   // Collect hardware facts.
+	ActionReconcile = "reconcile" // Re-apply configuration that changed or drifted.
+	ActionCerts     = "certs"     // Report, or renew the HTTPS cert.
+	ActionRotate    = "rotate"    // Rotate the password of a BMC user account.
+	ActionFirmware  = "firmware"  // Report if the asset runs the firmware declared in the catalog.
+)
+
 // Asset is a unit of Server BMC/Chassis BMC.
 // Assets are passed around from inventories to butlers.
 type Asset struct {
@@ -28,8 +45,17 @@ type Asset struct {
 	HardwareType string
 	Type         string // "server" or "chassis"
 	Location     string
-	Setup        bool              // If set, butlers will setup the asset.
-	Configure    bool              // If set, butlers will configure the asset.
-	Execute      bool              // If set, butlers will execute given command(s) on the asset.
+	Chassis      string            // The serial of the chassis a blade is installed in, if known.
+	Action       string            // The action butlers carry out on the asset, one of the Action constants.
 	Extra        map[string]string // Any extra params needed to be set in a asset.
 }
+
+// Key returns the asset serial, or its IP addresses for assets without a serial,
+// it identifies the asset in state kept across runs.
+func (a *Asset) Key() string {
+	if a.Serial != "" {
+		return a.Serial
+	}
+
+	return strings.Join(a.IPAddresses, ",")
+}
@@ -0,0 +1,283 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/syslog"
+	"net/http"
+	"net/url"
+	"os"
+	"os/user"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/bmc-toolbox/bmcbutler/pkg/config"
+)
+
+// Outcomes recorded for changes.
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailed  = "failed"
+	OutcomeSkipped = "skipped" // Not applied, e.g disruptive resources outside the maintenance window.
+)
+
+const redacted = "[REDACTED]"
+
+// Secrets in URLs, and declared as key/value pairs in errors or command output.
+var secretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(://[^/\s:@]+:)[^/\s@]+(@)`),
+	regexp.MustCompile(`(?i)((?:password|passwd|secret|token)["']?\s*[:=]\s*["']?)[^\s"',&]+()`),
+}
+
+// Entry is a change applied to a BMC, or attempted.
+type Entry struct {
+	Time         time.Time `json:"time"`
+	Operator     string    `json:"operator"` // The user bmcbutler was run by.
+	Hostname     string    `json:"hostname"`
+	Action       string    `json:"action"`
+	ConfigHash   string    `json:"config_hash,omitempty"` // sha256 of the configuration file applied.
+	Serial       string    `json:"serial"`
+	IPAddress    string    `json:"ip_address"`
+	Vendor       string    `json:"vendor"`
+	HardwareType string    `json:"hardware_type"`
+	Type         string    `json:"type"`
+	Location     string    `json:"location"`
+	Resource     string    `json:"resource,omitempty"`
+	Command      string    `json:"command,omitempty"`
+	Outcome      string    `json:"outcome"`
+	Error        string    `json:"error,omitempty"`
+}
+
+// Journal appends entries to the audit file as JSON lines,
+// and sends them to the syslog and HTTP sinks if declared.
+type Journal struct {
+	Log      *logrus.Logger
+	file     *os.File
+	syslog   *syslog.Writer
+	url      string
+	client   *http.Client
+	httpChan chan []byte
+	httpDone chan struct{}
+	operator string
+	hostname string
+	secrets  []string
+	mu       sync.Mutex // Guards writes to the file and syslog.
+}
+
+// Open opens the journal declared in the configuration, secrets are redacted from all entries.
+func Open(c *config.Audit, secrets []string, log *logrus.Logger) (*Journal, error) {
+	file, err := os.OpenFile(c.File, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open audit journal: %s", err)
+	}
+
+	hostname, _ := os.Hostname()
+
+	j := &Journal{
+		Log:      log,
+		file:     file,
+		operator: Operator(),
+		hostname: hostname,
+	}
+
+	for _, s := range secrets {
+		if s != "" {
+			j.secrets = append(j.secrets, s)
+		}
+	}
+
+	if c.Syslog != "" {
+		var network, address string
+		if c.Syslog != "local" {
+			u, err := url.Parse(c.Syslog)
+			if err != nil {
+				file.Close()
+				return nil, fmt.Errorf("invalid audit syslog address: %s", err)
+			}
+			network, address = u.Scheme, u.Host
+		}
+
+		j.syslog, err = syslog.Dial(network, address, syslog.LOG_INFO|syslog.LOG_DAEMON, "bmcbutler-audit")
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("unable to connect to audit syslog: %s", err)
+		}
+	}
+
+	if c.URL != "" {
+		j.url = c.URL
+		j.client = &http.Client{Timeout: 10 * time.Second}
+		j.httpChan = make(chan []byte, 100)
+		j.httpDone = make(chan struct{})
+		go j.post()
+	}
+
+	return j, nil
+}
+
+// Operator returns the user bmcbutler is run by, the user that invoked sudo if run with sudo.
+func Operator() string {
+	if u := os.Getenv("SUDO_USER"); u != "" {
+		return u
+	}
+
+	u, err := user.Current()
+	if err != nil {
+		return os.Getenv("USER")
+	}
+
+	return u.Username
+}
+
+// Hash returns the sha256 of the configuration, as recorded in entries.
+func Hash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Redact replaces the secrets, and any passwords or tokens found in URLs or key/value pairs, in s.
+func Redact(s string, secrets []string) string {
+	for _, secret := range secrets {
+		if secret != "" {
+			s = strings.Replace(s, secret, redacted, -1)
+		}
+	}
+
+	for _, p := range secretPatterns {
+		s = p.ReplaceAllString(s, "${1}"+redacted+"${2}")
+	}
+
+	return s
+}
+
+// Record appends the entry to the journal, the operator, hostname and time are set if not declared,
+// secrets are redacted from the error and command.
+// Entries that can't be written are logged, they don't fail the action.
+func (j *Journal) Record(e Entry) {
+	if j == nil {
+		return
+	}
+
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	if e.Operator == "" {
+		e.Operator = j.operator
+	}
+
+	if e.Hostname == "" {
+		e.Hostname = j.hostname
+	}
+
+	e.Error = Redact(e.Error, j.secrets)
+	e.Command = Redact(e.Command, j.secrets)
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		j.Log.WithFields(logrus.Fields{"component": "audit", "Error": err}).Error("Unable to encode audit entry.")
+		return
+	}
+
+	j.mu.Lock()
+	_, err = j.file.Write(append(data, '\n'))
+	if err == nil && j.syslog != nil {
+		err = j.syslog.Info(string(data))
+	}
+	j.mu.Unlock()
+
+	if err != nil {
+		j.Log.WithFields(logrus.Fields{
+			"component": "audit",
+			"Serial":    e.Serial,
+			"Resource":  e.Resource,
+			"Command":   e.Command,
+			"Error":     err,
+		}).Error("Unable to write audit entry.")
+	}
+
+	if j.httpChan != nil {
+		j.httpChan <- data
+	}
+}
+
+// post sends entries to the HTTP sink, in the order they were recorded.
+func (j *Journal) post() {
+	defer close(j.httpDone)
+
+	for data := range j.httpChan {
+		resp, err := j.client.Post(j.url, "application/json", bytes.NewReader(data))
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode >= 300 {
+				err = fmt.Errorf("unexpected response status: %s", resp.Status)
+			}
+		}
+
+		if err != nil {
+			j.Log.WithFields(logrus.Fields{
+				"component": "audit",
+				"URL":       j.url,
+				"Error":     err,
+			}).Error("Unable to send audit entry, it is recorded in the journal file.")
+		}
+	}
+}
+
+// Close waits for entries to be sent to the HTTP sink, and closes the journal.
+func (j *Journal) Close() error {
+	if j == nil {
+		return nil
+	}
+
+	if j.httpChan != nil {
+		close(j.httpChan)
+		<-j.httpDone
+	}
+
+	if j.syslog != nil {
+		j.syslog.Close()
+	}
+
+	return j.file.Close()
+}
+
+// Read returns the entries in the journal file the match func returns true for, in the order recorded.
+func Read(file string, match func(Entry) bool) (entries []Entry, err error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var line int
+	for scanner.Scan() {
+		line++
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		var e Entry
+		err = json.Unmarshal(scanner.Bytes(), &e)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse audit journal %s line %d: %s", file, line, err)
+		}
+
+		if match == nil || match(e) {
+			entries = append(entries, e)
+		}
+	}
+
+	return entries, scanner.Err()
+}
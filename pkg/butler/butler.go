@@ -15,6 +15,7 @@
 package butler
 
 import (
+	"io"
 	"sync"
 	"time"
 
@@ -22,17 +23,26 @@ import (
 	"github.com/sirupsen/logrus"
 
 	"github.com/bmc-toolbox/bmcbutler/pkg/asset"
+	"github.com/bmc-toolbox/bmcbutler/pkg/audit"
 	"github.com/bmc-toolbox/bmcbutler/pkg/config"
+	"github.com/bmc-toolbox/bmcbutler/pkg/notify"
+	"github.com/bmc-toolbox/bmcbutler/pkg/resource"
 	"github.com/bmc-toolbox/bmcbutler/pkg/secrets"
+	"github.com/bmc-toolbox/bmcbutler/pkg/tracing"
 )
 
 // Represents butler messages passed over the butlerChan.
 // These declare assets for butlers to carry actions on.
 type Msg struct {
-	Asset        asset.Asset // Asset to be configured
-	AssetConfig  []byte      // The BMC configuration read in from configuration.yml
-	AssetSetup   []byte      // The one-time-setup configuration read from setup.yml
-	AssetExecute string      // Commands to be executed on the BMC
+	Asset         asset.Asset      // Asset to be configured
+	AssetConfig   *resource.Layers // The BMC configuration read in from configuration.yml and its overlays
+	AssetSetup    []byte           // The one-time-setup configuration read from setup.yml
+	AssetExecute  string           // Commands to be executed on the BMC
+	AssetPassword string           // The password to rotate to, a unique password is generated if empty.
+	JobID         string           // The job the asset was submitted with, if any.
+	Cancel        <-chan struct{}  // If closed, the asset is skipped unless a butler already started on it.
+	retry         *retryState      // The outcome of earlier attempts, if the asset was queued for retry.
+	span          *tracing.Span    // The span of the action on the asset, nil unless tracing is declared.
 }
 
 // Holds attributes required to spawn butlers.
@@ -44,10 +54,22 @@ type Butler struct {
 	SyncWG     *sync.WaitGroup
 	WorkerPool *workerpool.WorkerPool
 	interrupt  bool
+	runSpan    *tracing.Span  // The root span of the run, asset spans are its children.
+	mu         sync.Mutex     // Guards interrupt, runSpan.
+	pending    sync.WaitGroup // Assets submitted to the pool, or queued for retry.
+	limiter    *limiter       // Enforces the concurrency limits, nil if none are declared.
 	Secrets    *secrets.Store
+	Journal    *audit.Journal   // If declared, changes applied to assets are recorded here.
+	Notifier   *notify.Notifier // If declared, run events are sent to the webhooks declared for them.
+	Tracer     *tracing.Tracer  // If declared, spans of assets and the operations carried out on them are exported.
+	ResultChan chan<- Result    // If declared, the result for each asset is sent here.
+	Output     io.Writer        // Reports of the certs and firmware actions are written here, os.Stdout if not declared.
+	// Holds the state of assets reconciled, required for the reconcile action.
+	ReconcileState *ReconcileState
 }
 
 // Runner spawns a pool of butlers, waits until they are done.
+// Assets are submitted to the pool once a slot of the concurrency limits declared for them is free.
 func (b *Butler) Runner() {
 	log := b.Log
 	component := "Runner"
@@ -55,15 +77,22 @@ func (b *Butler) Runner() {
 	defer b.SyncWG.Done()
 
 	b.WorkerPool = workerpool.New(b.Config.ButlersToSpawn)
+	b.limiter = newLimiter(b.Config.Concurrency)
+
+	// Assets waiting for a slot of the concurrency limits.
+	var deferred []Msg
+
+	butlerChan := b.ButlerChan
 loop:
-	for {
+	for butlerChan != nil || len(deferred) > 0 {
 		select {
-		case msg, ok := <-b.ButlerChan:
+		case msg, ok := <-butlerChan:
 			if !ok {
 				log.WithFields(logrus.Fields{
 					"component": component,
 				}).Trace("Butler channel closed.")
-				break loop
+				butlerChan = nil
+				continue
 			}
 
 			for b.WorkerPool.WaitingQueueSize() > b.Config.ButlersToSpawn {
@@ -75,20 +104,51 @@ loop:
 				time.Sleep(10 * time.Second)
 			}
 
-			b.WorkerPool.Submit(func() { b.msgHandler(msg) })
+			if !b.limiter.tryAcquire(msg.Asset.Key(), &msg.Asset) {
+				log.WithFields(logrus.Fields{
+					"component": component,
+					"Serial":    msg.Asset.Serial,
+					"Location":  msg.Asset.Location,
+					"Vendor":    msg.Asset.Vendor,
+				}).Debug("Concurrency limits reached, asset deferred.")
+				deferred = append(deferred, msg)
+				continue
+			}
+
+			b.submit(msg)
+		case <-b.limiter.wakeChan():
+			waiting := deferred[:0]
+			for _, msg := range deferred {
+				if b.limiter.tryAcquire(msg.Asset.Key(), &msg.Asset) {
+					b.submit(msg)
+				} else {
+					waiting = append(waiting, msg)
+				}
+			}
+
+			deferred = waiting
 		case <-b.StopChan:
+			b.mu.Lock()
 			b.interrupt = true
+			b.mu.Unlock()
+
 			log.WithFields(logrus.Fields{
 				"component":          component,
 				"Waiting queue size": b.WorkerPool.WaitingQueueSize(),
+				"Deferred":           len(deferred),
 			}).Debug("Interrupt received.")
 
-			// wait for currently running routines, pending tasks are abandoned.
-			b.WorkerPool.Stop()
+			// pending tasks, deferred assets and assets queued for retry are skipped.
+			for _, msg := range deferred {
+				b.submit(msg)
+			}
+
 			break loop
 		}
 	}
 
+	// Assets in progress may be queued for retry, these are handled before the pool is stopped.
+	b.pending.Wait()
 	b.WorkerPool.StopWait()
 
 	log.WithFields(logrus.Fields{
@@ -96,3 +156,22 @@ loop:
 		"Count":     b.Config.ButlersToSpawn,
 	}).Debug("All butlers exited.")
 }
+
+// submit submits the msg to the pool,
+// the concurrency limit slots held for the asset are released once the butler is done,
+// before the asset is queued for retry if it failed with a transient error.
+func (b *Butler) submit(msg Msg) {
+	key := msg.Asset.Key()
+
+	b.pending.Add(1)
+	b.WorkerPool.Submit(func() {
+		defer b.pending.Done()
+
+		retry := b.msgHandler(msg)
+		b.limiter.release(key)
+
+		if retry != nil {
+			b.queueRetry(*retry)
+		}
+	})
+}
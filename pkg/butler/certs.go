@@ -0,0 +1,187 @@
+package butler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/bmc-toolbox/bmcbutler/pkg/asset"
+	"github.com/bmc-toolbox/bmcbutler/pkg/butler/configure"
+	"github.com/bmc-toolbox/bmcbutler/pkg/metrics"
+	"github.com/bmc-toolbox/bmcbutler/pkg/notify"
+	"github.com/bmc-toolbox/bmcbutler/pkg/resource"
+	"github.com/bmc-toolbox/bmcbutler/pkg/tracing"
+	"github.com/bmc-toolbox/bmclib/devices"
+	"github.com/bmc-toolbox/bmclogin"
+)
+
+// CertReport is the HTTPS cert state reported for an asset.
+type CertReport struct {
+	Serial        string     `json:"serial"`
+	IPAddress     string     `json:"ip_address"`
+	Vendor        string     `json:"vendor"`
+	HardwareType  string     `json:"hardware_type"`
+	Location      string     `json:"location"`
+	Subject       string     `json:"subject"`
+	SANs          []string   `json:"sans"`
+	Issuer        string     `json:"issuer"`
+	NotAfter      *time.Time `json:"not_after"`
+	Valid         bool       `json:"valid"`
+	InvalidReason string     `json:"invalid_reason"`
+	Renewed       bool       `json:"renewed"`
+	Error         string     `json:"error,omitempty"` // The cert state could not be retrieved, or renewal failed.
+}
+
+// certReportColumns are the CSV columns of a CertReport.
+var certReportColumns = []string{
+	"serial", "ip_address", "vendor", "hardware_type", "location",
+	"subject", "sans", "issuer", "not_after", "valid", "invalid_reason", "renewed", "error",
+}
+
+// csvRecord returns the report as a CSV record.
+func (r *CertReport) csvRecord() []string {
+	var notAfter string
+	if r.NotAfter != nil {
+		notAfter = r.NotAfter.UTC().Format(time.RFC3339)
+	}
+
+	return []string{
+		r.Serial, r.IPAddress, r.Vendor, r.HardwareType, r.Location,
+		r.Subject, strings.Join(r.SANs, " "), r.Issuer, notAfter,
+		fmt.Sprintf("%t", r.Valid), r.InvalidReason, fmt.Sprintf("%t", r.Renewed), r.Error,
+	}
+}
+
+// WriteCertReportHeader writes the CSV header for cert reports, other formats have no header.
+func WriteCertReportHeader(w io.Writer, format string) error {
+	return writeReportHeader(w, certReportColumns, format)
+}
+
+// certsAsset sets up the bmc connection,
+// reports the current HTTPS cert of the asset,
+// and renews it if it fails validation and --renew was given.
+// A report is recorded on the result for every asset, including ones that fail to login.
+func (b *Butler) certsAsset(config *resource.Layers, asset *asset.Asset, span *tracing.Span, result *Result) (err error) {
+	component := "certsAsset"
+
+	defer b.timeTrack(time.Now(), "certsAsset", asset)
+
+	report := &CertReport{}
+
+	defer func() {
+		report.Serial = asset.Serial
+		report.IPAddress = asset.IPAddress
+		report.Vendor = asset.Vendor
+		report.HardwareType = asset.HardwareType
+		report.Location = asset.Location
+
+		if report.IPAddress == "" {
+			report.IPAddress = strings.Join(asset.IPAddresses, ",")
+		}
+
+		if err != nil {
+			report.Error = err.Error()
+		}
+
+		result.report = &assetReport{record: report, format: b.Config.CertsFormat}
+	}()
+
+	b.Log.WithFields(logrus.Fields{
+		"component": component,
+		"Serial":    asset.Serial,
+		"IPAddress": asset.IPAddresses,
+	}).Debug("Connecting to asset...")
+
+	bmcConn := bmclogin.Params{
+		IpAddresses:     asset.IPAddresses,
+		Credentials:     b.credentials(asset),
+		CheckCredential: true,
+		Retries:         1,
+		StopChan:        b.StopChan,
+	}
+
+	client, loginInfo, err := login(span, bmcConn)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrLoginFailed, err)
+	}
+
+	asset.IPAddress = loginInfo.ActiveIpAddress
+
+	bmc, ok := client.(devices.Bmc)
+	if !ok {
+		if chassis, ok := client.(devices.Cmc); ok {
+			chassis.Close()
+		}
+
+		return errors.New("HTTPS cert management is supported only on server BMCs")
+	}
+
+	defer bmc.Close(context.TODO())
+
+	asset.Type = "server"
+	asset.HardwareType = bmc.HardwareType()
+	asset.Vendor = bmc.Vendor()
+
+	resourceInstance := resource.Resource{Log: b.Log, Asset: asset, Secrets: b.Secrets, Span: span}
+	renderedConfig, err := resourceInstance.LoadLayers(config)
+	if err != nil {
+		return err
+	}
+
+	if renderedConfig == nil {
+		return errors.New("No BMC configuration to be applied!")
+	}
+
+	c := configure.NewBmcConfigurator(bmc, asset, span, nil, renderedConfig, b.Config, b.StopChan, b.Log)
+
+	var status *configure.CertStatus
+	if b.Config.CertsRenew && !b.Config.DryRun {
+		status, report.Renewed, err = c.RenewCertificate(b.Config.CertsRenewBefore)
+	} else {
+		status, err = c.CertificateStatus(b.Config.CertsRenewBefore)
+	}
+
+	if status != nil {
+		report.Subject = status.Subject
+		report.SANs = status.SANs
+		report.Issuer = status.Issuer
+		report.Valid = status.Valid
+		report.InvalidReason = status.InvalidReason
+
+		if !status.NotAfter.IsZero() {
+			report.NotAfter = &status.NotAfter
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	log := b.Log.WithFields(logrus.Fields{
+		"component": component,
+		"Serial":    asset.Serial,
+		"IPAddress": asset.IPAddress,
+		"Vendor":    asset.Vendor,
+		"Cause":     report.InvalidReason,
+	})
+
+	if !report.Valid {
+		metrics.IncrCounter([]string{"certs", "invalid"}, 1)
+	}
+
+	switch {
+	case report.Renewed:
+		log.Info("HTTPS cert renewed.")
+		metrics.IncrCounter([]string{"certs", "renewed"}, 1)
+		b.Notifier.Notify(notify.AssetEvent(notify.EventCertRenewed, asset, nil))
+	case !report.Valid && b.Config.CertsRenew:
+		log.Info("Dry run, HTTPS cert will not be renewed.")
+	}
+
+	return nil
+}
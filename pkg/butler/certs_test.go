@@ -0,0 +1,51 @@
+package butler
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// Test cert reports are written as CSV records matching the header, or JSON lines.
+func TestWriteCertReport(t *testing.T) {
+	notAfter := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	report := &CertReport{Serial: "fooserial", SANs: []string{"bmc.example.com", "10.0.0.1"}, NotAfter: &notAfter, InvalidReason: "CN mismatch, has foo want bar"}
+
+	var buf bytes.Buffer
+	err := WriteCertReportHeader(&buf, "csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = writeReport(&buf, report, "csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(records) != 2 || len(records[0]) != len(records[1]) {
+		t.Fatalf("Expected a header and a record of the same length, got: %v", records)
+	}
+
+	if records[1][0] != "fooserial" || records[1][6] != "bmc.example.com 10.0.0.1" || records[1][8] != "2030-01-01T00:00:00Z" {
+		t.Fatalf("Unexpected record: %v", records[1])
+	}
+
+	buf.Reset()
+	err = writeReport(&buf, report, "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded CertReport
+	err = json.Unmarshal(buf.Bytes(), &decoded)
+	if err != nil || decoded.Serial != "fooserial" || !decoded.NotAfter.Equal(notAfter) {
+		t.Fatalf("Unexpected JSON report: %s", buf.String())
+	}
+}
@@ -0,0 +1,265 @@
+package butler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/bmc-toolbox/bmcbutler/pkg/asset"
+	"github.com/bmc-toolbox/bmcbutler/pkg/tracing"
+	"github.com/bmc-toolbox/bmclib/devices"
+	"github.com/bmc-toolbox/bmclogin"
+)
+
+// Facts holds the hardware facts collected from an asset.
+// Facts that could not be collected are listed in Errors.
+type Facts struct {
+	Serial       string            `json:"serial"`
+	IPAddress    string            `json:"ip_address"`
+	Vendor       string            `json:"vendor"`
+	HardwareType string            `json:"hardware_type"`
+	Type         string            `json:"type"`
+	Location     string            `json:"location"`
+	CollectedAt  time.Time         `json:"collected_at"`
+	Model        string            `json:"model,omitempty"`
+	Version      string            `json:"version,omitempty"` // BMC firmware version.
+	Server       *ServerFacts      `json:"server,omitempty"`
+	Chassis      *ChassisFacts     `json:"chassis,omitempty"`
+	Errors       map[string]string `json:"errors,omitempty"`
+}
+
+// ServerFacts holds facts collected from server BMCs.
+type ServerFacts struct {
+	BiosVersion   string          `json:"bios_version,omitempty"`
+	CPU           string          `json:"cpu,omitempty"`
+	CPUCount      int             `json:"cpu_count,omitempty"`
+	CPUCores      int             `json:"cpu_cores,omitempty"`
+	CPUThreads    int             `json:"cpu_threads,omitempty"`
+	MemoryGB      int             `json:"memory_gb,omitempty"`
+	Disks         []*devices.Disk `json:"disks,omitempty"`
+	Nics          []*devices.Nic  `json:"nics,omitempty"`
+	LicenseName   string          `json:"license_name,omitempty"`
+	LicenseStatus string          `json:"license_status,omitempty"`
+	PowerState    string          `json:"power_state,omitempty"`
+	Slot          int             `json:"slot,omitempty"`
+	ChassisSerial string          `json:"chassis_serial,omitempty"`
+}
+
+// ChassisFacts holds facts collected from chassis BMCs.
+type ChassisFacts struct {
+	Blades            []*devices.Blade `json:"blades,omitempty"`
+	Psus              []*devices.Psu   `json:"psus,omitempty"`
+	Fans              []*devices.Fan   `json:"fans,omitempty"`
+	PsuRedundancyMode string           `json:"psu_redundancy_mode,omitempty"`
+}
+
+// collectAsset sets up the bmc connection,
+// collects hardware facts from the asset and writes them out.
+func (b *Butler) collectAsset(asset *asset.Asset, span *tracing.Span) (err error) {
+	component := "collectAsset"
+
+	defer b.timeTrack(time.Now(), "collectAsset", asset)
+
+	b.Log.WithFields(logrus.Fields{
+		"component": component,
+		"Serial":    asset.Serial,
+		"IPAddress": asset.IPAddresses,
+	}).Debug("Connecting to asset...")
+
+	bmcConn := bmclogin.Params{
+		IpAddresses:     asset.IPAddresses,
+		Credentials:     b.credentials(asset),
+		CheckCredential: true,
+		Retries:         1,
+		StopChan:        b.StopChan,
+	}
+
+	client, loginInfo, err := login(span, bmcConn)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrLoginFailed, err)
+	}
+
+	asset.IPAddress = loginInfo.ActiveIpAddress
+
+	var facts *Facts
+
+	switch clientType := client.(type) {
+	case devices.Bmc:
+		bmc := client.(devices.Bmc)
+		defer bmc.Close(context.TODO())
+
+		asset.Type = "server"
+		asset.HardwareType = bmc.HardwareType()
+		asset.Vendor = bmc.Vendor()
+
+		facts = collectBmcFacts(bmc, asset)
+	case devices.Cmc:
+		chassis := client.(devices.Cmc)
+		defer chassis.Close()
+
+		asset.Type = "chassis"
+		asset.HardwareType = chassis.HardwareType()
+		asset.Vendor = chassis.Vendor()
+
+		facts = collectCmcFacts(chassis, asset)
+	default:
+		b.Log.WithFields(logrus.Fields{
+			"component": component,
+			"Type":      fmt.Sprintf("%s", clientType),
+		}).Warn("Unknown device type.")
+		return fmt.Errorf("Unknown device type \"%s\"!", clientType)
+	}
+
+	for fact, factErr := range facts.Errors {
+		b.Log.WithFields(logrus.Fields{
+			"component": component,
+			"Serial":    asset.Serial,
+			"IPAddress": asset.IPAddress,
+			"Fact":      fact,
+			"Error":     factErr,
+		}).Warn("Unable to collect fact.")
+	}
+
+	return b.writeFacts(facts)
+}
+
+// newFacts returns Facts with the asset attributes set.
+func newFacts(asset *asset.Asset) *Facts {
+	return &Facts{
+		Serial:       asset.Serial,
+		IPAddress:    asset.IPAddress,
+		Vendor:       asset.Vendor,
+		HardwareType: asset.HardwareType,
+		Type:         asset.Type,
+		Location:     asset.Location,
+		CollectedAt:  time.Now().UTC(),
+		Errors:       make(map[string]string),
+	}
+}
+
+// collectBmcFacts collects facts from a server BMC,
+// a fact that fails to be collected doesn't fail the rest.
+func collectBmcFacts(bmc devices.Bmc, asset *asset.Asset) *Facts {
+	var err error
+
+	facts := newFacts(asset)
+	server := &ServerFacts{}
+	facts.Server = server
+
+	collected := func(fact string, err error) {
+		if err != nil {
+			facts.Errors[fact] = err.Error()
+		}
+	}
+
+	facts.Model, err = bmc.Model()
+	collected("model", err)
+
+	facts.Version, err = bmc.Version()
+	collected("version", err)
+
+	server.BiosVersion, err = bmc.BiosVersion()
+	collected("bios_version", err)
+
+	server.CPU, server.CPUCount, server.CPUCores, server.CPUThreads, err = bmc.CPU()
+	collected("cpu", err)
+
+	server.MemoryGB, err = bmc.Memory()
+	collected("memory", err)
+
+	server.Disks, err = bmc.Disks()
+	collected("disks", err)
+
+	server.Nics, err = bmc.Nics()
+	collected("nics", err)
+
+	server.LicenseName, server.LicenseStatus, err = bmc.License()
+	collected("license", err)
+
+	server.PowerState, err = bmc.PowerState()
+	collected("power_state", err)
+
+	server.Slot, err = bmc.Slot()
+	collected("slot", err)
+
+	server.ChassisSerial, err = bmc.ChassisSerial()
+	collected("chassis_serial", err)
+
+	return facts
+}
+
+// collectCmcFacts collects facts from a chassis BMC,
+// a fact that fails to be collected doesn't fail the rest.
+func collectCmcFacts(chassis devices.Cmc, asset *asset.Asset) *Facts {
+	var err error
+
+	facts := newFacts(asset)
+	cmc := &ChassisFacts{}
+	facts.Chassis = cmc
+
+	collected := func(fact string, err error) {
+		if err != nil {
+			facts.Errors[fact] = err.Error()
+		}
+	}
+
+	facts.Model, err = chassis.Model()
+	collected("model", err)
+
+	facts.Version, err = chassis.Version()
+	collected("version", err)
+
+	cmc.Blades, err = chassis.Blades()
+	collected("blades", err)
+
+	cmc.Psus, err = chassis.Psus()
+	collected("psus", err)
+
+	cmc.Fans, err = chassis.Fans()
+	collected("fans", err)
+
+	cmc.PsuRedundancyMode, err = chassis.PsuRedundancyMode()
+	collected("psu_redundancy_mode", err)
+
+	return facts
+}
+
+// writeFacts writes the facts as a JSON document to the collect directory,
+// or as a JSON line on stdout if no directory was declared.
+func (b *Butler) writeFacts(facts *Facts) error {
+	if b.Config.CollectDir == "" {
+		line, err := json.Marshal(facts)
+		if err != nil {
+			return err
+		}
+
+		outputMutex.Lock()
+		defer outputMutex.Unlock()
+
+		_, err = fmt.Fprintln(os.Stdout, string(line))
+		return err
+	}
+
+	document, err := json.MarshalIndent(facts, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filepath.Join(b.Config.CollectDir, factsFileName(facts)), document, 0644)
+}
+
+// factsFileName returns the file name facts for an asset are written to,
+// assets without a serial are identified by their IP address.
+func factsFileName(facts *Facts) string {
+	if facts.Serial != "" {
+		return facts.Serial + ".json"
+	}
+
+	return facts.IPAddress + ".json"
+}
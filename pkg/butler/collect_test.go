@@ -0,0 +1,81 @@
+package butler
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/bmc-toolbox/bmclib/devices"
+
+	"github.com/bmc-toolbox/bmcbutler/pkg/asset"
+)
+
+var errNotSupported = errors.New("not supported")
+
+// factsBmc implements the fact methods of devices.Bmc, the license and slot facts fail.
+type factsBmc struct {
+	devices.Bmc
+}
+
+func (f *factsBmc) Model() (string, error)       { return "PowerEdge R640", nil }
+func (f *factsBmc) Version() (string, error)     { return "4.20.20.20", nil }
+func (f *factsBmc) BiosVersion() (string, error) { return "2.8.1", nil }
+func (f *factsBmc) CPU() (string, int, int, int, error) {
+	return "Intel(R) Xeon(R) Gold 6130", 2, 16, 32, nil
+}
+func (f *factsBmc) Memory() (int, error)             { return 384, nil }
+func (f *factsBmc) Disks() ([]*devices.Disk, error)  { return []*devices.Disk{{Serial: "D1"}}, nil }
+func (f *factsBmc) Nics() ([]*devices.Nic, error)    { return []*devices.Nic{{Name: "bmc"}}, nil }
+func (f *factsBmc) License() (string, string, error) { return "", "", errNotSupported }
+func (f *factsBmc) PowerState() (string, error)      { return "on", nil }
+func (f *factsBmc) Slot() (int, error)               { return -1, errNotSupported }
+func (f *factsBmc) ChassisSerial() (string, error)   { return "", nil }
+
+// factsCmc implements the fact methods of devices.Cmc, the fans fact fails.
+type factsCmc struct {
+	devices.Cmc
+}
+
+func (f *factsCmc) Model() (string, error)             { return "M1000e", nil }
+func (f *factsCmc) Version() (string, error)           { return "6.21", nil }
+func (f *factsCmc) Blades() ([]*devices.Blade, error)  { return []*devices.Blade{{Serial: "B1"}}, nil }
+func (f *factsCmc) Psus() ([]*devices.Psu, error)      { return []*devices.Psu{{Serial: "P1"}}, nil }
+func (f *factsCmc) Fans() ([]*devices.Fan, error)      { return nil, errNotSupported }
+func (f *factsCmc) PsuRedundancyMode() (string, error) { return "Grid", nil }
+
+// Test server facts are collected, a fact that fails is recorded without failing the rest.
+func TestCollectBmcFacts(t *testing.T) {
+	a := &asset.Asset{Serial: "FOO", IPAddress: "192.0.2.1", Vendor: "dell", Type: "server"}
+
+	facts := collectBmcFacts(&factsBmc{}, a)
+	if facts.Serial != "FOO" || facts.Model != "PowerEdge R640" || facts.Version != "4.20.20.20" || facts.Chassis != nil {
+		t.Fatalf("Unexpected facts: %+v", facts)
+	}
+
+	server := facts.Server
+	if server.CPUCount != 2 || server.MemoryGB != 384 || len(server.Disks) != 1 || server.PowerState != "on" {
+		t.Fatalf("Unexpected server facts: %+v", server)
+	}
+
+	if len(facts.Errors) != 2 || facts.Errors["license"] == "" || facts.Errors["slot"] == "" {
+		t.Fatalf("Expected the license and slot facts to fail, got: %v", facts.Errors)
+	}
+}
+
+// Test chassis facts are collected, a fact that fails is recorded without failing the rest.
+func TestCollectCmcFacts(t *testing.T) {
+	a := &asset.Asset{Serial: "BAR", IPAddress: "192.0.2.2", Vendor: "dell", Type: "chassis"}
+
+	facts := collectCmcFacts(&factsCmc{}, a)
+	if facts.Serial != "BAR" || facts.Model != "M1000e" || facts.Server != nil {
+		t.Fatalf("Unexpected facts: %+v", facts)
+	}
+
+	chassis := facts.Chassis
+	if len(chassis.Blades) != 1 || len(chassis.Psus) != 1 || chassis.Fans != nil || chassis.PsuRedundancyMode != "Grid" {
+		t.Fatalf("Unexpected chassis facts: %+v", chassis)
+	}
+
+	if len(facts.Errors) != 1 || facts.Errors["fans"] != errNotSupported.Error() {
+		t.Fatalf("Expected the fans fact to fail, got: %v", facts.Errors)
+	}
+}
@@ -4,22 +4,26 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
 
 	"github.com/bmc-toolbox/bmcbutler/pkg/asset"
 	"github.com/bmc-toolbox/bmcbutler/pkg/butler/configure"
+	"github.com/bmc-toolbox/bmcbutler/pkg/metrics"
 	"github.com/bmc-toolbox/bmcbutler/pkg/resource"
+	"github.com/bmc-toolbox/bmcbutler/pkg/tracing"
 	"github.com/bmc-toolbox/bmclib/devices"
 	"github.com/bmc-toolbox/bmclogin"
-	metrics "github.com/bmc-toolbox/gin-go-metrics"
 )
 
 // applyConfig setups up the bmc connection
 // gets any Asset config templated data rendered
-// applies the asset configuration using bmclib
-func (b *Butler) configureAsset(config []byte, asset *asset.Asset) (err error) {
+// applies the asset configuration using bmclib, nil resources applies all resources
+// or the ones passed with --resources
+// records the outcome of each resource on the result, an error is returned if any failed.
+func (b *Butler) configureAsset(config *resource.Layers, asset *asset.Asset, span *tracing.Span, resources []string, result *Result) (err error) {
 	component := "configureAsset"
 
 	if b.Config.DryRun {
@@ -40,19 +44,27 @@ func (b *Butler) configureAsset(config []byte, asset *asset.Asset) (err error) {
 
 	bmcConn := bmclogin.Params{
 		IpAddresses:     asset.IPAddresses,
-		Credentials:     b.Config.Credentials,
+		Credentials:     b.credentials(asset),
 		CheckCredential: true,
 		Retries:         1,
 		StopChan:        b.StopChan,
 	}
 
-	client, loginInfo, err := bmcConn.Login()
+	client, loginInfo, err := login(span, bmcConn)
 	if err != nil {
-		return err
+		return fmt.Errorf("%w: %s", ErrLoginFailed, err)
 	}
 
 	asset.IPAddress = loginInfo.ActiveIpAddress
 
+	err = b.limitAsset(asset, client)
+	if err != nil {
+		return err
+	}
+	result.User = loginUser(loginInfo)
+
+	var applied *configure.Applied
+
 	switch clientType := client.(type) {
 	case devices.Bmc:
 		bmc := client.(devices.Bmc)
@@ -79,14 +91,18 @@ func (b *Butler) configureAsset(config []byte, asset *asset.Asset) (err error) {
 		}
 
 		// Gets any templated values in the asset configuration rendered.
-		resourceInstance := resource.Resource{Log: b.Log, Asset: asset, Secrets: b.Secrets}
-		renderedConfig := resourceInstance.LoadConfigResources(config)
+		resourceInstance := resource.Resource{Log: b.Log, Asset: asset, Secrets: b.Secrets, Span: span}
+		renderedConfig, err := resourceInstance.LoadLayers(config)
+		if err != nil {
+			return err
+		}
+
 		if renderedConfig == nil {
 			return errors.New("No BMC configuration to be applied!")
 		}
 
-		c := configure.NewBmcConfigurator(bmc, asset, b.Config.Resources, renderedConfig, b.Config, b.StopChan, b.Log)
-		c.Apply()
+		c := configure.NewBmcConfigurator(bmc, asset, span, resources, renderedConfig, b.Config, b.StopChan, b.Log)
+		applied = c.Apply()
 
 		bmc.Close(context.TODO())
 	case devices.Cmc:
@@ -113,28 +129,28 @@ func (b *Butler) configureAsset(config []byte, asset *asset.Asset) (err error) {
 			}).Warn("The CMC reports a different serial than the inventory source!")
 		}
 
-		resourceInstance := resource.Resource{Log: b.Log, Asset: asset, Secrets: b.Secrets}
-		renderedConfig := resourceInstance.LoadConfigResources(config)
+		resourceInstance := resource.Resource{Log: b.Log, Asset: asset, Secrets: b.Secrets, Span: span}
+		renderedConfig, err := resourceInstance.LoadLayers(config)
+		if err != nil {
+			return err
+		}
+
 		if renderedConfig == nil {
 			return errors.New("No CMC configuration to be applied!")
 		}
 
+		// One time setup is applied by the setup action, from setup.yml.
 		if renderedConfig.SetupChassis != nil {
-			s := configure.NewCmcSetup(
-				chassis,
-				asset,
-				b.Config.Resources,
-				renderedConfig.SetupChassis,
-				b.Config,
-				b.StopChan,
-				b.Log,
-			)
-			s.Apply()
+			b.Log.WithFields(logrus.Fields{
+				"component": component,
+				"Serial":    asset.Serial,
+				"IPAddress": asset.IPAddress,
+			}).Warn("setupChassis declared in configuration.yml is ignored, declare it in setup.yml and run bmcbutler setup.")
 		}
 
 		// Apply configuration
-		c := configure.NewCmcConfigurator(chassis, asset, b.Config.Resources, renderedConfig, b.StopChan, b.Log)
-		c.Apply()
+		c := configure.NewCmcConfigurator(chassis, asset, span, resources, renderedConfig, b.Config, b.StopChan, b.Log)
+		applied = c.Apply()
 
 		chassis.Close()
 	default:
@@ -145,5 +161,16 @@ func (b *Butler) configureAsset(config []byte, asset *asset.Asset) (err error) {
 		return fmt.Errorf("Unknown device type \"%s\"!", clientType)
 	}
 
-	return err
+	b.recordApplied(config.Hash(asset), asset, applied)
+
+	// Resources applied on earlier attempts are recorded on the result already.
+	result.Resources = append(result.Resources, applied.Resources...)
+	result.Reset = result.Reset || applied.Reset
+
+	failed := applied.Failed()
+	if len(failed) > 0 {
+		return fmt.Errorf("resources failed to apply: %s", strings.Join(failed, ", "))
+	}
+
+	return nil
 }
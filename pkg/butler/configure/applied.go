@@ -0,0 +1,53 @@
+package configure
+
+// ResourceResult is the outcome of applying a configuration resource.
+type ResourceResult struct {
+	Resource string `json:"resource"`
+	Success  bool   `json:"success"`
+	Error    string `json:"error,omitempty"`
+	Skipped  bool   `json:"skipped,omitempty"` // The resource was not applied, for the Reason given.
+	Reason   string `json:"reason,omitempty"`
+}
+
+// Applied is the outcome of applying configuration to a BMC.
+type Applied struct {
+	Resources []ResourceResult
+	Reset     bool // The BMC was reset for configuration to take effect.
+}
+
+// record records the outcome of applying the resource.
+func (a *Applied) record(resource string, err error) {
+	r := ResourceResult{Resource: resource, Success: err == nil}
+	if err != nil {
+		r.Error = err.Error()
+	}
+
+	a.Resources = append(a.Resources, r)
+}
+
+// skip records the resource was not applied, and why.
+func (a *Applied) skip(resource string, reason string) {
+	a.Resources = append(a.Resources, ResourceResult{Resource: resource, Skipped: true, Reason: reason})
+}
+
+// Failed returns the resources that failed to apply, skipped resources are not failures.
+func (a *Applied) Failed() (failed []string) {
+	for _, r := range a.Resources {
+		if !r.Success && !r.Skipped {
+			failed = append(failed, r.Resource)
+		}
+	}
+
+	return failed
+}
+
+// Skipped returns the resources that were not applied.
+func (a *Applied) Skipped() (skipped []string) {
+	for _, r := range a.Resources {
+		if r.Skipped {
+			skipped = append(skipped, r.Resource)
+		}
+	}
+
+	return skipped
+}
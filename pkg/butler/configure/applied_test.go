@@ -0,0 +1,58 @@
+package configure
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/bmc-toolbox/bmcbutler/pkg/asset"
+	"github.com/bmc-toolbox/bmcbutler/pkg/config"
+)
+
+// Test resources that failed to apply are listed in the order they were applied.
+func TestAppliedFailed(t *testing.T) {
+	applied := &Applied{}
+	applied.record("ntp", nil)
+	applied.record("syslog", errors.New("connection reset"))
+	applied.record("user", errors.New("invalid role"))
+
+	failed := applied.Failed()
+	if len(failed) != 2 || failed[0] != "syslog" || failed[1] != "user" {
+		t.Fatalf("Expected syslog and user to fail, got %v", failed)
+	}
+
+	if applied.Resources[1].Error != "connection reset" || applied.Resources[0].Error != "" {
+		t.Fatalf("Unexpected resource results: %+v", applied.Resources)
+	}
+}
+
+// Test disruptive resources are recorded skipped with the reason outside the asset location maintenance window.
+func TestAppliedSkipDisruptive(t *testing.T) {
+	maintenance := &config.Maintenance{
+		Disruptive: []string{"network"},
+		Windows:    map[string]*config.MaintenanceWindow{"ams4": {Ranges: []string{"Sat,Sun 00:00-24:00"}}},
+	}
+
+	wednesday := time.Date(2020, 6, 10, 12, 0, 0, 0, time.UTC)
+	a := &asset.Asset{Serial: "FOO", Location: "ams4"}
+
+	applied := &Applied{}
+	if applied.skipDisruptive("ntp", a, maintenance, wednesday, logrus.New()) {
+		t.Fatal("Expected resources not declared disruptive applied.")
+	}
+
+	if applied.skipDisruptive("network", a, maintenance, wednesday.AddDate(0, 0, 3), logrus.New()) {
+		t.Fatal("Expected disruptive resources applied within the maintenance window.")
+	}
+
+	if !applied.skipDisruptive("network", a, maintenance, wednesday, logrus.New()) {
+		t.Fatal("Expected disruptive resources skipped outside the maintenance window.")
+	}
+
+	skipped := applied.Skipped()
+	if len(skipped) != 1 || skipped[0] != "network" || applied.Resources[0].Reason == "" || len(applied.Failed()) != 0 {
+		t.Fatalf("Expected network skipped with the reason, got %+v", applied.Resources)
+	}
+}
@@ -28,21 +28,9 @@ import (
 // iDrac needs a reset
 // POST https://10.193.251.25/data?set=iDracReset:1
 func (b *Bmc) certificateSetup() (bool, error) {
-	if b.config.HTTPSCert.Attributes.CommonName == "" {
-		return false, fmt.Errorf("Declared certificate configuration requires a commonName")
-	}
-
-	// validate the CN doesn't begin with a '.' - for cases where the template variables aren't rendered.
-	if strings.HasPrefix(b.config.HTTPSCert.Attributes.CommonName, ".") {
-		return false, fmt.Errorf("Declared certificate commonName invalid: %s", b.config.HTTPSCert.Attributes.CommonName)
-	}
-
-	// replace any underscores with hyphens
-	b.config.HTTPSCert.Attributes.CommonName = strings.Replace(b.config.HTTPSCert.Attributes.CommonName, "_", "-", -1)
-	commonName := b.config.HTTPSCert.Attributes.CommonName
-
-	if b.butlerConfig.CertSigner == nil {
-		return false, fmt.Errorf("No cert signer declared in butler configuration")
+	err := b.validateCertConfig()
+	if err != nil {
+		return false, err
 	}
 
 	// Retrieve current cert(s)
@@ -74,9 +62,43 @@ func (b *Bmc) certificateSetup() (bool, error) {
 		"Cause":        invalidReason,
 	}).Trace("Current certificate does not match configuration.")
 
+	return b.renewCertificate(csrCapability)
+}
+
+// validateCertConfig validates and normalizes the declared cert configuration.
+func (b *Bmc) validateCertConfig() error {
+	if b.config.HTTPSCert == nil || b.config.HTTPSCert.Attributes == nil {
+		return fmt.Errorf("No certificate attributes declared in configuration")
+	}
+
+	if b.config.HTTPSCert.Attributes.CommonName == "" {
+		return fmt.Errorf("Declared certificate configuration requires a commonName")
+	}
+
+	// validate the CN doesn't begin with a '.' - for cases where the template variables aren't rendered.
+	if strings.HasPrefix(b.config.HTTPSCert.Attributes.CommonName, ".") {
+		return fmt.Errorf("Declared certificate commonName invalid: %s", b.config.HTTPSCert.Attributes.CommonName)
+	}
+
+	// replace any underscores with hyphens
+	b.config.HTTPSCert.Attributes.CommonName = strings.Replace(b.config.HTTPSCert.Attributes.CommonName, "_", "-", -1)
+
+	if b.butlerConfig.CertSigner == nil {
+		return fmt.Errorf("No cert signer declared in butler configuration")
+	}
+
+	return nil
+}
+
+// renewCertificate generates a CSR, gets it signed and uploads the signed cert,
+// returns true if the BMC needs a reset.
+func (b *Bmc) renewCertificate(csrCapability bool) (bool, error) {
 	var csr []byte
 	var privateKey []byte
 	var privateKeyFileName string
+	var err error
+
+	commonName := b.config.HTTPSCert.Attributes.CommonName
 
 	// BMC doesn't support generating a CSR
 	if !csrCapability {
@@ -117,10 +139,109 @@ func (b *Bmc) certificateSetup() (bool, error) {
 	return resetBMC, nil
 }
 
+// CertStatus describes the current HTTPS cert of a BMC.
+type CertStatus struct {
+	Subject       string
+	SANs          []string
+	Issuer        string
+	NotAfter      time.Time
+	Valid         bool
+	InvalidReason string
+}
+
+// CertificateStatus returns the current HTTPS cert validated against the declared cert configuration,
+// if renewBefore is set, it overrides the renewBeforeExpiry declared.
+// With no cert configuration declared, only the cert expiry is validated.
+func (b *Bmc) CertificateStatus(renewBefore time.Duration) (*CertStatus, error) {
+	// work on a copy of the configuration, certificateSetup normalizes the CN in the same manner.
+	config := cfgresources.HTTPSCert{}
+	if b.config.HTTPSCert != nil {
+		config = *b.config.HTTPSCert
+	}
+
+	attributes := cfgresources.HTTPSCertAttributes{}
+	if config.Attributes != nil {
+		attributes = *config.Attributes
+		attributes.CommonName = strings.Replace(attributes.CommonName, "_", "-", -1)
+	}
+	config.Attributes = &attributes
+
+	if renewBefore > 0 {
+		config.RenewBeforeExpiry = renewBefore
+	}
+
+	certs, _, err := b.bmc.CurrentHTTPSCert()
+	if err != nil {
+		return nil, fmt.Errorf("Error retreiving current cert: %s", err)
+	}
+
+	return b.certStatus(certs, &config), nil
+}
+
+// RenewCertificate runs only the CSR, sign and upload flow,
+// if the current cert fails validation or expires within renewBefore,
+// the BMC is reset if required. Returns the status of the cert before it was renewed.
+func (b *Bmc) RenewCertificate(renewBefore time.Duration) (status *CertStatus, renewed bool, err error) {
+	err = b.validateCertConfig()
+	if err != nil {
+		return nil, false, err
+	}
+
+	if renewBefore > 0 {
+		b.config.HTTPSCert.RenewBeforeExpiry = renewBefore
+	}
+
+	certs, csrCapability, err := b.bmc.CurrentHTTPSCert()
+	if err != nil {
+		return nil, false, fmt.Errorf("Error retreiving current cert: %s", err)
+	}
+
+	status = b.certStatus(certs, b.config.HTTPSCert)
+	if status.Valid {
+		return status, false, nil
+	}
+
+	reset, err := b.renewCertificate(csrCapability)
+	if err != nil {
+		return status, false, err
+	}
+
+	if reset {
+		b.resetBmc([]string{"https_cert"})
+	}
+
+	return status, true, nil
+}
+
+// certStatus returns the status of the first of the given certs.
+func (b *Bmc) certStatus(certs []*x509.Certificate, config *cfgresources.HTTPSCert) *CertStatus {
+	status := &CertStatus{}
+	status.InvalidReason, status.Valid = b.validateCert(certs, config)
+
+	if len(certs) == 0 {
+		return status
+	}
+
+	cert := certs[0]
+	status.Subject = cert.Subject.String()
+	status.Issuer = cert.Issuer.String()
+	status.NotAfter = cert.NotAfter
+	status.SANs = append(status.SANs, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		status.SANs = append(status.SANs, ip.String())
+	}
+
+	return status
+}
+
 // signCSR signs the given csr with the configured signer
-func (b *Bmc) signCSR(csr []byte, commonName string) ([]byte, error) {
+func (b *Bmc) signCSR(csr []byte, commonName string) (crt []byte, err error) {
 	config := b.butlerConfig.CertSigner
 
+	span := b.span.Child("sign_csr")
+	span.SetAttribute("signer", config.Client)
+	defer func() { span.End(err) }()
+
 	var cmd string
 	var args []string
 	env := make(map[string]string)
@@ -0,0 +1,75 @@
+package configure
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/bmc-toolbox/bmclib/cfgresources"
+	"github.com/sirupsen/logrus"
+)
+
+// testCert returns a self signed cert for the given CN and IP, expiring after the given duration.
+func testCert(t *testing.T, commonName string, ip string, expiry time.Duration) *x509.Certificate {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		IPAddresses:  []net.IP{net.ParseIP(ip)},
+		DNSNames:     []string{commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(expiry),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cert
+}
+
+// Test the cert status lists the cert attributes and why it fails validation.
+func TestCertStatus(t *testing.T) {
+	b := &Bmc{logger: logrus.New(), ip: "10.0.0.1"}
+
+	config := &cfgresources.HTTPSCert{
+		RenewBeforeExpiry:  720 * time.Hour,
+		ValidateAttributes: []string{"commonName"},
+		Attributes:         &cfgresources.HTTPSCertAttributes{CommonName: "bmc.example.com"},
+	}
+
+	status := b.certStatus([]*x509.Certificate{testCert(t, "bmc.example.com", "10.0.0.1", 24*time.Hour*365)}, config)
+	if !status.Valid || status.Subject != "CN=bmc.example.com" || len(status.SANs) != 2 {
+		t.Fatalf("Unexpected cert status: %+v", status)
+	}
+
+	status = b.certStatus([]*x509.Certificate{testCert(t, "bmc.example.com", "10.0.0.1", 24*time.Hour)}, config)
+	if status.Valid || status.InvalidReason == "" {
+		t.Fatalf("Expected a cert expiring within the renew window to be invalid: %+v", status)
+	}
+
+	status = b.certStatus([]*x509.Certificate{testCert(t, "foo.example.com", "10.0.0.1", 24*time.Hour*365)}, config)
+	if status.Valid {
+		t.Fatalf("Expected a cert with a mismatched CN to be invalid: %+v", status)
+	}
+
+	status = b.certStatus(nil, config)
+	if status.Valid || status.NotAfter != (time.Time{}) {
+		t.Fatalf("Expected no certs to be invalid: %+v", status)
+	}
+}
@@ -2,8 +2,11 @@ package configure
 
 import (
 	"strings"
+	"time"
 
 	"github.com/bmc-toolbox/bmcbutler/pkg/asset"
+	"github.com/bmc-toolbox/bmcbutler/pkg/config"
+	"github.com/bmc-toolbox/bmcbutler/pkg/tracing"
 	"github.com/bmc-toolbox/bmclib/cfgresources"
 	"github.com/bmc-toolbox/bmclib/devices"
 	"github.com/sirupsen/logrus"
@@ -12,10 +15,12 @@ import (
 // Cmc struct declares attributes required to apply configuration.
 type Cmc struct {
 	asset        *asset.Asset
+	span         *tracing.Span // The span of the action on the asset, nil unless tracing is declared.
 	bmc          devices.Cmc
 	resources    []string
 	configure    devices.Configure
 	config       *cfgresources.ResourcesConfig
+	butlerConfig *config.Params
 	logger       *logrus.Logger
 	ip           string
 	serial       string
@@ -27,14 +32,18 @@ type Cmc struct {
 // NewCmcConfigurator returns a new configure struct to apply configuration.
 func NewCmcConfigurator(bmc devices.Cmc,
 	asset *asset.Asset,
+	span *tracing.Span,
 	resources []string,
 	config *cfgresources.ResourcesConfig,
+	butlerConfig *config.Params,
 	stopChan <-chan struct{},
 	logger *logrus.Logger) *Cmc {
 
 	return &Cmc{
 		// asset to be setup
 		asset: asset,
+		// spans of resources applied are children of the asset span
+		span: span,
 		// client is of type devices.Bmc
 		bmc: bmc,
 		// if --resources was passed, only these resources will be applied
@@ -43,6 +52,7 @@ func NewCmcConfigurator(bmc devices.Cmc,
 		// this is possible since devices.Bmc embeds the Configure interface.
 		configure:    bmc.(devices.Configure),
 		config:       config,
+		butlerConfig: butlerConfig,
 		logger:       logger,
 		stopChan:     stopChan,
 		ip:           asset.IPAddress,
@@ -52,8 +62,8 @@ func NewCmcConfigurator(bmc devices.Cmc,
 	}
 }
 
-// Apply applies configuration.
-func (b *Cmc) Apply() { //nolint: gocyclo
+// Apply applies configuration, and returns the outcome for each resource.
+func (b *Cmc) Apply() (applied *Applied) { //nolint: gocyclo
 	var interrupt bool
 	go func() { <-b.stopChan; interrupt = true }()
 
@@ -71,6 +81,8 @@ func (b *Cmc) Apply() { //nolint: gocyclo
 
 	var failed, success []string
 
+	applied = &Applied{}
+
 	b.logger.WithFields(logrus.Fields{
 		"Vendor":       b.vendor,
 		"HardwareType": b.hardwareType,
@@ -93,6 +105,14 @@ func (b *Cmc) Apply() { //nolint: gocyclo
 			break
 		}
 
+		// Disruptive resources are applied only within the maintenance window of the asset location.
+		if applied.skipDisruptive(resource, b.asset, b.butlerConfig.Maintenance, time.Now(), b.logger) {
+			continue
+		}
+
+		span := b.span.Child("apply " + resource)
+		span.SetAttribute("resource", resource)
+
 		switch resource {
 		case "user":
 			if b.config.User != nil {
@@ -140,6 +160,9 @@ func (b *Cmc) Apply() { //nolint: gocyclo
 			}).Warn("Unknown resource.")
 		}
 
+		applied.record(resource, err)
+		span.End(err)
+
 		if err != nil {
 			failed = append(failed, resource)
 			b.logger.WithFields(logrus.Fields{
@@ -173,7 +196,7 @@ func (b *Cmc) Apply() { //nolint: gocyclo
 			"applied":      strings.Join(success, ", "),
 			"failed":       strings.Join(failed, ", "),
 		}).Warn("One or more resources failed to apply.")
-		return
+		return applied
 	}
 
 	b.logger.WithFields(logrus.Fields{
@@ -184,4 +207,6 @@ func (b *Cmc) Apply() { //nolint: gocyclo
 		"success":      true,
 		"applied":      strings.Join(success, ", "),
 	}).Info("CMC configuration actions successful.")
+
+	return applied
 }
@@ -0,0 +1,35 @@
+package configure
+
+import (
+	"time"
+
+	"github.com/bmc-toolbox/bmcbutler/pkg/asset"
+	"github.com/bmc-toolbox/bmcbutler/pkg/config"
+	"github.com/sirupsen/logrus"
+)
+
+// skipDisruptive returns true if the resource disrupts the BMC and the asset location is outside its maintenance window at the given time,
+// the resource is then recorded as skipped, with the reason.
+func (a *Applied) skipDisruptive(resource string, asset *asset.Asset, maintenance *config.Maintenance, t time.Time, logger *logrus.Logger) bool {
+	if !maintenance.Disrupts(resource) {
+		return false
+	}
+
+	allowed, reason := maintenance.Allowed(asset.Location, t)
+	if allowed {
+		return false
+	}
+
+	a.skip(resource, reason)
+	logger.WithFields(logrus.Fields{
+		"resource":     resource,
+		"Vendor":       asset.Vendor,
+		"HardwareType": asset.HardwareType,
+		"Serial":       asset.Serial,
+		"IPAddress":    asset.IPAddress,
+		"Location":     asset.Location,
+		"Reason":       reason,
+	}).Info("Disruptive resource skipped.")
+
+	return true
+}
@@ -0,0 +1,350 @@
+package configure
+
+import (
+	"crypto/x509"
+	"fmt"
+	"strings"
+
+	"github.com/bmc-toolbox/bmclib/cfgresources"
+	"gopkg.in/yaml.v2"
+)
+
+// ResourcePlan declares the change configure would make to a resource.
+type ResourcePlan struct {
+	Resource string
+	// Current is the resource state as read from the BMC,
+	// empty if bmclib does not expose the current state of the resource.
+	Current string
+	// Desired is the resource state as declared in the rendered configuration.
+	Desired string
+	// Readable is set when the current state could be read from the BMC.
+	Readable bool
+	// Change is set when the resource is expected to be changed by configure.
+	Change bool
+	// Reason describes why the resource will be changed.
+	Reason string
+}
+
+// desiredState returns the state of a resource declared in the rendered configuration,
+// nil if the resource is not declared.
+type desiredState func(config *cfgresources.ResourcesConfig) interface{}
+
+// chassisResources are the resources bmcbutler configures on CMCs.
+var chassisResources = map[string]desiredState{
+	"user": func(c *cfgresources.ResourcesConfig) interface{} {
+		if c.User == nil {
+			return nil
+		}
+		return redactUsers(c.User)
+	},
+	"syslog": func(c *cfgresources.ResourcesConfig) interface{} {
+		if c.Syslog == nil {
+			return nil
+		}
+		return c.Syslog
+	},
+	"ntp": func(c *cfgresources.ResourcesConfig) interface{} {
+		if c.Ntp == nil {
+			return nil
+		}
+		return c.Ntp
+	},
+	"ldap": func(c *cfgresources.ResourcesConfig) interface{} {
+		if c.Ldap == nil {
+			return nil
+		}
+		return c.Ldap
+	},
+	"ldap_group": func(c *cfgresources.ResourcesConfig) interface{} {
+		if c.LdapGroups == nil || c.Ldap == nil {
+			return nil
+		}
+		return c.LdapGroups.Groups
+	},
+	"license": func(c *cfgresources.ResourcesConfig) interface{} {
+		if c.License == nil {
+			return nil
+		}
+		return &cfgresources.License{Key: "<redacted>"}
+	},
+	"network": func(c *cfgresources.ResourcesConfig) interface{} {
+		if c.Network == nil {
+			return nil
+		}
+		return c.Network
+	},
+}
+
+// serverResources are the resources bmcbutler configures on BMCs,
+// https_cert and power are planned by the Bmc since their current state is read.
+var serverResources = map[string]desiredState{
+	"bios": func(c *cfgresources.ResourcesConfig) interface{} {
+		if c.Bios == nil {
+			return nil
+		}
+		return c.Bios
+	},
+}
+
+func init() {
+	for resource, state := range chassisResources {
+		serverResources[resource] = state
+	}
+}
+
+// planner returns the plan for a resource the current state is read for,
+// declared is false if the resource is not declared in the configuration.
+type planner func() (plan ResourcePlan, declared bool)
+
+// planResources returns the plan for each of the resources,
+// resources without a planner are expected to be changed since bmclib doesn't expose their current state.
+func planResources(resources []string, config *cfgresources.ResourcesConfig, known map[string]desiredState, planners map[string]planner) (plans []ResourcePlan) {
+	for _, resource := range resources {
+		if p, exists := planners[resource]; exists {
+			if plan, declared := p(); declared {
+				plans = append(plans, plan)
+			}
+			continue
+		}
+
+		state, exists := known[resource]
+		if !exists {
+			plans = append(plans, ResourcePlan{Resource: resource, Reason: "Unknown resource."})
+			continue
+		}
+
+		desired := state(config)
+		if desired == nil {
+			continue
+		}
+
+		plans = append(plans, ResourcePlan{
+			Resource: resource,
+			Desired:  toYaml(desired),
+			Change:   true,
+			Reason:   "Current state not exposed by bmclib, resource will be applied.",
+		})
+	}
+
+	return plans
+}
+
+// Plan compares the current BMC state with the rendered configuration,
+// without applying any configuration.
+func (b *Bmc) Plan() (plans []ResourcePlan) {
+	var resources []string
+
+	if len(b.resources) > 0 {
+		resources = b.resources
+	} else {
+		resources = b.configure.Resources()
+	}
+
+	return planResources(resources, b.config, serverResources, map[string]planner{
+		"https_cert": b.planCertificate,
+		"power":      b.planPower,
+	})
+}
+
+// Plan compares the current CMC state with the rendered configuration,
+// without applying any configuration.
+func (b *Cmc) Plan() (plans []ResourcePlan) {
+	var resources []string
+
+	if len(b.resources) > 0 {
+		resources = b.resources
+	} else {
+		resources = b.configure.Resources()
+	}
+
+	return planResources(resources, b.config, chassisResources, nil)
+}
+
+// planPower plans the power configuration, along with the current power state of the server.
+func (b *Bmc) planPower() (plan ResourcePlan, declared bool) {
+	if b.config.Power == nil {
+		return plan, false
+	}
+
+	plan = ResourcePlan{
+		Resource: "power",
+		Desired:  toYaml(b.config.Power),
+		Change:   true,
+	}
+
+	state, err := b.bmc.PowerState()
+	if err != nil {
+		state = fmt.Sprintf("unknown (%s)", err)
+	}
+
+	plan.Reason = fmt.Sprintf("Power settings not exposed by bmclib, resource will be applied (server power state: %s).", state)
+
+	return plan, true
+}
+
+// planCertificate compares the current HTTPS cert with the declared cert configuration.
+func (b *Bmc) planCertificate() (plan ResourcePlan, declared bool) {
+	if b.config.HTTPSCert == nil {
+		return plan, false
+	}
+
+	plan = ResourcePlan{Resource: "https_cert"}
+
+	// work on a copy of the attributes, certificateSetup normalizes the CN in the same manner.
+	desired := *b.config.HTTPSCert.Attributes
+	desired.CommonName = strings.Replace(desired.CommonName, "_", "-", -1)
+	desired.Email = ""
+	plan.Desired = toYaml(desired)
+
+	certs, _, err := b.bmc.CurrentHTTPSCert()
+	if err != nil {
+		plan.Change = true
+		plan.Reason = fmt.Sprintf("Error retreiving current cert: %s", err)
+		return plan, true
+	}
+
+	plan.Readable = true
+	if len(certs) > 0 {
+		plan.Current = toYaml(certAttributes(certs[0])) + fmt.Sprintf("notAfter: %s\n", certs[0].NotAfter)
+	}
+
+	config := *b.config.HTTPSCert
+	config.Attributes = &desired
+
+	invalidReason, valid := b.validateCert(certs, &config)
+	if !valid {
+		plan.Change = true
+		plan.Reason = invalidReason
+	}
+
+	return plan, true
+}
+
+// certAttributes returns the subject attributes of the given cert,
+// in the form they are declared in the configuration.
+func certAttributes(cert *x509.Certificate) cfgresources.HTTPSCertAttributes {
+	first := func(s []string) string {
+		if len(s) == 0 {
+			return ""
+		}
+		return s[0]
+	}
+
+	attributes := cfgresources.HTTPSCertAttributes{
+		CommonName:       cert.Subject.CommonName,
+		OrganizationName: first(cert.Subject.Organization),
+		OrganizationUnit: first(cert.Subject.OrganizationalUnit),
+		Locality:         first(cert.Subject.Locality),
+		StateName:        first(cert.Subject.Province),
+		CountryCode:      first(cert.Subject.Country),
+	}
+
+	if len(cert.IPAddresses) > 0 {
+		attributes.SubjectAltName = cert.IPAddresses[0].String()
+	}
+
+	return attributes
+}
+
+// redactUsers returns a copy of the user configuration without passwords.
+func redactUsers(users []*cfgresources.User) []*cfgresources.User {
+	redacted := make([]*cfgresources.User, 0, len(users))
+	for _, u := range users {
+		r := *u
+		if r.Password != "" {
+			r.Password = "<redacted>"
+		}
+		redacted = append(redacted, &r)
+	}
+
+	return redacted
+}
+
+func toYaml(v interface{}) string {
+	out, err := yaml.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("<unable to marshal: %s>\n", err)
+	}
+
+	return string(out)
+}
+
+// FormatPlan returns a human readable representation of the given resource plans.
+func FormatPlan(plans []ResourcePlan) string {
+	var s strings.Builder
+
+	for _, plan := range plans {
+		switch {
+		case !plan.Change:
+			fmt.Fprintf(&s, "  %s: no changes.\n", plan.Resource)
+			continue
+		case plan.Readable:
+			fmt.Fprintf(&s, "~ %s: %s\n", plan.Resource, plan.Reason)
+		default:
+			fmt.Fprintf(&s, "? %s: %s\n", plan.Resource, plan.Reason)
+		}
+
+		for _, line := range diffLines(splitLines(plan.Current), splitLines(plan.Desired)) {
+			fmt.Fprintf(&s, "    %s\n", line)
+		}
+	}
+
+	return s.String()
+}
+
+func splitLines(s string) []string {
+	s = strings.TrimRight(s, "\n")
+	if s == "" {
+		return []string{}
+	}
+
+	return strings.Split(s, "\n")
+}
+
+// diffLines returns a line based diff of a and b,
+// lines only in a are prefixed with '-', lines only in b with '+'.
+func diffLines(a, b []string) (diff []string) {
+	// longest common subsequence lengths, lcs[i][j] holds the LCS of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			diff = append(diff, "  "+a[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			diff = append(diff, "- "+a[i])
+			i++
+		default:
+			diff = append(diff, "+ "+b[j])
+			j++
+		}
+	}
+
+	for ; i < len(a); i++ {
+		diff = append(diff, "- "+a[i])
+	}
+
+	for ; j < len(b); j++ {
+		diff = append(diff, "+ "+b[j])
+	}
+
+	return diff
+}
@@ -0,0 +1,80 @@
+package configure
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bmc-toolbox/bmclib/cfgresources"
+	"github.com/bmc-toolbox/bmclib/devices"
+)
+
+// Test diffLines marks removed, added and unchanged lines.
+func TestDiffLines(t *testing.T) {
+	current := []string{"commonName: foo", "organizationName: Acme", "locality: Amsterdam"}
+	desired := []string{"commonName: bar", "organizationName: Acme", "locality: Amsterdam", "countryCode: NL"}
+
+	expected := []string{
+		"- commonName: foo",
+		"+ commonName: bar",
+		"  organizationName: Acme",
+		"  locality: Amsterdam",
+		"+ countryCode: NL",
+	}
+
+	diff := diffLines(current, desired)
+	if strings.Join(diff, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("Unexpected diff, got:\n%s\nwant:\n%s", strings.Join(diff, "\n"), strings.Join(expected, "\n"))
+	}
+}
+
+// Test user passwords are never part of a plan.
+func TestRedactUsers(t *testing.T) {
+	users := []*cfgresources.User{{Name: "Administrator", Password: "hunter2", Role: "admin"}}
+
+	redacted := toYaml(redactUsers(users))
+	if strings.Contains(redacted, "hunter2") {
+		t.Fatal("Expected password to be redacted.")
+	}
+
+	if users[0].Password != "hunter2" {
+		t.Fatal("Expected user configuration to be left untouched.")
+	}
+}
+
+// planBmc implements the methods of devices.Bmc Plan reads, calling any other method panics.
+type planBmc struct {
+	devices.Bmc
+}
+
+func (p *planBmc) Resources() []string         { return []string{"syslog", "ntp", "power", "bios", "foo"} }
+func (p *planBmc) PowerState() (string, error) { return "on", nil }
+
+// Test resources are planned as declared, along with the server power state.
+func TestBmcPlan(t *testing.T) {
+	bmc := &planBmc{}
+	b := &Bmc{
+		bmc:       bmc,
+		configure: bmc,
+		config: &cfgresources.ResourcesConfig{
+			Syslog: &cfgresources.Syslog{Server: "syslog.example.com"},
+			Power:  &cfgresources.Power{},
+		},
+	}
+
+	plans := b.Plan()
+	if len(plans) != 3 {
+		t.Fatalf("Expected plans for syslog, power and the unknown resource, got: %+v", plans)
+	}
+
+	if plans[0].Resource != "syslog" || !plans[0].Change || !strings.Contains(plans[0].Desired, "syslog.example.com") {
+		t.Fatalf("Unexpected syslog plan: %+v", plans[0])
+	}
+
+	if plans[1].Resource != "power" || !strings.Contains(plans[1].Reason, "server power state: on") {
+		t.Fatalf("Expected the power plan to include the power state: %+v", plans[1])
+	}
+
+	if plans[2].Resource != "foo" || plans[2].Change {
+		t.Fatalf("Expected an unknown resource to be listed without a change: %+v", plans[2])
+	}
+}
@@ -3,9 +3,11 @@ package configure
 import (
 	"context"
 	"strings"
+	"time"
 
 	"github.com/bmc-toolbox/bmcbutler/pkg/asset"
 	"github.com/bmc-toolbox/bmcbutler/pkg/config"
+	"github.com/bmc-toolbox/bmcbutler/pkg/tracing"
 	"github.com/bmc-toolbox/bmclib/cfgresources"
 	"github.com/bmc-toolbox/bmclib/devices"
 	"github.com/sirupsen/logrus"
@@ -15,6 +17,7 @@ import (
 type Bmc struct {
 	bmc          devices.Bmc
 	asset        *asset.Asset
+	span         *tracing.Span // The span of the action on the asset, nil unless tracing is declared.
 	resources    []string
 	configure    devices.Configure
 	config       *cfgresources.ResourcesConfig
@@ -30,6 +33,7 @@ type Bmc struct {
 // NewBmcConfigurator returns a new configure struct to apply configuration.
 func NewBmcConfigurator(bmc devices.Bmc,
 	asset *asset.Asset,
+	span *tracing.Span,
 	resources []string,
 	config *cfgresources.ResourcesConfig,
 	butlerConfig *config.Params,
@@ -39,6 +43,8 @@ func NewBmcConfigurator(bmc devices.Bmc,
 	return &Bmc{
 		// asset to be setup
 		asset: asset,
+		// spans of resources applied are children of the asset span
+		span: span,
 		// client is of type devices.Bmc
 		bmc: bmc,
 		// devices.Bmc is type asserted to apply configuration,
@@ -57,9 +63,9 @@ func NewBmcConfigurator(bmc devices.Bmc,
 	}
 }
 
-// Apply applies configuration.
+// Apply applies configuration, and returns the outcome for each resource.
 // nolint: gocyclo
-func (b *Bmc) Apply() {
+func (b *Bmc) Apply() (applied *Applied) {
 	var interrupt bool
 
 	go func() { <-b.stopChan; interrupt = true }()
@@ -77,6 +83,8 @@ func (b *Bmc) Apply() {
 
 	var failed, success []string
 
+	applied = &Applied{}
+
 	// reset causes are appended here
 	var resetCause []string
 
@@ -104,6 +112,14 @@ func (b *Bmc) Apply() {
 			break
 		}
 
+		// Disruptive resources are applied only within the maintenance window of the asset location.
+		if applied.skipDisruptive(resource, b.asset, b.butlerConfig.Maintenance, time.Now(), b.logger) {
+			continue
+		}
+
+		span := b.span.Child("apply " + resource)
+		span.SetAttribute("resource", resource)
+
 		switch resource {
 		case "user":
 			if b.config.User != nil {
@@ -163,6 +179,10 @@ func (b *Bmc) Apply() {
 			}).Warn("Unknown resource.")
 		}
 
+		applied.record(resource, err)
+		span.SetAttribute("reset", reset)
+		span.End(err)
+
 		if err != nil {
 			failed = append(failed, resource)
 			b.logger.WithFields(logrus.Fields{
@@ -192,28 +212,7 @@ func (b *Bmc) Apply() {
 
 	// Reset BMC if needed.
 	if len(resetCause) > 0 {
-		b.logger.WithFields(logrus.Fields{
-			"Vendor":       b.vendor,
-			"HardwareType": b.hardwareType,
-			"Serial":       b.serial,
-			"IPAddress":    b.ip,
-			"cause":        strings.Join(resetCause, ", "),
-		}).Info("BMC to be reset.")
-
-		// Close the current connection - so we don't leave connections hanging.
-		b.bmc.Close(context.TODO())
-
-		// Reset BMC using SSH.
-		_, err := b.bmc.PowerCycleBmc()
-		if err != nil {
-			b.logger.WithFields(logrus.Fields{
-				"Vendor":       b.vendor,
-				"HardwareType": b.hardwareType,
-				"Serial":       b.serial,
-				"IPAddress":    b.ip,
-				"Error":        err,
-			}).Warn("BMC reset failed.")
-		}
+		applied.Reset = b.resetBmc(resetCause)
 	}
 
 	if len(failed) > 0 {
@@ -226,7 +225,7 @@ func (b *Bmc) Apply() {
 			"applied":      strings.Join(success, ", "),
 			"failed":       strings.Join(failed, ", "),
 		}).Warn("One or more resources failed to apply.")
-		return
+		return applied
 	}
 
 	b.logger.WithFields(logrus.Fields{
@@ -237,4 +236,40 @@ func (b *Bmc) Apply() {
 		"success":      true,
 		"applied":      strings.Join(success, ", "),
 	}).Info("BMC configuration actions successful.")
+
+	return applied
+}
+
+// resetBmc resets the BMC, for configuration that requires a reset to take effect,
+// returns true if the reset succeeded.
+func (b *Bmc) resetBmc(cause []string) bool {
+	b.logger.WithFields(logrus.Fields{
+		"Vendor":       b.vendor,
+		"HardwareType": b.hardwareType,
+		"Serial":       b.serial,
+		"IPAddress":    b.ip,
+		"cause":        strings.Join(cause, ", "),
+	}).Info("BMC to be reset.")
+
+	span := b.span.Child("bmc_reset")
+	span.SetAttribute("cause", strings.Join(cause, ", "))
+
+	// Close the current connection - so we don't leave connections hanging.
+	b.bmc.Close(context.TODO())
+
+	// Reset BMC using SSH.
+	_, err := b.bmc.PowerCycleBmc()
+	span.End(err)
+	if err != nil {
+		b.logger.WithFields(logrus.Fields{
+			"Vendor":       b.vendor,
+			"HardwareType": b.hardwareType,
+			"Serial":       b.serial,
+			"IPAddress":    b.ip,
+			"Error":        err,
+		}).Warn("BMC reset failed.")
+		return false
+	}
+
+	return true
 }
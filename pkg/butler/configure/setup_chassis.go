@@ -57,8 +57,8 @@ func NewCmcSetup(
 	}
 }
 
-// Apply applies one time setup configuration.
-func (b *CmcSetup) Apply() { //nolint: gocyclo
+// Apply applies one time setup configuration, and returns the outcome for each resource.
+func (b *CmcSetup) Apply() (applied *Applied) { //nolint: gocyclo
 	//defer b.metricsEmitter.MeasureRuntime(
 	//	[]string{"butler", "setupChassis_runtime"},
 	//	time.Now(),
@@ -88,6 +88,8 @@ func (b *CmcSetup) Apply() { //nolint: gocyclo
 
 	var failed, success []string
 
+	applied = &Applied{}
+
 	b.log.WithFields(logrus.Fields{
 		"Vendor":    b.vendor,
 		"Model":     b.model,
@@ -110,6 +112,11 @@ func (b *CmcSetup) Apply() { //nolint: gocyclo
 			break
 		}
 
+		// Disruptive resources are applied only within the maintenance window of the asset location.
+		if applied.skipDisruptive(resource, b.asset, b.butlerConfig.Maintenance, time.Now(), b.log) {
+			continue
+		}
+
 		err = b.ensurePoweredUp()
 		if err != nil {
 			b.log.WithFields(logrus.Fields{
@@ -120,7 +127,8 @@ func (b *CmcSetup) Apply() { //nolint: gocyclo
 				"IPAddress": b.ip,
 				"Error":     err,
 			}).Warn("Chassis power status")
-			return
+			applied.record(resource, err)
+			return applied
 		}
 
 		b.log.WithFields(logrus.Fields{
@@ -162,6 +170,8 @@ func (b *CmcSetup) Apply() { //nolint: gocyclo
 			}).Warn("Unknown setup resource.")
 		}
 
+		applied.record(resource, err)
+
 		if err != nil {
 			setupActionSuccess = false
 			failed = append(failed, resource)
@@ -199,6 +209,8 @@ func (b *CmcSetup) Apply() { //nolint: gocyclo
 		"applied":      strings.Join(success, ", "),
 		"unsuccessful": strings.Join(failed, ", "),
 	}).Info("Chassis setup actions done.")
+
+	return applied
 }
 
 // Post method is when a chassis was setup successfully.
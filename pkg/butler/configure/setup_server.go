@@ -0,0 +1,49 @@
+package configure
+
+import (
+	"github.com/bmc-toolbox/bmcbutler/pkg/asset"
+	"github.com/bmc-toolbox/bmcbutler/pkg/config"
+	"github.com/bmc-toolbox/bmcbutler/pkg/tracing"
+	"github.com/bmc-toolbox/bmclib/cfgresources"
+	"github.com/bmc-toolbox/bmclib/devices"
+	"github.com/sirupsen/logrus"
+)
+
+// BmcSetup struct holds attributes for server one time setup.
+//
+// bmclib doesn't declare a setup interface for servers,
+// one time setup applies the configuration resources declared in setup.yml
+// (e.g bios, network, license) through the Configure interface.
+type BmcSetup struct {
+	configurator *Bmc
+	log          *logrus.Logger
+}
+
+// NewBmcSetup returns a new struct to apply one time setup configuration.
+func NewBmcSetup(
+	bmc devices.Bmc,
+	asset *asset.Asset,
+	span *tracing.Span,
+	resources []string,
+	config *cfgresources.ResourcesConfig,
+	butlerConfig *config.Params,
+	stopChan <-chan struct{},
+	logger *logrus.Logger) *BmcSetup {
+
+	return &BmcSetup{
+		configurator: NewBmcConfigurator(bmc, asset, span, resources, config, butlerConfig, stopChan, logger),
+		log:          logger,
+	}
+}
+
+// Apply applies one time setup configuration, and returns the outcome for each resource.
+func (b *BmcSetup) Apply() *Applied {
+	b.log.WithFields(logrus.Fields{
+		"Vendor":       b.configurator.vendor,
+		"HardwareType": b.configurator.hardwareType,
+		"Serial":       b.configurator.serial,
+		"IPAddress":    b.configurator.asset.IPAddress,
+	}).Debug("Applying server setup configuration.")
+
+	return b.configurator.Apply()
+}
@@ -0,0 +1,276 @@
+package butler
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/bmc-toolbox/bmcbutler/pkg/asset"
+	"github.com/bmc-toolbox/bmcbutler/pkg/metrics"
+	"github.com/bmc-toolbox/bmcbutler/pkg/resource"
+	"github.com/bmc-toolbox/bmcbutler/pkg/tracing"
+	"github.com/bmc-toolbox/bmclib/cfgresources"
+	"github.com/bmc-toolbox/bmclib/devices"
+	"github.com/bmc-toolbox/bmclogin"
+)
+
+// Generated passwords include at least one character of each class,
+// symbols are limited to ones accepted by BMCs of all supported vendors.
+var passwordClasses = []string{
+	"abcdefghijklmnopqrstuvwxyz",
+	"ABCDEFGHIJKLMNOPQRSTUVWXYZ",
+	"0123456789",
+	"-_.",
+}
+
+// GeneratePassword returns a random password of the given length.
+func GeneratePassword(length int) (string, error) {
+	if length < len(passwordClasses) {
+		return "", fmt.Errorf("password length should be at least %d", len(passwordClasses))
+	}
+
+	randomIndex := func(n int) (int, error) {
+		i, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+		if err != nil {
+			return 0, err
+		}
+		return int(i.Int64()), nil
+	}
+
+	password := make([]byte, 0, length)
+	for _, class := range passwordClasses {
+		i, err := randomIndex(len(class))
+		if err != nil {
+			return "", err
+		}
+		password = append(password, class[i])
+	}
+
+	all := strings.Join(passwordClasses, "")
+	for len(password) < length {
+		i, err := randomIndex(len(all))
+		if err != nil {
+			return "", err
+		}
+		password = append(password, all[i])
+	}
+
+	// shuffle, so the class characters are not always leading.
+	for i := len(password) - 1; i > 0; i-- {
+		j, err := randomIndex(i + 1)
+		if err != nil {
+			return "", err
+		}
+		password[i], password[j] = password[j], password[i]
+	}
+
+	return string(password), nil
+}
+
+// ErrUnverified is the Result error prefix for assets the password was set on,
+// but a login with it could not be verified.
+var ErrUnverified = errors.New("Unable to verify login with the rotated password")
+
+// credentials returns the credentials to login to the asset with,
+// preceded by the ones unique to the asset, for assets rotated with a unique password.
+func (b *Butler) credentials(asset *asset.Asset) []map[string]string {
+	if b.Secrets == nil {
+		return b.Config.Credentials
+	}
+
+	credentials, err := b.Secrets.AssetCredentials(b.Config.Credentials, asset.Serial)
+	if err != nil {
+		b.Log.WithFields(logrus.Fields{
+			"component": "credentials",
+			"Serial":    asset.Serial,
+			"Error":     err,
+		}).Warn("Unable to read credentials unique to the asset, logging in with the shared credentials.")
+	}
+
+	return credentials
+}
+
+// rotateCredential sets up the bmc connection,
+// sets the password of the rotated user account to the given password,
+// and verifies a login with the new password works.
+// If no password is given, a password unique to the asset is generated,
+// and written to vault once a login with it is verified.
+func (b *Butler) rotateCredential(config *resource.Layers, password string, asset *asset.Asset, span *tracing.Span) (err error) {
+	component := "rotateCredential"
+	user := b.Config.RotateUser
+	key := b.Config.RotateKey
+
+	if b.Config.DryRun {
+		b.Log.WithFields(logrus.Fields{
+			"component": component,
+			"User":      user,
+		}).Info("Dry run, credential won't be rotated.")
+		return nil
+	}
+
+	perAsset := password == ""
+	if perAsset {
+		if asset.Serial == "" {
+			return errors.New("Asset has no serial, a unique password can't be stored.")
+		}
+
+		password, err = GeneratePassword(b.Config.RotateLength)
+		if err != nil {
+			return err
+		}
+	}
+
+	defer b.timeTrack(time.Now(), "rotateCredential", asset)
+	defer metrics.MeasureRuntime([]string{"butler", "rotate_runtime"}, time.Now())
+
+	bmcConn := bmclogin.Params{
+		IpAddresses:     asset.IPAddresses,
+		Credentials:     b.credentials(asset),
+		CheckCredential: true,
+		Retries:         1,
+		StopChan:        b.StopChan,
+	}
+
+	client, loginInfo, err := login(span, bmcConn)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrLoginFailed, err)
+	}
+
+	asset.IPAddress = loginInfo.ActiveIpAddress
+
+	var configurator devices.Configure
+
+	switch clientType := client.(type) {
+	case devices.Bmc:
+		bmc := client.(devices.Bmc)
+		defer bmc.Close(context.TODO())
+
+		asset.Type = "server"
+		asset.HardwareType = bmc.HardwareType()
+		asset.Vendor = bmc.Vendor()
+		configurator = bmc
+	case devices.Cmc:
+		chassis := client.(devices.Cmc)
+		defer chassis.Close()
+
+		asset.Type = "chassis"
+		asset.HardwareType = chassis.HardwareType()
+		asset.Vendor = chassis.Vendor()
+		configurator = chassis
+	default:
+		b.Log.WithFields(logrus.Fields{
+			"component": component,
+			"Type":      fmt.Sprintf("%s", clientType),
+		}).Warn("Unknown device type.")
+		return fmt.Errorf("Unknown device type \"%s\"!", clientType)
+	}
+
+	log := b.Log.WithFields(logrus.Fields{
+		"component": component,
+		"Serial":    asset.Serial,
+		"IPAddress": asset.IPAddress,
+		"Vendor":    asset.Vendor,
+		"User":      user,
+		"VaultPath": b.Secrets.AssetPath(asset.Serial),
+	})
+
+	// The role and other attributes of the account are taken from configuration.yml.
+	resourceInstance := resource.Resource{Log: b.Log, Asset: asset, Secrets: b.Secrets, Span: span}
+	renderedConfig, err := resourceInstance.LoadLayers(config)
+	if err != nil {
+		return err
+	}
+
+	account, err := rotatedAccount(renderedConfig, user, password)
+	if err != nil {
+		return err
+	}
+
+	err = configurator.User([]*cfgresources.User{account})
+	if err != nil {
+		return fmt.Errorf("Unable to set password: %s", err)
+	}
+
+	// The BMC may take a moment to accept the new password.
+	verify := bmclogin.Params{
+		IpAddresses:     []string{asset.IPAddress},
+		Credentials:     []map[string]string{{user: password}},
+		CheckCredential: true,
+		Retries:         3,
+		StopChan:        b.StopChan,
+	}
+
+	verifyClient, _, err := login(span, verify)
+	if err != nil {
+		// The asset may have accepted the shared password without a working login,
+		// it's kept as a secret unique to the asset so it can still be logged into,
+		// a unique password is left out of vault, its secret stays unchanged.
+		if perAsset {
+			log.Error("Asset may be left with a unique password that could not be verified, the password was not written to vault!")
+		} else {
+			b.StoreAssetPassword(asset, password)
+		}
+		return fmt.Errorf("%s: %s", ErrUnverified, err)
+	}
+
+	switch c := verifyClient.(type) {
+	case devices.Bmc:
+		c.Close(context.TODO())
+	case devices.Cmc:
+		c.Close()
+	}
+
+	// A unique password is written to vault once verified only.
+	if perAsset {
+		err = b.Secrets.WriteAssetSecret(asset.Serial, key, password)
+		if err != nil {
+			log.WithFields(logrus.Fields{"Error": err}).Error("Asset was set a verified password that could not be written to vault!")
+			return fmt.Errorf("Unable to write password to vault: %s", err)
+		}
+	}
+
+	log.Info("Password set and verified.")
+
+	return nil
+}
+
+// StoreAssetPassword writes a password the asset may have been left with to the secrets unique to the asset,
+// if that fails the asset needs attention, the password is not logged.
+func (b *Butler) StoreAssetPassword(asset *asset.Asset, password string) {
+	log := b.Log.WithFields(logrus.Fields{
+		"component": "StoreAssetPassword",
+		"Serial":    asset.Serial,
+		"IPAddress": asset.IPAddress,
+		"User":      b.Config.RotateUser,
+		"VaultPath": b.Secrets.AssetPath(asset.Serial),
+	})
+
+	err := b.Secrets.WriteAssetSecret(asset.Serial, b.Config.RotateKey, password)
+	if err != nil {
+		log.WithFields(logrus.Fields{"Error": err}).Error("Asset may be left with a password not stored in vault!")
+		return
+	}
+
+	log.Warn("Asset may be left with the rotated password, written to vault as a secret unique to the asset.")
+}
+
+// rotatedAccount returns the user account declared in the configuration with the given password.
+func rotatedAccount(config *cfgresources.ResourcesConfig, user string, password string) (*cfgresources.User, error) {
+	if config != nil {
+		for _, u := range config.User {
+			if u.Name == user {
+				account := *u
+				account.Password = password
+				return &account, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("User %s is not declared in configuration.yml", user)
+}
@@ -0,0 +1,66 @@
+package butler
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bmc-toolbox/bmclib/cfgresources"
+)
+
+// Test generated passwords have the given length and a character of each class.
+func TestGeneratePassword(t *testing.T) {
+	_, err := GeneratePassword(len(passwordClasses) - 1)
+	if err == nil {
+		t.Fatal("Expected an error for a password too short to include each class.")
+	}
+
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		password, err := GeneratePassword(20)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(password) != 20 {
+			t.Fatalf("Expected a password of length 20, got %d", len(password))
+		}
+
+		for _, class := range passwordClasses {
+			if !strings.ContainsAny(password, class) {
+				t.Fatalf("Expected password %s to include one of %s", password, class)
+			}
+		}
+
+		if seen[password] {
+			t.Fatalf("Password %s generated twice.", password)
+		}
+		seen[password] = true
+	}
+}
+
+// Test the rotated account keeps the declared attributes, without changing the configuration.
+func TestRotatedAccount(t *testing.T) {
+	config := &cfgresources.ResourcesConfig{
+		User: []*cfgresources.User{
+			{Name: "Administrator", Password: "hunter2", Role: "admin", Enable: true},
+		},
+	}
+
+	account, err := rotatedAccount(config, "Administrator", "newpassword")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if account.Password != "newpassword" || account.Role != "admin" || !account.Enable {
+		t.Fatalf("Unexpected rotated account: %+v", account)
+	}
+
+	if config.User[0].Password != "hunter2" {
+		t.Fatal("Expected the configuration to be left untouched.")
+	}
+
+	_, err = rotatedAccount(config, "Ops", "newpassword")
+	if err == nil {
+		t.Fatal("Expected an error for a user not declared.")
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -12,12 +14,12 @@ import (
 	"github.com/bmc-toolbox/bmclogin"
 
 	"github.com/bmc-toolbox/bmcbutler/pkg/asset"
+	"github.com/bmc-toolbox/bmcbutler/pkg/tracing"
 )
 
-// applyConfig setups up the bmc connection
-// gets any config templated data rendered
-// applies the configuration using bmclib
-func (b *Butler) executeCommand(command string, asset *asset.Asset) (err error) {
+// executeCommand sets up the bmc connection,
+// executes the command on the asset and records the command output on the result.
+func (b *Butler) executeCommand(command string, asset *asset.Asset, span *tracing.Span, result *Result) (err error) {
 	component := "executeCommand"
 	log := b.Log
 
@@ -32,56 +34,41 @@ func (b *Butler) executeCommand(command string, asset *asset.Asset) (err error)
 
 	bmcConn := bmclogin.Params{
 		IpAddresses:     asset.IPAddresses,
-		Credentials:     b.Config.Credentials,
+		Credentials:     b.credentials(asset),
 		CheckCredential: false,
 		Retries:         1,
+		StopChan:        b.StopChan,
 	}
 
-	client, loginInfo, err := bmcConn.Login()
+	client, loginInfo, err := login(span, bmcConn)
 	if err != nil {
-		return err
+		return fmt.Errorf("%w: %s", ErrLoginFailed, err)
 	}
 
 	asset.IPAddress = loginInfo.ActiveIpAddress
 
+	err = b.limitAsset(asset, client)
+	if err != nil {
+		return err
+	}
+	result.User = loginUser(loginInfo)
+
+	var success bool
+	var output string
+
+	defer func() { result.Output = output }()
+
 	switch client.(type) {
 	case devices.Bmc:
 		bmc := client.(devices.Bmc)
-		success, output, err := b.executeCommandBmc(bmc, command)
-		if err != nil || !success {
-			log.WithFields(logrus.Fields{
-				"component":         component,
-				"Serial":            asset.Serial,
-				"AssetType":         asset.Type,
-				"Vendor":            asset.Vendor, // At this point, the vendor may or may not be known.
-				"Location":          asset.Location,
-				"IPAddress":         asset.IPAddress,
-				"Command":           command,
-				"CommandSuccessful": success,
-				"Error":             err,
-				"Output":            output,
-			}).Warn("Command execute returned error.")
-		} else {
-			log.WithFields(logrus.Fields{
-				"component":         component,
-				"Serial":            asset.Serial,
-				"AssetType":         asset.Type,
-				"Vendor":            asset.Vendor,
-				"Location":          asset.Location,
-				"IPAddress":         asset.IPAddress,
-				"Command":           command,
-				"CommandSuccessful": success,
-				"Output":            output,
-			}).Debug("Command successfully executed.")
-		}
-		bmc.Close(context.TODO())
+		defer bmc.Close(context.TODO())
+
+		success, output, err = b.executeCommandBmc(bmc, command)
 	case devices.Cmc:
 		chassis := client.(devices.Cmc)
-		// b.executeCommandChassis(chassis, command)
-		log.WithFields(logrus.Fields{
-			"component": component,
-		}).Info("Command executed.")
-		chassis.Close()
+		defer chassis.Close()
+
+		success, output, err = b.executeCommandChassis(chassis, command)
 	default:
 		log.WithFields(logrus.Fields{
 			"component": component,
@@ -89,19 +76,155 @@ func (b *Butler) executeCommand(command string, asset *asset.Asset) (err error)
 		return errors.New("unknown asset type")
 	}
 
-	return err
+	if err == nil && !success {
+		err = fmt.Errorf("command %s was not successful", command)
+	}
+
+	b.journalCommand(command, asset, err)
+
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"component":         component,
+			"Serial":            asset.Serial,
+			"AssetType":         asset.Type,
+			"Vendor":            asset.Vendor, // At this point, the vendor may or may not be known.
+			"Location":          asset.Location,
+			"IPAddress":         asset.IPAddress,
+			"Command":           command,
+			"CommandSuccessful": success,
+			"Error":             err,
+			"Output":            output,
+		}).Warn("Command execute returned error.")
+
+		return err
+	}
+
+	log.WithFields(logrus.Fields{
+		"component":         component,
+		"Serial":            asset.Serial,
+		"AssetType":         asset.Type,
+		"Vendor":            asset.Vendor,
+		"Location":          asset.Location,
+		"IPAddress":         asset.IPAddress,
+		"Command":           command,
+		"CommandSuccessful": success,
+		"Output":            output,
+	}).Info("Command successfully executed.")
+
+	return nil
+}
+
+// powerCommand changes the power or boot state of a server.
+type powerCommand struct {
+	action func(devices.Bmc) (bool, error)
+	// If set, the power state is polled until the server is on (or off) before it's observed.
+	settle string
+}
+
+// powerCommands are executed on servers,
+// their output includes the power state observed before and after.
+var powerCommands = map[string]powerCommand{
+	"poweron":        {action: devices.Bmc.PowerOn, settle: "on"},
+	"poweroff":       {action: devices.Bmc.PowerOff, settle: "off"},
+	"powercycle":     {action: devices.Bmc.PowerCycle},
+	"pxeonce":        {action: devices.Bmc.PxeOnce},
+	"pxe-and-reboot": {action: pxeAndReboot, settle: "on"},
+	"ensure-on":      {action: ensureOn, settle: "on"},
+}
+
+// The time allowed for the power state to settle, and the interval it's polled at.
+var (
+	powerSettleTimeout  = 30 * time.Second
+	powerSettleInterval = 2 * time.Second
+)
+
+// pxeAndReboot sets the server to PXE boot once and reboots it,
+// a server that is off is powered on.
+func pxeAndReboot(bmc devices.Bmc) (bool, error) {
+	success, err := bmc.PxeOnce()
+	if err != nil || !success {
+		return success, err
+	}
+
+	on, err := bmc.IsOn()
+	if err != nil {
+		return false, err
+	}
+
+	if on {
+		return bmc.PowerCycle()
+	}
+
+	return bmc.PowerOn()
+}
+
+// ensureOn powers on the server, unless it's on already.
+func ensureOn(bmc devices.Bmc) (bool, error) {
+	on, err := bmc.IsOn()
+	if err != nil {
+		return false, err
+	}
+
+	if on {
+		return true, nil
+	}
+
+	return bmc.PowerOn()
+}
+
+// powerState returns the power state of the server, or the error it could not be read with.
+func powerState(bmc devices.Bmc) string {
+	state, err := bmc.PowerState()
+	if err != nil {
+		return fmt.Sprintf("unknown (%s)", err)
+	}
+
+	return state
+}
+
+// settlePowerState waits until the server is on (or off), or the settle timeout expires.
+func (b *Butler) settlePowerState(bmc devices.Bmc, settle string) {
+	timeout := time.After(powerSettleTimeout)
+	for {
+		on, err := bmc.IsOn()
+		if err == nil && on == (settle == "on") {
+			return
+		}
+
+		select {
+		case <-time.After(powerSettleInterval):
+		case <-timeout:
+			return
+		case <-b.StopChan:
+			return
+		}
+	}
 }
 
 func (b *Butler) executeCommandBmc(bmc devices.Bmc, command string) (success bool, output string, err error) {
+	if c, exists := powerCommands[command]; exists {
+		before := powerState(bmc)
+
+		success, err = c.action(bmc)
+		if err == nil && success && c.settle != "" {
+			b.settlePowerState(bmc, c.settle)
+		}
+
+		return success, fmt.Sprintf("power state before: %s, after: %s", before, powerState(bmc)), err
+	}
+
 	switch command {
 	case "bmc-reset":
 		success, err := bmc.PowerCycleBmc()
 		return success, "", err
-	case "powercycle":
-		success, err := bmc.PowerCycle()
-		return success, "", err
+	case "power-state":
+		state, err := bmc.PowerState()
+		return err == nil, state, err
+	case "is-on":
+		on, err := bmc.IsOn()
+		return err == nil, fmt.Sprintf("%t", on), err
 	case "firmware-update":
-		return bmc.UpdateFirmware("https://10.198.174.2", "bmc-firmware/"+bmc.Vendor()+"/"+bmc.HardwareType())
+		return updateFirmware(bmc, b.Config.Firmware)
 	case "firmware-version":
 		output, err := bmc.CheckFirmwareVersion()
 		return err == nil, output, err
@@ -110,14 +233,142 @@ func (b *Butler) executeCommandBmc(bmc devices.Bmc, command string) (success boo
 	}
 }
 
-//func (b *Butler) executeCommandChassis(chassis devices.Cmc, command []byte) (err error) {
-//
-//	switch string(command) {
-//	case "Chassis reset":
-//		chassis.PowerCycleBmc()
-//	default:
-//		return errors.New(fmt.Sprintf("Unknown command: %s", command))
-//	}
-//
-//	return err
-//}
+// chassisCommands are the commands executed on blades through the chassis.
+var chassisCommands = map[string]func(devices.Cmc, int) (bool, error){
+	"blade-powercycle": devices.Cmc.PowerCycleBlade,
+	"blade-poweroff":   devices.Cmc.PowerOffBlade,
+	"blade-poweron":    devices.Cmc.PowerOnBlade,
+	"blade-reseat":     devices.Cmc.ReseatBlade,
+	"blade-pxeonce":    devices.Cmc.PxeOnceBlade,
+	"blade-bmc-reset":  devices.Cmc.PowerCycleBmcBlade,
+}
+
+// parseChassisCommand splits a chassis command declared as <command>:<blades>,
+// blades are declared as slot:<number> or serial:<blade serial> separated by commas, e.g blade-reseat:slot:3,serial:CZ3XXX
+func parseChassisCommand(command string) (name string, blades []string, err error) {
+	parts := strings.SplitN(command, ":", 2)
+	name = parts[0]
+
+	if _, exists := chassisCommands[name]; !exists {
+		return name, blades, fmt.Errorf("unknown command: %s", name)
+	}
+
+	if len(parts) == 2 {
+		for _, blade := range strings.Split(parts[1], ",") {
+			blade = strings.TrimSpace(blade)
+			if blade == "" {
+				continue
+			}
+
+			_, _, err := parseBlade(blade)
+			if err != nil {
+				return name, blades, err
+			}
+
+			blades = append(blades, blade)
+		}
+	}
+
+	if len(blades) == 0 {
+		return name, blades, fmt.Errorf("command %s expects one or more blades, e.g %s:slot:3 or %s:serial:CZ3XXX", name, name, name)
+	}
+
+	return name, blades, nil
+}
+
+// parseBlade returns the slot or the serial of a blade declared as slot:<number> or serial:<blade serial>.
+func parseBlade(blade string) (slot int, serial string, err error) {
+	parts := strings.SplitN(blade, ":", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return -1, "", fmt.Errorf("invalid blade %q, expected slot:<number> or serial:<blade serial>", blade)
+	}
+
+	switch parts[0] {
+	case "slot":
+		slot, err = strconv.Atoi(parts[1])
+		if err != nil || slot < 1 {
+			return -1, "", fmt.Errorf("invalid blade slot %q", parts[1])
+		}
+		return slot, "", nil
+	case "serial":
+		return -1, parts[1], nil
+	default:
+		return -1, "", fmt.Errorf("invalid blade %q, expected slot:<number> or serial:<blade serial>", blade)
+	}
+}
+
+// bladePosition returns the slot of the blade declared as slot:<number> or serial:<blade serial>,
+// a slot is expected to hold one of the given chassis blades.
+func bladePosition(chassis devices.Cmc, blades []*devices.Blade, blade string) (int, error) {
+	slot, serial, err := parseBlade(blade)
+	if err != nil {
+		return -1, err
+	}
+
+	if serial != "" {
+		position, err := chassis.FindBladePosition(serial)
+		if err != nil {
+			return -1, fmt.Errorf("unable to find blade %s: %s", serial, err)
+		}
+		return position, nil
+	}
+
+	for _, b := range blades {
+		if b.BladePosition == slot {
+			return slot, nil
+		}
+	}
+
+	return -1, fmt.Errorf("no blade in slot %d of the chassis", slot)
+}
+
+// executeCommandChassis executes firmware commands on the chassis,
+// or the blade command on each of the blades declared,
+// a blade that fails doesn't stop the command from being executed on the rest.
+func (b *Butler) executeCommandChassis(chassis devices.Cmc, command string) (success bool, output string, err error) {
+	switch command {
+	case "firmware-update":
+		return updateFirmware(chassis, b.Config.Firmware)
+	case "firmware-version":
+		output, err := chassis.CheckFirmwareVersion()
+		return err == nil, output, err
+	}
+
+	name, blades, err := parseChassisCommand(command)
+	if err != nil {
+		return false, "", err
+	}
+
+	action := chassisCommands[name]
+
+	// Slots declared are checked against the blades the chassis holds.
+	chassisBlades, err := chassis.Blades()
+	if err != nil {
+		return false, "", fmt.Errorf("unable to list chassis blades: %s", err)
+	}
+
+	var outputs, failed []string
+	for _, blade := range blades {
+		position, err := bladePosition(chassis, chassisBlades, blade)
+		if err != nil {
+			failed = append(failed, err.Error())
+			continue
+		}
+
+		ok, err := action(chassis, position)
+		switch {
+		case err != nil:
+			failed = append(failed, fmt.Sprintf("blade %s (position %d): %s", blade, position, err))
+		case !ok:
+			failed = append(failed, fmt.Sprintf("blade %s (position %d): not successful", blade, position))
+		default:
+			outputs = append(outputs, fmt.Sprintf("blade %s (position %d): ok", blade, position))
+		}
+	}
+
+	if len(failed) > 0 {
+		return false, strings.Join(outputs, ", "), errors.New(strings.Join(failed, ", "))
+	}
+
+	return true, strings.Join(outputs, ", "), nil
+}
@@ -0,0 +1,154 @@
+package butler
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bmc-toolbox/bmclib/devices"
+)
+
+// Test chassis commands are parsed into the command and the blades declared.
+func TestParseChassisCommand(t *testing.T) {
+	name, blades, err := parseChassisCommand("blade-reseat:slot:3, serial:CZ3XXX,")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if name != "blade-reseat" || len(blades) != 2 || blades[0] != "slot:3" || blades[1] != "serial:CZ3XXX" {
+		t.Fatalf("Unexpected command %s, blades %v", name, blades)
+	}
+
+	invalid := []string{
+		"blade-reseat",
+		"blade-reseat:",
+		"blade-reseat:3",
+		"blade-reseat:CZ3XXX",
+		"blade-reseat:slot:0",
+		"blade-reseat:slot:CZ3XXX",
+		"blade-reseat:serial:",
+		"bmc-reset:slot:3",
+		"blade-explode:slot:3",
+	}
+
+	for _, command := range invalid {
+		_, _, err := parseChassisCommand(command)
+		if err == nil {
+			t.Fatalf("Expected command %s to be invalid.", command)
+		}
+	}
+}
+
+// fakeCmc implements the blade methods of devices.Cmc used by chassis commands.
+type fakeCmc struct {
+	devices.Cmc
+	reseated []int
+}
+
+func (f *fakeCmc) Blades() ([]*devices.Blade, error) {
+	return []*devices.Blade{{BladePosition: 1, Serial: "CZ1XXX"}, {BladePosition: 3, Serial: "CZ3XXX"}}, nil
+}
+
+func (f *fakeCmc) FindBladePosition(serial string) (int, error) {
+	blades, _ := f.Blades()
+	for _, b := range blades {
+		if b.Serial == serial {
+			return b.BladePosition, nil
+		}
+	}
+	return -1, errNotSupported
+}
+
+func (f *fakeCmc) ReseatBlade(position int) (bool, error) {
+	f.reseated = append(f.reseated, position)
+	return true, nil
+}
+
+// Test blades are resolved by slot or serial, slots without a blade fail without stopping the rest.
+func TestExecuteCommandChassis(t *testing.T) {
+	b := &Butler{}
+	chassis := &fakeCmc{}
+
+	success, output, err := b.executeCommandChassis(chassis, "blade-reseat:slot:3,slot:16,serial:CZ1XXX")
+	if success || err == nil || !strings.Contains(err.Error(), "no blade in slot 16") {
+		t.Fatalf("Expected the empty slot to fail, got success: %t, err: %v", success, err)
+	}
+
+	if len(chassis.reseated) != 2 || chassis.reseated[0] != 3 || chassis.reseated[1] != 1 {
+		t.Fatalf("Expected blades in slot 3 and 1 to be reseated, got %v, output: %s", chassis.reseated, output)
+	}
+}
+
+// fakeBmc implements the power methods of devices.Bmc, calling any other method panics.
+type fakeBmc struct {
+	devices.Bmc
+	on    bool
+	calls []string
+}
+
+func (f *fakeBmc) PowerState() (string, error) {
+	if f.on {
+		return "on", nil
+	}
+	return "off", nil
+}
+
+func (f *fakeBmc) IsOn() (bool, error) { return f.on, nil }
+
+func (f *fakeBmc) PowerOn() (bool, error) {
+	f.calls = append(f.calls, "PowerOn")
+	f.on = true
+	return true, nil
+}
+
+func (f *fakeBmc) PowerOff() (bool, error) {
+	f.calls = append(f.calls, "PowerOff")
+	f.on = false
+	return true, nil
+}
+
+func (f *fakeBmc) PowerCycle() (bool, error) {
+	f.calls = append(f.calls, "PowerCycle")
+	return true, nil
+}
+
+func (f *fakeBmc) PxeOnce() (bool, error) {
+	f.calls = append(f.calls, "PxeOnce")
+	return true, nil
+}
+
+// Test power commands act based on the current power state, and report the state before and after.
+func TestExecuteCommandBmcPower(t *testing.T) {
+	interval := powerSettleInterval
+	powerSettleInterval = time.Millisecond
+	t.Cleanup(func() { powerSettleInterval = interval })
+
+	b := &Butler{}
+
+	cases := []struct {
+		command string
+		on      bool
+		calls   string
+		output  string
+	}{
+		{"ensure-on", true, "", "power state before: on, after: on"},
+		{"ensure-on", false, "PowerOn", "power state before: off, after: on"},
+		{"pxe-and-reboot", true, "PxeOnce,PowerCycle", "power state before: on, after: on"},
+		{"pxe-and-reboot", false, "PxeOnce,PowerOn", "power state before: off, after: on"},
+		{"poweroff", true, "PowerOff", "power state before: on, after: off"},
+		{"is-on", true, "", "true"},
+	}
+
+	for _, c := range cases {
+		bmc := &fakeBmc{on: c.on}
+
+		success, output, err := b.executeCommandBmc(bmc, c.command)
+		if err != nil || !success {
+			t.Fatalf("%s: unexpected error %v", c.command, err)
+		}
+
+		if strings.Join(bmc.calls, ",") != c.calls || output != c.output {
+			t.Fatalf("%s (on: %t): got calls %v output %q, want calls %s output %q", c.command, c.on, bmc.calls, output, c.calls, c.output)
+		}
+	}
+}
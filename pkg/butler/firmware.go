@@ -0,0 +1,236 @@
+package butler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/bmc-toolbox/bmcbutler/pkg/asset"
+	"github.com/bmc-toolbox/bmcbutler/pkg/config"
+	"github.com/bmc-toolbox/bmcbutler/pkg/metrics"
+	"github.com/bmc-toolbox/bmcbutler/pkg/tracing"
+	"github.com/bmc-toolbox/bmclib/devices"
+	"github.com/bmc-toolbox/bmclogin"
+)
+
+// firmwareDevice is implemented by both server and chassis BMCs.
+type firmwareDevice interface {
+	Vendor() string
+	HardwareType() string
+	Model() (string, error)
+	CheckFirmwareVersion() (string, error)
+	UpdateFirmware(string, string) (bool, string, error)
+}
+
+// FirmwareReport is the firmware compliance reported for an asset.
+type FirmwareReport struct {
+	Serial        string `json:"serial"`
+	IPAddress     string `json:"ip_address"`
+	Vendor        string `json:"vendor"`
+	HardwareType  string `json:"hardware_type"`
+	Model         string `json:"model"`
+	Type          string `json:"type"`
+	Location      string `json:"location"`
+	Version       string `json:"version"`
+	TargetVersion string `json:"target_version"`
+	Compliant     bool   `json:"compliant"`
+	Error         string `json:"error,omitempty"` // The version could not be retrieved, or no version is declared in the catalog.
+}
+
+// firmwareReportColumns are the CSV columns of a FirmwareReport.
+var firmwareReportColumns = []string{
+	"serial", "ip_address", "vendor", "hardware_type", "model", "type", "location",
+	"version", "target_version", "compliant", "error",
+}
+
+// csvRecord returns the report as a CSV record.
+func (r *FirmwareReport) csvRecord() []string {
+	return []string{
+		r.Serial, r.IPAddress, r.Vendor, r.HardwareType, r.Model, r.Type, r.Location,
+		r.Version, r.TargetVersion, fmt.Sprintf("%t", r.Compliant), r.Error,
+	}
+}
+
+// WriteFirmwareReportHeader writes the CSV header for firmware reports, other formats have no header.
+func WriteFirmwareReportHeader(w io.Writer, format string) error {
+	return writeReportHeader(w, firmwareReportColumns, format)
+}
+
+// firmwareStatus returns the model and current firmware version of the device,
+// along with the catalog entry declared for it.
+func firmwareStatus(device firmwareDevice, firmware *config.Firmware) (model string, version string, entry *config.FirmwareEntry, err error) {
+	// The model is only required to match catalog entries, it's not available on all devices.
+	model, _ = device.Model()
+
+	// There's no fallback repository, devices missing from the catalog are not updated.
+	entry = firmware.Lookup(device.Vendor(), device.HardwareType(), model)
+	if entry == nil {
+		return model, "", nil, fmt.Errorf("no firmware declared in the catalog for vendor: %s, hardwareType: %s, model: %s", device.Vendor(), device.HardwareType(), model)
+	}
+
+	version, err = device.CheckFirmwareVersion()
+	if err != nil {
+		return model, "", entry, fmt.Errorf("unable to check firmware version: %s", err)
+	}
+
+	return model, strings.TrimSpace(version), entry, nil
+}
+
+// sameVersion returns true if both firmware versions are the same once normalized,
+// a leading v and trailing zero fields are ignored and numeric fields are compared as numbers,
+// so v2.10, 2.10 and 2.10.0 are the same version, 2.1 and 2.10 are not.
+func sameVersion(a string, b string) bool {
+	fieldsA, fieldsB := versionFields(a), versionFields(b)
+	if len(fieldsA) != len(fieldsB) {
+		return false
+	}
+
+	for i := range fieldsA {
+		if fieldsA[i] != fieldsB[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// versionFields splits a firmware version in its fields, numeric fields are stripped of leading zeros.
+func versionFields(version string) []string {
+	version = strings.ToLower(strings.TrimSpace(version))
+	version = strings.TrimPrefix(version, "v")
+
+	fields := strings.FieldsFunc(version, func(r rune) bool { return r == '.' || r == '-' || r == '_' })
+	for i, field := range fields {
+		if n, err := strconv.ParseUint(field, 10, 64); err == nil {
+			fields[i] = strconv.FormatUint(n, 10)
+		}
+	}
+
+	for len(fields) > 1 && fields[len(fields)-1] == "0" {
+		fields = fields[:len(fields)-1]
+	}
+
+	return fields
+}
+
+// updateFirmware updates the device firmware to the version declared in the catalog,
+// if it runs a different version.
+func updateFirmware(device firmwareDevice, firmware *config.Firmware) (success bool, output string, err error) {
+	_, version, entry, err := firmwareStatus(device, firmware)
+	if err != nil {
+		return false, "", err
+	}
+
+	if sameVersion(version, entry.Version) {
+		return true, fmt.Sprintf("firmware version %s is current", version), nil
+	}
+
+	success, output, err = device.UpdateFirmware(firmware.Repository, entry.File)
+
+	return success, fmt.Sprintf("firmware update from %s to %s: %s", version, entry.Version, output), err
+}
+
+// firmwareAsset sets up the bmc connection,
+// and reports if the asset runs the firmware version declared in the catalog, the report is recorded on the result.
+func (b *Butler) firmwareAsset(asset *asset.Asset, span *tracing.Span, result *Result) (err error) {
+	component := "firmwareAsset"
+
+	defer b.timeTrack(time.Now(), "firmwareAsset", asset)
+
+	report := &FirmwareReport{}
+
+	defer func() {
+		report.Serial = asset.Serial
+		report.IPAddress = asset.IPAddress
+		report.Vendor = asset.Vendor
+		report.HardwareType = asset.HardwareType
+		report.Type = asset.Type
+		report.Location = asset.Location
+
+		if report.IPAddress == "" {
+			report.IPAddress = strings.Join(asset.IPAddresses, ",")
+		}
+
+		if err != nil {
+			report.Error = err.Error()
+		}
+
+		// Compliant assets are listed only if asked for.
+		if report.Compliant && !b.Config.FirmwareListAll {
+			result.report = nil
+			return
+		}
+
+		result.report = &assetReport{record: report, format: b.Config.FirmwareFormat}
+	}()
+
+	b.Log.WithFields(logrus.Fields{
+		"component": component,
+		"Serial":    asset.Serial,
+		"IPAddress": asset.IPAddresses,
+	}).Debug("Connecting to asset...")
+
+	bmcConn := bmclogin.Params{
+		IpAddresses:     asset.IPAddresses,
+		Credentials:     b.credentials(asset),
+		CheckCredential: true,
+		Retries:         1,
+		StopChan:        b.StopChan,
+	}
+
+	client, loginInfo, err := login(span, bmcConn)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrLoginFailed, err)
+	}
+
+	asset.IPAddress = loginInfo.ActiveIpAddress
+
+	var device firmwareDevice
+
+	switch clientType := client.(type) {
+	case devices.Bmc:
+		bmc := client.(devices.Bmc)
+		defer bmc.Close(context.TODO())
+
+		asset.Type = "server"
+		device = bmc
+	case devices.Cmc:
+		chassis := client.(devices.Cmc)
+		defer chassis.Close()
+
+		asset.Type = "chassis"
+		device = chassis
+	default:
+		b.Log.WithFields(logrus.Fields{
+			"component": component,
+			"Type":      fmt.Sprintf("%s", clientType),
+		}).Warn("Unknown device type.")
+		return fmt.Errorf("Unknown device type \"%s\"!", clientType)
+	}
+
+	asset.HardwareType = device.HardwareType()
+	asset.Vendor = device.Vendor()
+
+	model, version, entry, err := firmwareStatus(device, b.Config.Firmware)
+	report.Model = model
+	report.Version = version
+	if entry != nil {
+		report.TargetVersion = entry.Version
+	}
+
+	if err != nil {
+		return err
+	}
+
+	report.Compliant = sameVersion(version, entry.Version)
+	if !report.Compliant {
+		metrics.IncrCounter([]string{"firmware", "assets_behind"}, 1)
+	}
+
+	return nil
+}
@@ -0,0 +1,76 @@
+package butler
+
+import (
+	"testing"
+
+	"github.com/bmc-toolbox/bmcbutler/pkg/config"
+)
+
+// fakeFirmwareDevice reports the given firmware version, and records updates.
+type fakeFirmwareDevice struct {
+	version string
+	updated string
+}
+
+func (f *fakeFirmwareDevice) Vendor() string         { return "dell" }
+func (f *fakeFirmwareDevice) HardwareType() string   { return "idrac9" }
+func (f *fakeFirmwareDevice) Model() (string, error) { return "PowerEdge R640", nil }
+
+func (f *fakeFirmwareDevice) CheckFirmwareVersion() (string, error) { return f.version + "\n", nil }
+
+func (f *fakeFirmwareDevice) UpdateFirmware(source, file string) (bool, string, error) {
+	f.updated = source + "/" + file
+	return true, "Firmware update completed successfully", nil
+}
+
+// Test firmware is updated only if the device runs a version other than the one in the catalog.
+func TestUpdateFirmware(t *testing.T) {
+	firmware := &config.Firmware{
+		Repository: "https://firmware.example.com",
+		Catalog: []*config.FirmwareEntry{
+			{Vendor: "dell", HardwareType: "idrac9", Version: "4.20.20.20", File: "dell/idrac9/4.20.20.20.exe"},
+		},
+	}
+
+	current := &fakeFirmwareDevice{version: "v4.20.20.20"}
+	success, _, err := updateFirmware(current, firmware)
+	if err != nil || !success || current.updated != "" {
+		t.Fatalf("Expected no update for a device running the catalog version, got: %s, %v", current.updated, err)
+	}
+
+	behind := &fakeFirmwareDevice{version: "3.30.30.30"}
+	success, _, err = updateFirmware(behind, firmware)
+	if err != nil || !success || behind.updated != "https://firmware.example.com/dell/idrac9/4.20.20.20.exe" {
+		t.Fatalf("Expected an update for a device behind, got: %s, %v", behind.updated, err)
+	}
+
+	_, _, err = updateFirmware(behind, &config.Firmware{})
+	if err == nil {
+		t.Fatal("Expected an error for a device not declared in the catalog.")
+	}
+}
+
+// Test firmware versions are compared normalized.
+func TestSameVersion(t *testing.T) {
+	cases := []struct {
+		a, b string
+		same bool
+	}{
+		{"2.10", "2.10", true},
+		{"v2.10", "2.10", true},
+		{"2.10", "2.10.0", true},
+		{"V2.10.0\n", "2.10", true},
+		{"2.010", "2.10", true},
+		{"2.1", "2.10", false},
+		{"2.10", "2.10.1", false},
+		{"4.20.20.20", "4.20.20.21", false},
+		{"1.0.a", "1.0.A", true},
+		{"1.0.a", "1.0.b", false},
+	}
+
+	for _, c := range cases {
+		if sameVersion(c.a, c.b) != c.same {
+			t.Errorf("Expected versions %q and %q same: %t", c.a, c.b, c.same)
+		}
+	}
+}
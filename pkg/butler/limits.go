@@ -0,0 +1,240 @@
+package butler
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/bmc-toolbox/bmcbutler/pkg/asset"
+	"github.com/bmc-toolbox/bmcbutler/pkg/config"
+	"github.com/bmc-toolbox/bmclib/devices"
+)
+
+// limiter enforces the concurrency limits declared in the configuration,
+// a slot of each limit declared for the asset attributes is held while butlers work on it.
+// A nil limiter enforces no limits.
+type limiter struct {
+	mu     sync.Mutex
+	config *config.Concurrency
+	counts map[string]int             // Slots taken, by limit key.
+	held   map[string]map[string]bool // Limit keys held, by asset key.
+	wake   chan struct{}              // Closed, and replaced, when slots are released.
+}
+
+// newLimiter returns the limiter for the concurrency limits, nil if none are declared.
+func newLimiter(c *config.Concurrency) *limiter {
+	if c == nil {
+		return nil
+	}
+
+	return &limiter{
+		config: c,
+		counts: make(map[string]int),
+		held:   make(map[string]map[string]bool),
+		wake:   make(chan struct{}),
+	}
+}
+
+// limitKeys returns the limits declared for the asset attributes, by limit key.
+func limitKeys(a *asset.Asset, c *config.Concurrency) map[string]int {
+	keys := make(map[string]int)
+
+	for prefix, attribute := range map[string]struct {
+		value  string
+		limits map[string]int
+	}{
+		"location":     {a.Location, c.Locations},
+		"vendor":       {a.Vendor, c.Vendors},
+		"hardwareType": {a.HardwareType, c.HardwareTypes},
+	} {
+		// Keys are lowercased when the configuration is read.
+		value := strings.ToLower(attribute.value)
+		if limit, declared := attribute.limits[value]; declared && value != "" {
+			keys[prefix+":"+value] = limit
+		}
+	}
+
+	chassis := a.Chassis
+	if a.Type == "chassis" {
+		chassis = a.Serial
+	}
+
+	if c.Chassis > 0 && chassis != "" {
+		keys["chassis:"+chassis] = c.Chassis
+	}
+
+	return keys
+}
+
+// tryAcquire takes a slot of each limit declared for the asset not held for it yet,
+// none are taken if any of the limits is full.
+func (l *limiter) tryAcquire(key string, a *asset.Asset) bool {
+	if l == nil {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.take(key, limitKeys(a, l.config))
+}
+
+// acquire blocks until a slot of each limit declared for the asset is held, or the stopChan is closed.
+// Slots held for the asset are released while it waits,
+// so assets waiting don't hold slots other assets wait for.
+func (l *limiter) acquire(key string, a *asset.Asset, stopChan <-chan struct{}) error {
+	if l == nil {
+		return nil
+	}
+
+	for {
+		l.mu.Lock()
+		if l.take(key, limitKeys(a, l.config)) {
+			l.mu.Unlock()
+			return nil
+		}
+
+		l.releaseHeld(key)
+		wake := l.wake
+		l.mu.Unlock()
+
+		select {
+		case <-wake:
+		case <-stopChan:
+			return ErrInterrupted
+		}
+	}
+}
+
+// release releases the slots held for the asset.
+func (l *limiter) release(key string) {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.releaseHeld(key)
+}
+
+// wakeChan returns a channel closed once slots are released.
+func (l *limiter) wakeChan() <-chan struct{} {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.wake
+}
+
+// take takes a slot of each of the limits not held for the asset, if all have a slot free,
+// expects l.mu to be held.
+func (l *limiter) take(key string, limits map[string]int) bool {
+	held := l.held[key]
+
+	var missing []string
+	for k, limit := range limits {
+		if held[k] {
+			continue
+		}
+
+		if l.counts[k] >= limit {
+			return false
+		}
+
+		missing = append(missing, k)
+	}
+
+	if len(missing) == 0 {
+		return true
+	}
+
+	if held == nil {
+		held = make(map[string]bool)
+		l.held[key] = held
+	}
+
+	for _, k := range missing {
+		l.counts[k]++
+		held[k] = true
+	}
+
+	return true
+}
+
+// releaseHeld releases the slots held for the asset, expects l.mu to be held.
+func (l *limiter) releaseHeld(key string) {
+	held := l.held[key]
+	if len(held) == 0 {
+		return
+	}
+
+	for k := range held {
+		l.counts[k]--
+	}
+
+	delete(l.held, key)
+
+	close(l.wake)
+	l.wake = make(chan struct{})
+}
+
+// limitAsset waits for a slot of the limits declared for asset attributes learned once logged in,
+// the vendor, hardware type, or the chassis a blade is installed in.
+// The client is closed if an interrupt is received while waiting.
+func (b *Butler) limitAsset(a *asset.Asset, client interface{}) error {
+	if b.limiter == nil {
+		return nil
+	}
+
+	learned := *a
+
+	switch device := client.(type) {
+	case devices.Bmc:
+		learned.Type = "server"
+		learned.Vendor = device.Vendor()
+		learned.HardwareType = device.HardwareType()
+
+		if b.Config.Concurrency.Chassis > 0 && a.Chassis == "" {
+			if blade, err := device.IsBlade(); err == nil && blade {
+				a.Chassis, _ = device.ChassisSerial()
+				learned.Chassis = a.Chassis
+			}
+		}
+	case devices.Cmc:
+		learned.Type = "chassis"
+		learned.Vendor = device.Vendor()
+		learned.HardwareType = device.HardwareType()
+	}
+
+	key := a.Key()
+	if b.limiter.tryAcquire(key, &learned) {
+		return nil
+	}
+
+	b.Log.WithFields(logrus.Fields{
+		"component":    "limitAsset",
+		"Serial":       a.Serial,
+		"IPAddress":    a.IPAddress,
+		"Vendor":       learned.Vendor,
+		"HardwareType": learned.HardwareType,
+		"Chassis":      learned.Chassis,
+	}).Debug("Concurrency limits reached, waiting for a slot.")
+
+	err := b.limiter.acquire(key, &learned, b.StopChan)
+	if err != nil {
+		switch device := client.(type) {
+		case devices.Bmc:
+			device.Close(context.TODO())
+		case devices.Cmc:
+			device.Close()
+		}
+	}
+
+	return err
+}
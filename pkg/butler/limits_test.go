@@ -0,0 +1,78 @@
+package butler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bmc-toolbox/bmcbutler/pkg/asset"
+	"github.com/bmc-toolbox/bmcbutler/pkg/config"
+)
+
+// Test limits are keyed by the asset attributes, blades and their chassis share the chassis limit.
+func TestLimitKeys(t *testing.T) {
+	c := &config.Concurrency{
+		Locations:     map[string]int{"ams4": 20},
+		HardwareTypes: map[string]int{"ilo4": 2},
+		Chassis:       1,
+	}
+
+	keys := limitKeys(&asset.Asset{Serial: "BLADE1", Location: "AMS4", Vendor: "HP", HardwareType: "ilo4", Chassis: "CHASSIS1"}, c)
+	if len(keys) != 3 || keys["location:ams4"] != 20 || keys["hardwareType:ilo4"] != 2 || keys["chassis:CHASSIS1"] != 1 {
+		t.Fatalf("Unexpected limit keys: %v", keys)
+	}
+
+	keys = limitKeys(&asset.Asset{Serial: "CHASSIS1", Type: "chassis", Location: "fra4"}, c)
+	if len(keys) != 1 || keys["chassis:CHASSIS1"] != 1 {
+		t.Fatalf("Expected a chassis limited along with its blades, got: %v", keys)
+	}
+}
+
+// Test slots are taken for all the limits of an asset or none, and assets waiting hold no slots.
+func TestLimiter(t *testing.T) {
+	l := newLimiter(&config.Concurrency{Locations: map[string]int{"ams4": 2}, HardwareTypes: map[string]int{"ilo4": 1}})
+
+	first := &asset.Asset{Serial: "FOO", Location: "ams4", HardwareType: "ilo4"}
+	second := &asset.Asset{Serial: "BAR", Location: "ams4"}
+
+	if !l.tryAcquire("FOO", first) || !l.tryAcquire("BAR", second) {
+		t.Fatal("Expected slots free for the first assets.")
+	}
+
+	if l.tryAcquire("BAZ", &asset.Asset{Serial: "BAZ", Location: "ams4"}) {
+		t.Fatal("Expected the location limit enforced.")
+	}
+
+	// The hardware type of BAR is learned once logged in, FOO holds the only ilo4 slot.
+	second.HardwareType = "ilo4"
+	if l.tryAcquire("BAR", second) {
+		t.Fatal("Expected the hardware type limit enforced.")
+	}
+
+	acquired := make(chan error)
+	go func() { acquired <- l.acquire("BAR", second, nil) }()
+
+	// BAR releases its location slot while it waits.
+	deadline := time.Now().Add(time.Second)
+	for !l.tryAcquire("BAZ", &asset.Asset{Serial: "BAZ", Location: "ams4"}) {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the slots of an asset released while it waits.")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	l.release("BAZ")
+	l.release("FOO")
+
+	select {
+	case err := <-acquired:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the waiting asset to acquire slots once released.")
+	}
+
+	if l.counts["location:ams4"] != 1 || l.counts["hardwareType:ilo4"] != 1 {
+		t.Fatalf("Unexpected slots taken: %v", l.counts)
+	}
+}
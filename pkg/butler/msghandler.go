@@ -1,6 +1,7 @@
 package butler
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -8,11 +9,45 @@ import (
 	"github.com/sirupsen/logrus"
 
 	"github.com/bmc-toolbox/bmcbutler/pkg/asset"
-	metrics "github.com/bmc-toolbox/gin-go-metrics"
+	"github.com/bmc-toolbox/bmcbutler/pkg/audit"
+	"github.com/bmc-toolbox/bmcbutler/pkg/config"
+	"github.com/bmc-toolbox/bmcbutler/pkg/metrics"
+	"github.com/bmc-toolbox/bmcbutler/pkg/notify"
 )
 
-func (b *Butler) myLocation(location string) bool {
-	for _, l := range b.Config.Locations {
+var (
+	// ErrAssetNoIP is returned by Manageable for assets without any IP addresses.
+	ErrAssetNoIP = errors.New("asset has no IP address(es)")
+	// ErrAssetLocationUnmanaged is returned by Manageable for assets in locations not managed by bmcbutler.
+	ErrAssetLocationUnmanaged = errors.New("asset location is not managed")
+	// ErrInterrupted is the Result error for assets skipped since an interrupt was received.
+	ErrInterrupted = errors.New("interrupt received")
+	// ErrCancelled is the Result error for assets skipped since their job was cancelled.
+	ErrCancelled = errors.New("job cancelled")
+	// ErrUnknownAction is the Result error for assets without an action declared.
+	ErrUnknownAction = errors.New("unknown action")
+	// ErrLoginFailed is wrapped by the errors of actions that could not login to the asset.
+	ErrLoginFailed = errors.New("login failed")
+)
+
+// Manageable returns an error if butlers won't manage the asset,
+// because it has no IP address(es) or based on its location.
+func Manageable(asset *asset.Asset, config *config.Params) error {
+	// If an asset has no IPAddress, we can't do anything about it!
+	if len(asset.IPAddresses) == 0 || len(asset.IPAddresses) == 1 && asset.IPAddresses[0] == "0.0.0.0" {
+		return ErrAssetNoIP
+	}
+
+	// If an asset has a location defined, we may want to filter it.
+	if asset.Location != "" && !config.IgnoreLocation && !myLocation(asset.Location, config.Locations) {
+		return ErrAssetLocationUnmanaged
+	}
+
+	return nil
+}
+
+func myLocation(location string, locations []string) bool {
+	for _, l := range locations {
 		if l == location {
 			return true
 		}
@@ -33,20 +68,82 @@ func (b *Butler) timeTrack(start time.Time, name string, asset *asset.Asset) {
 	}).Info(fmt.Sprintf("%s on %s took %f seconds.", name, asset.IPAddress, seconds))
 }
 
-// msgHandler invokes the appropriate action based on msg attributes.
-// nolint: gocyclo
-func (b *Butler) msgHandler(msg Msg) {
+// interrupted returns true if an interrupt was received.
+func (b *Butler) interrupted() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.interrupt
+}
+
+// msgHandler invokes the appropriate action based on msg attributes,
+// returns the msg to queue for retry if the action failed with a transient error.
+func (b *Butler) msgHandler(msg Msg) (retry *Msg) {
+	// The action error, or the reason the asset was skipped.
+	var err, skipped error
+	result := retryResult(&msg)
+
+	if b.ResultChan != nil {
+		start := time.Now()
+		defer func() {
+			// The result of assets queued for retry is sent once the last attempt is done.
+			if retry == nil {
+				b.sendResult(&msg, &result, start, err, skipped)
+			}
+		}()
+	}
+
+	// Reports are written once the last attempt on the asset is done,
+	// an asset skipped after an earlier attempt is reported with the outcome of that attempt.
+	defer func() {
+		if retry != nil {
+			return
+		}
+
+		writeErr := b.writeAssetReport(&result)
+		if writeErr != nil && err == nil {
+			err = writeErr
+		}
+	}()
+
 	// If an interrupt was received, return.
-	if b.interrupt {
+	if b.interrupted() {
+		skipped = ErrInterrupted
 		return
 	}
 
 	component := "msgHandler"
 
+	// If the job the asset was submitted with was cancelled, return.
+	select {
+	case <-msg.Cancel:
+		skipped = ErrCancelled
+		return
+	default:
+	}
+
 	metrics.IncrCounter([]string{"butler", "asset_recvd"}, 1)
 
-	// If an asset has no IPAddress, we can't do anything about it!
-	if len(msg.Asset.IPAddresses) == 0 || len(msg.Asset.IPAddresses) == 1 && msg.Asset.IPAddresses[0] == "0.0.0.0" {
+	b.startAssetSpan(&msg)
+	defer func() {
+		var outcome string
+		switch {
+		case retry != nil:
+			outcome = "retried"
+		case skipped != nil:
+			outcome = audit.OutcomeSkipped
+		case err != nil:
+			outcome = audit.OutcomeFailed
+		default:
+			outcome = audit.OutcomeSuccess
+		}
+
+		endAssetSpan(&msg, outcome, err)
+	}()
+
+	skipped = Manageable(&msg.Asset, b.Config)
+	switch skipped {
+	case ErrAssetNoIP:
 		b.Log.WithFields(logrus.Fields{
 			"component": component,
 			"Serial":    msg.Asset.Serial,
@@ -54,99 +151,119 @@ func (b *Butler) msgHandler(msg Msg) {
 		}).Warn("Asset was received by butler without any IP(s) info, skipped.")
 
 		metrics.IncrCounter([]string{"butler", "asset_recvd_noip"}, 1)
+		countAsset(&msg.Asset, audit.OutcomeSkipped)
 		return
-	}
+	case ErrAssetLocationUnmanaged:
+		b.Log.WithFields(logrus.Fields{
+			"component":     component,
+			"Serial":        msg.Asset.Serial,
+			"AssetType":     msg.Asset.Type,
+			"AssetLocation": msg.Asset.Location,
+		}).Warn("Butler won't manage asset based on its current location.")
 
-	// If an asset has a location defined, we may want to filter it.
-	if msg.Asset.Location != "" {
-		if !b.myLocation(msg.Asset.Location) && !b.Config.IgnoreLocation {
-			b.Log.WithFields(logrus.Fields{
-				"component":     component,
-				"Serial":        msg.Asset.Serial,
-				"AssetType":     msg.Asset.Type,
-				"AssetLocation": msg.Asset.Location,
-			}).Warn("Butler won't manage asset based on its current location.")
-
-			metrics.IncrCounter([]string{"butler", "asset_recvd_location_unmanaged"}, 1)
-			return
-		}
+		metrics.IncrCounter([]string{"butler", "asset_recvd_location_unmanaged"}, 1)
+		countAsset(&msg.Asset, audit.OutcomeSkipped)
+		return
 	}
 
-	// This field helps with enumerating the unique assets we have, since some assets don't
-	//   have a serial and some don't have an IP address. This is only for logging.
-	identifier := "Serial: " + msg.Asset.Serial + ", IP(s): " + strings.Join(msg.Asset.IPAddresses, ",")
-
-	switch {
-	case msg.Asset.Execute:
-		err := b.executeCommand(msg.AssetExecute, &msg.Asset)
-		if err != nil {
-			b.Log.WithFields(logrus.Fields{
-				"component":    component,
-				"AssetType":    msg.Asset.Type,
-				"Error":        err,
-				"HardwareType": msg.Asset.HardwareType,
-				"ID":           identifier,
-				"IPAddress":    msg.Asset.IPAddress,                      // When we fail to login to the BMC, this field is not set...
-				"IPAddresses":  strings.Join(msg.Asset.IPAddresses, ","), // ... and that's why we list all tried IP addresses.
-				"Location":     msg.Asset.Location,
-				"Serial":       msg.Asset.Serial,
-				"Vendor":       msg.Asset.Vendor, // At this point the vendor may or may not be known.
-			}).Warn("Execute action returned error.")
-			metrics.IncrCounter([]string{"butler", "execute_fail"}, 1)
-			return
-		}
-
+	action, exists := actions[msg.Asset.Action]
+	if !exists {
 		b.Log.WithFields(logrus.Fields{
 			"component": component,
 			"Serial":    msg.Asset.Serial,
 			"AssetType": msg.Asset.Type,
 			"Vendor":    msg.Asset.Vendor, // At this point the vendor may or may not be known.
 			"Location":  msg.Asset.Location,
-		}).Info("Execute action succeeded.")
+		}).Warn("Unknown action request on asset.")
 
-		metrics.IncrCounter([]string{"butler", "execute_success"}, 1)
+		err = ErrUnknownAction
 		return
-	case msg.Asset.Configure:
-		err := b.configureAsset(msg.AssetConfig, &msg.Asset)
-		if err != nil {
-			b.Log.WithFields(logrus.Fields{
-				"component":    component,
-				"AssetType":    msg.Asset.Type,
-				"Error":        err,
-				"HardwareType": msg.Asset.HardwareType,
-				"ID":           identifier,
-				"IPAddress":    msg.Asset.IPAddress,                      // When we fail to login to the BMC, this field is not set...
-				"IPAddresses":  strings.Join(msg.Asset.IPAddresses, ","), // ... and that's why we list all tried IP addresses.
-				"Location":     msg.Asset.Location,
-				"Serial":       msg.Asset.Serial,
-				"Vendor":       msg.Asset.Vendor, // At this point the vendor may or may not be known.
-			}).Warn("Configure action returned error.")
-
-			metrics.IncrCounter([]string{"butler", "configure_fail"}, 1)
-			return
-		}
+	}
 
+	// This field helps with enumerating the unique assets we have, since some assets don't
+	//   have a serial and some don't have an IP address. This is only for logging.
+	identifier := "Serial: " + msg.Asset.Serial + ", IP(s): " + strings.Join(msg.Asset.IPAddresses, ",")
+
+	err = action(b, &msg, &result)
+	if err != nil {
 		b.Log.WithFields(logrus.Fields{
-			"AssetType":    msg.Asset.Type,
 			"component":    component,
+			"Action":       msg.Asset.Action,
+			"AssetType":    msg.Asset.Type,
 			"Error":        err,
 			"HardwareType": msg.Asset.HardwareType,
-			"IPAddress":    msg.Asset.IPAddress,
 			"ID":           identifier,
+			"IPAddress":    msg.Asset.IPAddress,                      // When we fail to login to the BMC, this field is not set...
+			"IPAddresses":  strings.Join(msg.Asset.IPAddresses, ","), // ... and that's why we list all tried IP addresses.
 			"Location":     msg.Asset.Location,
 			"Serial":       msg.Asset.Serial,
 			"Vendor":       msg.Asset.Vendor, // At this point the vendor may or may not be known.
-		}).Info("Configure action succeeded.")
+		}).Warn("Action returned error.")
 
-		metrics.IncrCounter([]string{"butler", "configure_success"}, 1)
+		if retryMsg, ok := b.retryMsg(msg, &result, err); ok && !b.interrupted() {
+			return &retryMsg
+		}
+
+		metrics.IncrCounter([]string{"butler", msg.Asset.Action + "_fail"}, 1)
+		countAsset(&msg.Asset, audit.OutcomeFailed)
+		b.Notifier.Notify(notify.AssetEvent(notify.EventFailure, &msg.Asset, err))
 		return
-	default:
-		b.Log.WithFields(logrus.Fields{
-			"component": component,
-			"Serial":    msg.Asset.Serial,
-			"AssetType": msg.Asset.Type,
-			"Vendor":    msg.Asset.Vendor, // At this point the vendor may or may not be known.
-			"Location":  msg.Asset.Location,
-		}).Warn("Unknown action request on asset.")
 	}
+
+	b.Log.WithFields(logrus.Fields{
+		"component":    component,
+		"Action":       msg.Asset.Action,
+		"AssetType":    msg.Asset.Type,
+		"HardwareType": msg.Asset.HardwareType,
+		"ID":           identifier,
+		"IPAddress":    msg.Asset.IPAddress,
+		"Location":     msg.Asset.Location,
+		"Serial":       msg.Asset.Serial,
+		"Vendor":       msg.Asset.Vendor,
+	}).Info("Action succeeded.")
+
+	metrics.IncrCounter([]string{"butler", msg.Asset.Action + "_success"}, 1)
+	countAsset(&msg.Asset, audit.OutcomeSuccess)
+	return nil
+}
+
+// actionFunc carries out an action on the msg asset,
+// details of the outcome such as the command output are recorded on the result.
+type actionFunc func(b *Butler, msg *Msg, result *Result) error
+
+// actions maps the asset action to the butler method carrying it out.
+var actions = map[string]actionFunc{
+	asset.ActionConfigure: func(b *Butler, msg *Msg, result *Result) error {
+		// Assets queued for retry apply again only the resources that failed.
+		resources := b.Config.Resources
+		if msg.retry != nil && msg.retry.resources != nil {
+			resources = msg.retry.resources
+		}
+
+		return b.configureAsset(msg.AssetConfig, &msg.Asset, msg.span, resources, result)
+	},
+	asset.ActionExecute: func(b *Butler, msg *Msg, result *Result) error {
+		return b.executeCommand(msg.AssetExecute, &msg.Asset, msg.span, result)
+	},
+	asset.ActionSetup: func(b *Butler, msg *Msg, result *Result) error {
+		return b.setupAsset(msg.AssetSetup, &msg.Asset, msg.span)
+	},
+	asset.ActionPlan: func(b *Butler, msg *Msg, result *Result) error {
+		return b.planAsset(msg.AssetConfig, &msg.Asset, msg.span)
+	},
+	asset.ActionCollect: func(b *Butler, msg *Msg, result *Result) error {
+		return b.collectAsset(&msg.Asset, msg.span)
+	},
+	asset.ActionReconcile: func(b *Butler, msg *Msg, result *Result) error {
+		return b.reconcileAsset(msg.AssetConfig, &msg.Asset, msg.span)
+	},
+	asset.ActionCerts: func(b *Butler, msg *Msg, result *Result) error {
+		return b.certsAsset(msg.AssetConfig, &msg.Asset, msg.span, result)
+	},
+	asset.ActionRotate: func(b *Butler, msg *Msg, result *Result) error {
+		return b.rotateCredential(msg.AssetConfig, msg.AssetPassword, &msg.Asset, msg.span)
+	},
+	asset.ActionFirmware: func(b *Butler, msg *Msg, result *Result) error {
+		return b.firmwareAsset(&msg.Asset, msg.span, result)
+	},
 }
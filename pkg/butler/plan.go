@@ -0,0 +1,122 @@
+package butler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/bmc-toolbox/bmcbutler/pkg/asset"
+	"github.com/bmc-toolbox/bmcbutler/pkg/butler/configure"
+	"github.com/bmc-toolbox/bmcbutler/pkg/resource"
+	"github.com/bmc-toolbox/bmcbutler/pkg/tracing"
+	"github.com/bmc-toolbox/bmclib/devices"
+	"github.com/bmc-toolbox/bmclogin"
+)
+
+// Plans and facts for different assets are written to stdout by concurrent butlers.
+var outputMutex sync.Mutex
+
+// planAsset sets up the bmc connection,
+// gets any Asset config templated data rendered,
+// and prints the changes configureAsset would apply.
+func (b *Butler) planAsset(config *resource.Layers, asset *asset.Asset, span *tracing.Span) (err error) {
+	component := "planAsset"
+
+	defer b.timeTrack(time.Now(), "planAsset", asset)
+
+	b.Log.WithFields(logrus.Fields{
+		"component": component,
+		"Serial":    asset.Serial,
+		"IPAddress": asset.IPAddresses,
+	}).Debug("Connecting to asset...")
+
+	bmcConn := bmclogin.Params{
+		IpAddresses:     asset.IPAddresses,
+		Credentials:     b.credentials(asset),
+		CheckCredential: true,
+		Retries:         1,
+		StopChan:        b.StopChan,
+	}
+
+	client, loginInfo, err := login(span, bmcConn)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrLoginFailed, err)
+	}
+
+	asset.IPAddress = loginInfo.ActiveIpAddress
+
+	var plans []configure.ResourcePlan
+
+	// The power state is listed for servers, it's not part of the configuration.
+	var state string
+
+	switch clientType := client.(type) {
+	case devices.Bmc:
+		bmc := client.(devices.Bmc)
+		defer bmc.Close(context.TODO())
+
+		asset.Type = "server"
+		asset.HardwareType = bmc.HardwareType()
+		asset.Vendor = bmc.Vendor()
+
+		resourceInstance := resource.Resource{Log: b.Log, Asset: asset, Secrets: b.Secrets, Span: span}
+		renderedConfig, err := resourceInstance.LoadLayers(config)
+		if err != nil {
+			return err
+		}
+
+		if renderedConfig == nil {
+			return errors.New("No BMC configuration to be applied!")
+		}
+
+		c := configure.NewBmcConfigurator(bmc, asset, span, b.Config.Resources, renderedConfig, b.Config, b.StopChan, b.Log)
+		plans = c.Plan()
+		state = ", PowerState: " + powerState(bmc)
+	case devices.Cmc:
+		chassis := client.(devices.Cmc)
+		defer chassis.Close()
+
+		asset.Type = "chassis"
+		asset.HardwareType = chassis.HardwareType()
+		asset.Vendor = chassis.Vendor()
+
+		resourceInstance := resource.Resource{Log: b.Log, Asset: asset, Secrets: b.Secrets, Span: span}
+		renderedConfig, err := resourceInstance.LoadLayers(config)
+		if err != nil {
+			return err
+		}
+
+		if renderedConfig == nil {
+			return errors.New("No CMC configuration to be applied!")
+		}
+
+		c := configure.NewCmcConfigurator(chassis, asset, span, b.Config.Resources, renderedConfig, b.Config, b.StopChan, b.Log)
+		plans = c.Plan()
+	default:
+		b.Log.WithFields(logrus.Fields{
+			"component": component,
+			"Type":      fmt.Sprintf("%s", clientType),
+		}).Warn("Unknown device type.")
+		return fmt.Errorf("Unknown device type \"%s\"!", clientType)
+	}
+
+	header := fmt.Sprintf("# Serial: %s, IP: %s, Vendor: %s, HardwareType: %s",
+		asset.Serial,
+		asset.IPAddress,
+		asset.Vendor,
+		asset.HardwareType,
+	) + state
+
+	outputMutex.Lock()
+	defer outputMutex.Unlock()
+
+	fmt.Fprintln(os.Stdout, header)
+	fmt.Fprintln(os.Stdout, configure.FormatPlan(plans))
+
+	return nil
+}
@@ -0,0 +1,391 @@
+package butler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+
+	"github.com/bmc-toolbox/bmcbutler/pkg/asset"
+	"github.com/bmc-toolbox/bmcbutler/pkg/butler/configure"
+	"github.com/bmc-toolbox/bmcbutler/pkg/metrics"
+	"github.com/bmc-toolbox/bmcbutler/pkg/resource"
+	"github.com/bmc-toolbox/bmcbutler/pkg/tracing"
+	"github.com/bmc-toolbox/bmclib/cfgresources"
+	"github.com/bmc-toolbox/bmclib/devices"
+	"github.com/bmc-toolbox/bmclogin"
+)
+
+// Reconcile outcomes recorded for assets.
+const (
+	ReconcileInSync        = "in_sync"
+	ReconcileDrifted       = "drifted"
+	ReconcileConfigChanged = "config_changed"
+	ReconcileRetried       = "retried" // Resources that failed to apply, or were deferred, on the previous reconcile were applied again.
+)
+
+// ReconcileAssetState is the state recorded for an asset when it was last reconciled.
+type ReconcileAssetState struct {
+	ConfigHash string    `json:"config_hash"` // sha256 of the rendered configuration.
+	Reconciled time.Time `json:"reconciled"`
+	Outcome    string    `json:"outcome"`
+	Drifted    []string  `json:"drifted,omitempty"`  // Resources found to have drifted.
+	Applied    []string  `json:"applied,omitempty"`  // Resources applied, empty if all were applied.
+	Failed     []string  `json:"failed,omitempty"`   // Resources that failed to apply, applied again on the next reconcile.
+	Deferred   []string  `json:"deferred,omitempty"` // Disruptive resources skipped outside the maintenance window, applied again on the next reconcile.
+}
+
+// ReconcileState holds the state of reconciled assets,
+// it is persisted to File if declared.
+type ReconcileState struct {
+	File   string `json:"-"`
+	mu     sync.Mutex
+	Assets map[string]ReconcileAssetState `json:"assets"`
+}
+
+// LoadReconcileState returns the reconcile state read from the file,
+// a missing file is not an error.
+func LoadReconcileState(file string) (*ReconcileState, error) {
+	state := &ReconcileState{File: file, Assets: make(map[string]ReconcileAssetState)}
+	if file == "" {
+		return state, nil
+	}
+
+	data, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, state)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse reconcile state file %s: %s", file, err)
+	}
+
+	if state.Assets == nil {
+		state.Assets = make(map[string]ReconcileAssetState)
+	}
+
+	return state, nil
+}
+
+// Save writes the reconcile state to the state file, if declared.
+func (s *ReconcileState) Save() error {
+	if s.File == "" {
+		return nil
+	}
+
+	s.mu.Lock()
+	data, err := json.MarshalIndent(s, "", "  ")
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	// Written to a temporary file first, so an interrupted write doesn't leave a truncated state.
+	tmp, err := ioutil.TempFile(filepath.Dir(s.File), filepath.Base(s.File)+".tmp")
+	if err != nil {
+		return err
+	}
+
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.File)
+}
+
+// Get returns the state recorded for the asset.
+func (s *ReconcileState) Get(key string) (ReconcileAssetState, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, exists := s.Assets[key]
+	return state, exists
+}
+
+// Set records the state for the asset.
+func (s *ReconcileState) Set(key string, state ReconcileAssetState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Assets[key] = state
+}
+
+// setApplied records the state for the asset once configuration was applied,
+// resources that failed to apply or were skipped are recorded to be applied again on the next reconcile,
+// failed resources are returned as an error.
+func (s *ReconcileState) setApplied(key string, state ReconcileAssetState, applied *configure.Applied) error {
+	state.Failed = applied.Failed()
+	state.Deferred = applied.Skipped()
+	s.Set(key, state)
+
+	if len(state.Failed) > 0 {
+		return fmt.Errorf("resources failed to apply: %s", strings.Join(state.Failed, ", "))
+	}
+
+	return nil
+}
+
+// Since returns the states of assets reconciled since the given time.
+func (s *ReconcileState) Since(t time.Time) (states []ReconcileAssetState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, state := range s.Assets {
+		if !state.Reconciled.Before(t) {
+			states = append(states, state)
+		}
+	}
+
+	return states
+}
+
+// configHash returns the sha256 of the rendered configuration.
+func configHash(config *cfgresources.ResourcesConfig) (string, error) {
+	data, err := yaml.Marshal(config)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// driftedResources returns the resources the plan lists as changed,
+// resources bmclib doesn't expose the current state for are not considered.
+func driftedResources(plans []configure.ResourcePlan) (drifted []string) {
+	for _, p := range plans {
+		if p.Readable && p.Change {
+			drifted = append(drifted, p.Resource)
+		}
+	}
+
+	return drifted
+}
+
+// reconcileResources returns the reconcile outcome and the resources to apply,
+// given the state recorded when the asset was last reconciled if any,
+// the hash of the rendered configuration and the resources found to have drifted.
+// nil resources applies all resources, or the ones passed with --resources.
+func reconcileResources(previous ReconcileAssetState, reconciled bool, hash string, drifted []string, all []string) (outcome string, resources []string) {
+	if !reconciled || previous.ConfigHash != hash {
+		return ReconcileConfigChanged, all
+	}
+
+	if len(previous.Failed) > 0 || len(previous.Deferred) > 0 {
+		resources = append(resources, previous.Failed...)
+		for _, list := range [][]string{previous.Deferred, drifted} {
+			for _, r := range list {
+				if !contains(resources, r) {
+					resources = append(resources, r)
+				}
+			}
+		}
+
+		return ReconcileRetried, resources
+	}
+
+	if len(drifted) > 0 {
+		return ReconcileDrifted, drifted
+	}
+
+	return ReconcileInSync, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+// reconcileAsset sets up the bmc connection,
+// gets any Asset config templated data rendered,
+// and re-applies configuration if it changed since the asset was last reconciled,
+// or only the resources found to have drifted or that failed to apply on the last reconcile.
+func (b *Butler) reconcileAsset(config *resource.Layers, asset *asset.Asset, span *tracing.Span) (err error) { // nolint: gocyclo
+	component := "reconcileAsset"
+
+	if b.ReconcileState == nil {
+		return errors.New("No reconcile state declared!")
+	}
+
+	defer b.timeTrack(time.Now(), "reconcileAsset", asset)
+	defer metrics.MeasureRuntime([]string{"butler", "reconcile_runtime"}, time.Now())
+
+	b.Log.WithFields(logrus.Fields{
+		"component": component,
+		"Serial":    asset.Serial,
+		"IPAddress": asset.IPAddresses,
+	}).Debug("Connecting to asset...")
+
+	bmcConn := bmclogin.Params{
+		IpAddresses:     asset.IPAddresses,
+		Credentials:     b.credentials(asset),
+		CheckCredential: true,
+		Retries:         1,
+		StopChan:        b.StopChan,
+	}
+
+	client, loginInfo, err := login(span, bmcConn)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrLoginFailed, err)
+	}
+
+	asset.IPAddress = loginInfo.ActiveIpAddress
+
+	err = b.limitAsset(asset, client)
+	if err != nil {
+		return err
+	}
+
+	var renderedConfig *cfgresources.ResourcesConfig
+	var plan func() []configure.ResourcePlan
+	var apply func(resources []string) *configure.Applied
+
+	switch clientType := client.(type) {
+	case devices.Bmc:
+		bmc := client.(devices.Bmc)
+		defer bmc.Close(context.TODO())
+
+		asset.Type = "server"
+		asset.HardwareType = bmc.HardwareType()
+		asset.Vendor = bmc.Vendor()
+
+		resourceInstance := resource.Resource{Log: b.Log, Asset: asset, Secrets: b.Secrets, Span: span}
+		renderedConfig, err = resourceInstance.LoadLayers(config)
+		if err != nil {
+			return err
+		}
+
+		if renderedConfig == nil {
+			return errors.New("No BMC configuration to be applied!")
+		}
+
+		plan = configure.NewBmcConfigurator(bmc, asset, span, b.Config.Resources, renderedConfig, b.Config, b.StopChan, b.Log).Plan
+		apply = func(resources []string) *configure.Applied {
+			return configure.NewBmcConfigurator(bmc, asset, span, resources, renderedConfig, b.Config, b.StopChan, b.Log).Apply()
+		}
+	case devices.Cmc:
+		chassis := client.(devices.Cmc)
+		defer chassis.Close()
+
+		asset.Type = "chassis"
+		asset.HardwareType = chassis.HardwareType()
+		asset.Vendor = chassis.Vendor()
+
+		resourceInstance := resource.Resource{Log: b.Log, Asset: asset, Secrets: b.Secrets, Span: span}
+		renderedConfig, err = resourceInstance.LoadLayers(config)
+		if err != nil {
+			return err
+		}
+
+		if renderedConfig == nil {
+			return errors.New("No CMC configuration to be applied!")
+		}
+
+		plan = configure.NewCmcConfigurator(chassis, asset, span, b.Config.Resources, renderedConfig, b.Config, b.StopChan, b.Log).Plan
+		apply = func(resources []string) *configure.Applied {
+			return configure.NewCmcConfigurator(chassis, asset, span, resources, renderedConfig, b.Config, b.StopChan, b.Log).Apply()
+		}
+	default:
+		b.Log.WithFields(logrus.Fields{
+			"component": component,
+			"Type":      fmt.Sprintf("%s", clientType),
+		}).Warn("Unknown device type.")
+		return fmt.Errorf("Unknown device type \"%s\"!", clientType)
+	}
+
+	hash, err := configHash(renderedConfig)
+	if err != nil {
+		return err
+	}
+
+	key := asset.Key()
+	previous, reconciled := b.ReconcileState.Get(key)
+
+	state := ReconcileAssetState{
+		ConfigHash: hash,
+		Reconciled: time.Now(),
+		Drifted:    driftedResources(plan()),
+	}
+
+	var resources []string
+	state.Outcome, resources = reconcileResources(previous, reconciled, hash, state.Drifted, b.Config.Resources)
+
+	switch state.Outcome {
+	case ReconcileConfigChanged:
+		metrics.IncrCounter([]string{"reconcile", "asset_config_changed"}, 1)
+	case ReconcileRetried:
+		metrics.IncrCounter([]string{"reconcile", "asset_retried"}, 1)
+	case ReconcileDrifted:
+		metrics.IncrCounter([]string{"reconcile", "asset_drifted"}, 1)
+	default:
+		metrics.IncrCounter([]string{"reconcile", "asset_in_sync"}, 1)
+	}
+
+	for _, r := range state.Drifted {
+		metrics.IncrCounter([]string{"reconcile", "resource_drifted", r}, 1)
+	}
+
+	state.Applied = resources
+
+	log := b.Log.WithFields(logrus.Fields{
+		"component": component,
+		"Serial":    asset.Serial,
+		"IPAddress": asset.IPAddress,
+		"Vendor":    asset.Vendor,
+		"Outcome":   state.Outcome,
+		"Drifted":   strings.Join(state.Drifted, ", "),
+	})
+
+	if state.Outcome == ReconcileInSync {
+		log.Debug("Asset configuration in sync.")
+		b.ReconcileState.Set(key, state)
+		return nil
+	}
+
+	if b.Config.DryRun {
+		log.Info("Dry run, asset configuration will not be reconciled.")
+		return nil
+	}
+
+	log.Info("Reconciling asset configuration.")
+
+	applied := apply(resources)
+	b.recordApplied(config.Hash(asset), asset, applied)
+
+	// Resources not applied since an interrupt was received are not known,
+	// the state is left as is so the asset is reconciled again.
+	select {
+	case <-b.StopChan:
+		return ErrInterrupted
+	default:
+	}
+
+	return b.ReconcileState.setApplied(key, state, applied)
+}
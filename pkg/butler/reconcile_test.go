@@ -0,0 +1,148 @@
+package butler
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bmc-toolbox/bmclib/cfgresources"
+
+	"github.com/bmc-toolbox/bmcbutler/pkg/butler/configure"
+)
+
+// Test the reconcile state is carried across a save and load.
+func TestReconcileStateSaveLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reconcile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "state.json")
+
+	state, err := LoadReconcileState(file)
+	if err != nil {
+		t.Fatalf("Expected a missing state file to be ignored, got: %s", err)
+	}
+
+	reconciled := time.Now().UTC().Truncate(time.Second)
+	state.Set("fooserial", ReconcileAssetState{ConfigHash: "abc", Reconciled: reconciled, Outcome: ReconcileDrifted, Drifted: []string{"https_cert"}})
+
+	err = state.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadReconcileState(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, exists := loaded.Get("fooserial")
+	if !exists || s.ConfigHash != "abc" || !s.Reconciled.Equal(reconciled) || len(s.Drifted) != 1 {
+		t.Fatalf("Unexpected state loaded: %+v", s)
+	}
+
+	if len(loaded.Since(reconciled.Add(time.Second))) != 0 || len(loaded.Since(reconciled)) != 1 {
+		t.Fatal("Expected Since to filter on the reconciled time.")
+	}
+}
+
+// Test the config hash changes with the rendered configuration.
+func TestConfigHash(t *testing.T) {
+	a, _ := configHash(&cfgresources.ResourcesConfig{Ntp: &cfgresources.Ntp{Server1: "ntp0.example.com"}})
+	b, _ := configHash(&cfgresources.ResourcesConfig{Ntp: &cfgresources.Ntp{Server1: "ntp0.example.com"}})
+	c, _ := configHash(&cfgresources.ResourcesConfig{Ntp: &cfgresources.Ntp{Server1: "ntp1.example.com"}})
+
+	if a != b || a == c {
+		t.Fatal("Expected the config hash to change only with the configuration.")
+	}
+}
+
+// Test only resources bmclib exposes the current state for are considered drifted.
+func TestDriftedResources(t *testing.T) {
+	plans := []configure.ResourcePlan{
+		{Resource: "https_cert", Readable: true, Change: true},
+		{Resource: "ntp", Readable: false, Change: true},
+		{Resource: "syslog", Readable: true, Change: false},
+	}
+
+	drifted := driftedResources(plans)
+	if len(drifted) != 1 || drifted[0] != "https_cert" {
+		t.Fatalf("Unexpected drifted resources: %v", drifted)
+	}
+}
+
+// Test resources that failed to apply are recorded and applied again on the next reconcile,
+// along with any drifted resources, until the configuration changes.
+func TestReconcileFailedApply(t *testing.T) {
+	state := &ReconcileState{Assets: make(map[string]ReconcileAssetState)}
+
+	outcome, resources := reconcileResources(ReconcileAssetState{}, false, "abc", nil, nil)
+	if outcome != ReconcileConfigChanged || resources != nil {
+		t.Fatalf("Expected all resources applied for an asset not reconciled before, got %s %v", outcome, resources)
+	}
+
+	applied := &configure.Applied{Resources: []configure.ResourceResult{
+		{Resource: "ntp", Success: true},
+		{Resource: "syslog", Error: "connection reset"},
+	}}
+
+	err := state.setApplied("fooserial", ReconcileAssetState{ConfigHash: "abc", Outcome: outcome}, applied)
+	if err == nil {
+		t.Fatal("Expected an error for resources that failed to apply.")
+	}
+
+	previous, reconciled := state.Get("fooserial")
+	if !reconciled || len(previous.Failed) != 1 || previous.Failed[0] != "syslog" {
+		t.Fatalf("Expected the failed resource recorded, got %+v", previous)
+	}
+
+	outcome, resources = reconcileResources(previous, reconciled, "abc", []string{"https_cert", "syslog"}, nil)
+	if outcome != ReconcileRetried || len(resources) != 2 || resources[0] != "syslog" || resources[1] != "https_cert" {
+		t.Fatalf("Expected the failed and drifted resources applied again, got %s %v", outcome, resources)
+	}
+
+	outcome, resources = reconcileResources(previous, reconciled, "def", nil, nil)
+	if outcome != ReconcileConfigChanged || resources != nil {
+		t.Fatalf("Expected all resources applied once the configuration changed, got %s %v", outcome, resources)
+	}
+
+	err = state.setApplied("fooserial", ReconcileAssetState{ConfigHash: "abc", Outcome: ReconcileRetried}, &configure.Applied{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	previous, reconciled = state.Get("fooserial")
+	outcome, _ = reconcileResources(previous, reconciled, "abc", nil, nil)
+	if outcome != ReconcileInSync {
+		t.Fatalf("Expected the asset in sync once resources applied, got %s", outcome)
+	}
+}
+
+// Test disruptive resources skipped outside the maintenance window are applied again on the next reconcile.
+func TestReconcileDeferredApply(t *testing.T) {
+	state := &ReconcileState{Assets: make(map[string]ReconcileAssetState)}
+
+	applied := &configure.Applied{Resources: []configure.ResourceResult{
+		{Resource: "ntp", Success: true},
+		{Resource: "network", Skipped: true, Reason: "outside the ams4 maintenance window"},
+	}}
+
+	err := state.setApplied("fooserial", ReconcileAssetState{ConfigHash: "abc", Outcome: ReconcileConfigChanged}, applied)
+	if err != nil {
+		t.Fatalf("Expected skipped resources not to fail, got %s", err)
+	}
+
+	previous, reconciled := state.Get("fooserial")
+	if len(previous.Failed) != 0 || len(previous.Deferred) != 1 || previous.Deferred[0] != "network" {
+		t.Fatalf("Expected the skipped resource recorded as deferred, got %+v", previous)
+	}
+
+	outcome, resources := reconcileResources(previous, reconciled, "abc", []string{"network", "syslog"}, nil)
+	if outcome != ReconcileRetried || len(resources) != 2 || resources[0] != "network" || resources[1] != "syslog" {
+		t.Fatalf("Expected the deferred and drifted resources applied, got %s %v", outcome, resources)
+	}
+}
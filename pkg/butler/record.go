@@ -0,0 +1,91 @@
+package butler
+
+import (
+	"github.com/bmc-toolbox/bmcbutler/pkg/asset"
+	"github.com/bmc-toolbox/bmcbutler/pkg/audit"
+	"github.com/bmc-toolbox/bmcbutler/pkg/butler/configure"
+	"github.com/bmc-toolbox/bmcbutler/pkg/metrics"
+	"github.com/bmc-toolbox/bmcbutler/pkg/notify"
+)
+
+// metricLabels returns the labels of metrics counted for the asset, with the outcome.
+func metricLabels(asset *asset.Asset, outcome string) []metrics.Label {
+	return []metrics.Label{
+		{Name: "action", Value: asset.Action},
+		{Name: "vendor", Value: asset.Vendor},
+		{Name: "hardware_type", Value: asset.HardwareType},
+		{Name: "location", Value: asset.Location},
+		{Name: "outcome", Value: outcome},
+	}
+}
+
+// countAsset counts the outcome of the action on the asset, labelled by vendor, hardware type and location.
+func countAsset(asset *asset.Asset, outcome string) {
+	metrics.IncrCounterWithLabels([]string{"butler", "asset"}, 1, metricLabels(asset, outcome))
+}
+
+// auditEntry returns the audit entry for a change to the asset.
+func auditEntry(asset *asset.Asset) audit.Entry {
+	return audit.Entry{
+		Action:       asset.Action,
+		Serial:       asset.Serial,
+		IPAddress:    asset.IPAddress,
+		Vendor:       asset.Vendor,
+		HardwareType: asset.HardwareType,
+		Type:         asset.Type,
+		Location:     asset.Location,
+	}
+}
+
+// recordApplied counts the outcome of each resource applied to the asset,
+// records it in the audit journal along with the hash of the configuration the resources were declared in,
+// and notifies of BMC resets.
+func (b *Butler) recordApplied(hash string, asset *asset.Asset, applied *configure.Applied) {
+	if applied == nil {
+		return
+	}
+
+	if applied.Reset {
+		b.Notifier.Notify(notify.AssetEvent(notify.EventReset, asset, nil))
+	}
+
+	for _, r := range applied.Resources {
+		e := auditEntry(asset)
+		e.ConfigHash = hash
+		e.Resource = r.Resource
+
+		switch {
+		case r.Skipped:
+			e.Outcome = audit.OutcomeSkipped
+			e.Error = r.Reason
+		case r.Success:
+			e.Outcome = audit.OutcomeSuccess
+		default:
+			e.Outcome = audit.OutcomeFailed
+			e.Error = r.Error
+		}
+
+		labels := append(metricLabels(asset, e.Outcome), metrics.Label{Name: "resource", Value: r.Resource})
+		metrics.IncrCounterWithLabels([]string{"butler", "resource"}, 1, labels)
+
+		b.Journal.Record(e)
+	}
+}
+
+// journalCommand records the outcome of the command executed on the asset in the audit journal.
+func (b *Butler) journalCommand(command string, asset *asset.Asset, err error) {
+	if b.Journal == nil {
+		return
+	}
+
+	e := auditEntry(asset)
+	e.Command = command
+	e.Outcome = audit.OutcomeSuccess
+
+	if err != nil {
+		e.Outcome = audit.OutcomeFailed
+		e.Error = err.Error()
+	}
+
+	b.Journal.Record(e)
+}
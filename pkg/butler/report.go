@@ -0,0 +1,73 @@
+package butler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"os"
+)
+
+// report is a record butlers write for each asset, as a JSON line or a CSV record.
+type report interface {
+	csvRecord() []string
+}
+
+// assetReport is the report of an asset, written once the last attempt on the asset is done,
+// so assets retried are reported once.
+type assetReport struct {
+	record report
+	format string
+}
+
+// output returns the writer reports are written to, os.Stdout if none is declared.
+func (b *Butler) output() io.Writer {
+	if b.Output == nil {
+		return os.Stdout
+	}
+
+	return b.Output
+}
+
+// writeAssetReport writes the report recorded on the result, if any.
+func (b *Butler) writeAssetReport(result *Result) error {
+	if result.report == nil {
+		return nil
+	}
+
+	return writeReport(b.output(), result.report.record, result.report.format)
+}
+
+// writeReportHeader writes the CSV header for reports, other formats have no header.
+func writeReportHeader(w io.Writer, columns []string, format string) error {
+	if format != "csv" {
+		return nil
+	}
+
+	writer := csv.NewWriter(w)
+	err := writer.Write(columns)
+	if err != nil {
+		return err
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// writeReport writes the report to w as a JSON line or a CSV record.
+func writeReport(w io.Writer, r report, format string) error {
+	outputMutex.Lock()
+	defer outputMutex.Unlock()
+
+	if format == "csv" {
+		writer := csv.NewWriter(w)
+		err := writer.Write(r.csvRecord())
+		if err != nil {
+			return err
+		}
+
+		writer.Flush()
+		return writer.Error()
+	}
+
+	return json.NewEncoder(w).Encode(r)
+}
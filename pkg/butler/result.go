@@ -0,0 +1,61 @@
+package butler
+
+import (
+	"errors"
+	"time"
+
+	"github.com/bmc-toolbox/bmclogin"
+
+	"github.com/bmc-toolbox/bmcbutler/pkg/asset"
+	"github.com/bmc-toolbox/bmcbutler/pkg/butler/configure"
+)
+
+// Result is the outcome of the action a butler carried out on an asset.
+type Result struct {
+	JobID       string
+	Asset       asset.Asset
+	Action      string // The asset action, see the asset Action constants.
+	Success     bool
+	Skipped     bool                       // The action was not attempted, Error holds the reason.
+	Error       string                     // The action error, or the reason the asset was skipped.
+	LoginFailed bool                       // The action failed since none of the credentials could login to the asset.
+	Output      string                     // The command output, for the execute action.
+	User        string                     // The user of the credentials the login worked with.
+	Resources   []configure.ResourceResult // The outcome of each configuration resource, for the configure action.
+	Reset       bool                       // The BMC was reset for configuration to take effect.
+	Attempts    int                        // The attempts made on the asset, assets that failed with transient errors are retried.
+	Start       time.Time
+	End         time.Time
+	report      *assetReport // The report of the asset, for the certs and firmware actions.
+}
+
+// sendResult sends the result of the action carried out on the msg asset over the ResultChan,
+// the result holds any details the action recorded.
+func (b *Butler) sendResult(msg *Msg, result *Result, start time.Time, err error, skipped error) {
+	result.JobID = msg.JobID
+	result.Asset = msg.Asset
+	result.Action = msg.Asset.Action
+	result.Success = err == nil && skipped == nil
+	result.Skipped = skipped != nil
+	result.LoginFailed = errors.Is(err, ErrLoginFailed)
+	result.Start = start
+	result.End = time.Now()
+
+	switch {
+	case skipped != nil:
+		result.Error = skipped.Error()
+	case err != nil:
+		result.Error = err.Error()
+	}
+
+	b.ResultChan <- *result
+}
+
+// loginUser returns the user of the credentials the login worked with.
+func loginUser(loginInfo bmclogin.LoginInfo) string {
+	for user := range loginInfo.WorkingCredentials {
+		return user
+	}
+
+	return ""
+}
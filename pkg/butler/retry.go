@@ -0,0 +1,186 @@
+package butler
+
+import (
+	"errors"
+	"net"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/bmc-toolbox/bmcbutler/pkg/asset"
+	"github.com/bmc-toolbox/bmcbutler/pkg/butler/configure"
+	"github.com/bmc-toolbox/bmcbutler/pkg/config"
+	"github.com/bmc-toolbox/bmcbutler/pkg/metrics"
+)
+
+// retryState carries the outcome of earlier attempts on an asset queued for retry.
+type retryState struct {
+	attempts  int                        // The attempts made on the asset so far.
+	resources []string                   // The resources to apply again, for the configure action.
+	previous  []configure.ResourceResult // The outcome of resources not applied again.
+	reset     bool                       // The BMC was reset on an earlier attempt.
+	report    *assetReport               // The report of the last attempt, written once the asset is done.
+}
+
+// transientMessages are error message fragments of failures expected to go away on a later attempt.
+var transientMessages = []string{
+	"timeout",
+	"timed out",
+	"connection refused",
+	"connection reset",
+	"no route to host",
+	"host is unreachable",
+	"broken pipe",
+	"unexpected eof",
+	"temporarily unavailable",
+	"internal server error",
+	"bad gateway",
+	"service unavailable",
+}
+
+// serverErrorStatus matches HTTP 5xx statuses in error messages, other than 501 Not Implemented.
+var serverErrorStatus = regexp.MustCompile(`(?i)(status(\s*code)?|http)\s*[:=]?\s*5(0[02-9]|[1-9]\d)\b`)
+
+// retryable returns true if the action error is deemed transient,
+// unknown errors, such as unsupported resources or template errors, are deemed permanent.
+func retryable(err error) bool {
+	switch {
+	case err == nil, errors.Is(err, ErrInterrupted), errors.Is(err, ErrCancelled), errors.Is(err, ErrUnknownAction):
+		return false
+	case errors.Is(err, ErrLoginFailed):
+		// bmclogin doesn't tell apart BMCs that were unreachable from credentials that didn't work.
+		return true
+	case errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.ECONNRESET):
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	return retryableMessage(err.Error())
+}
+
+// retryableMessage returns true if the error message is of a failure deemed transient.
+func retryableMessage(message string) bool {
+	m := strings.ToLower(message)
+	for _, t := range transientMessages {
+		if strings.Contains(m, t) {
+			return true
+		}
+	}
+
+	return serverErrorStatus.MatchString(message)
+}
+
+// retryBackoff returns the delay before the next attempt, given the attempts made so far.
+func retryBackoff(retry *config.Retry, attempts int) time.Duration {
+	delay := retry.Backoff
+	for i := 1; i < attempts && delay < retry.MaxBackoff; i++ {
+		delay *= 2
+	}
+
+	if delay > retry.MaxBackoff {
+		return retry.MaxBackoff
+	}
+
+	return delay
+}
+
+// retryMsg returns the msg to queue again for the asset that failed with the action error,
+// false if the error is permanent, or the asset is out of attempts.
+// Configure applies again only the resources that failed with transient errors,
+// execute commands are retried only if the asset could not be logged into.
+func (b *Butler) retryMsg(msg Msg, result *Result, err error) (Msg, bool) {
+	if b.Config.Retry == nil || result.Attempts >= b.Config.Retry.Attempts {
+		return msg, false
+	}
+
+	state := &retryState{}
+	if msg.retry != nil {
+		*state = *msg.retry
+	}
+
+	state.attempts = result.Attempts
+	state.report = result.report
+
+	switch {
+	case errors.Is(err, ErrLoginFailed):
+	case msg.Asset.Action == asset.ActionExecute:
+		return msg, false
+	case msg.Asset.Action == asset.ActionConfigure && len(result.Resources) > 0:
+		state.resources, state.previous = nil, nil
+		for _, r := range result.Resources {
+			if !r.Success && retryableMessage(r.Error) {
+				state.resources = append(state.resources, r.Resource)
+			} else {
+				state.previous = append(state.previous, r)
+			}
+		}
+
+		if len(state.resources) == 0 {
+			return msg, false
+		}
+
+		state.reset = result.Reset
+	case !retryable(err):
+		return msg, false
+	}
+
+	msg.retry = state
+	return msg, true
+}
+
+// queueRetry queues the msg to be submitted again once the backoff for the attempts made elapsed,
+// or right away if an interrupt is received, for the asset to be reported as skipped.
+func (b *Butler) queueRetry(msg Msg) {
+	delay := retryBackoff(b.Config.Retry, msg.retry.attempts)
+
+	b.Log.WithFields(logrus.Fields{
+		"component": "queueRetry",
+		"Action":    msg.Asset.Action,
+		"Serial":    msg.Asset.Serial,
+		"IPAddress": msg.Asset.IPAddress,
+		"Attempts":  msg.retry.attempts,
+		"Resources": strings.Join(msg.retry.resources, ", "),
+		"Delay":     delay,
+	}).Info("Asset queued for retry.")
+
+	metrics.IncrCounter([]string{"butler", msg.Asset.Action + "_retry"}, 1)
+
+	b.pending.Add(1)
+	go func() {
+		defer b.pending.Done()
+
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+		case <-b.StopChan:
+		}
+
+		b.limiter.acquire(msg.Asset.Key(), &msg.Asset, b.StopChan) // nolint: errcheck
+		b.submit(msg)
+	}()
+}
+
+// retryResult returns the result for an attempt on the msg asset,
+// with the outcome of earlier attempts recorded.
+func retryResult(msg *Msg) Result {
+	result := Result{Attempts: 1}
+	if msg.retry == nil {
+		return result
+	}
+
+	result.Attempts += msg.retry.attempts
+	result.Resources = append(result.Resources, msg.retry.previous...)
+	result.Reset = msg.retry.reset
+	result.report = msg.retry.report
+
+	return result
+}
@@ -0,0 +1,139 @@
+package butler
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/bmc-toolbox/bmcbutler/pkg/asset"
+	"github.com/bmc-toolbox/bmcbutler/pkg/butler/configure"
+	"github.com/bmc-toolbox/bmcbutler/pkg/config"
+)
+
+// Test errors are classified as transient or permanent.
+func TestRetryable(t *testing.T) {
+	cases := []struct {
+		err       error
+		retryable bool
+	}{
+		{fmt.Errorf("%w: All attempts to login failed.", ErrLoginFailed), true},
+		{fmt.Errorf("dial tcp 192.0.2.1:443: %w", syscall.ECONNREFUSED), true},
+		{errors.New("Get https://192.0.2.1/redfish/v1: net/http: request canceled (Client.Timeout exceeded while awaiting headers)"), true},
+		{errors.New("read tcp 192.0.2.1:443: connection reset by peer"), true},
+		{errors.New("Received an unexpected status code: 503"), true},
+		{errors.New("HTTP 502 Bad Gateway"), true},
+		{errors.New("Received an unexpected status code: 501"), false},
+		{errors.New("Received an unexpected status code: 404"), false},
+		{errors.New("template: configuration.yml:3: function \"foo\" not defined"), false},
+		{errors.New("ntp resource not supported on this device"), false},
+		{ErrInterrupted, false},
+		{ErrUnknownAction, false},
+	}
+
+	for _, c := range cases {
+		if retryable(c.err) != c.retryable {
+			t.Errorf("Expected %q retryable: %t", c.err, c.retryable)
+		}
+	}
+}
+
+// Test the backoff doubles with each attempt, up to the max backoff.
+func TestRetryBackoff(t *testing.T) {
+	retry := &config.Retry{Attempts: 5, Backoff: time.Minute, MaxBackoff: 5 * time.Minute}
+
+	expected := []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute, 5 * time.Minute}
+	for i, e := range expected {
+		if d := retryBackoff(retry, i+1); d != e {
+			t.Errorf("Expected a %s backoff after %d attempts, got %s", e, i+1, d)
+		}
+	}
+}
+
+// Test configure retries only the resources that failed with transient errors,
+// and the outcome of the other resources is carried to the retry result.
+func TestRetryMsg(t *testing.T) {
+	b := &Butler{Config: &config.Params{Retry: &config.Retry{Attempts: 2, Backoff: time.Minute, MaxBackoff: time.Minute}}}
+
+	msg := Msg{Asset: asset.Asset{Serial: "FOO", Action: asset.ActionConfigure}}
+	result := retryResult(&msg)
+	result.Resources = []configure.ResourceResult{
+		{Resource: "ntp", Success: true},
+		{Resource: "syslog", Error: "dial tcp 192.0.2.1:443: i/o timeout"},
+		{Resource: "ldap", Error: "ldap resource not supported"},
+	}
+	result.Reset = true
+
+	err := errors.New("resources failed to apply: syslog, ldap")
+	retry, ok := b.retryMsg(msg, &result, err)
+	if !ok || len(retry.retry.resources) != 1 || retry.retry.resources[0] != "syslog" {
+		t.Fatalf("Expected the resource that timed out queued for retry, got: %t %+v", ok, retry.retry)
+	}
+
+	retried := retryResult(&retry)
+	if retried.Attempts != 2 || !retried.Reset || len(retried.Resources) != 2 || retried.Resources[1].Resource != "ldap" {
+		t.Fatalf("Expected the outcome of earlier attempts on the retry result, got: %+v", retried)
+	}
+
+	_, ok = b.retryMsg(retry, &retried, err)
+	if ok {
+		t.Fatal("Expected no retry once the asset is out of attempts.")
+	}
+
+	execute := Msg{Asset: asset.Asset{Serial: "FOO", Action: asset.ActionExecute}}
+	result = retryResult(&execute)
+	_, ok = b.retryMsg(execute, &result, errors.New("powercycle: i/o timeout"))
+	if ok {
+		t.Fatal("Expected execute commands not retried unless the login failed.")
+	}
+
+	_, ok = b.retryMsg(execute, &result, fmt.Errorf("%w: All attempts to login failed.", ErrLoginFailed))
+	if !ok {
+		t.Fatal("Expected execute commands retried if the login failed.")
+	}
+}
+
+// Test a certs asset retried is reported once, with the outcome of the last attempt.
+func TestRetryCertsReport(t *testing.T) {
+	var buf bytes.Buffer
+	b := &Butler{
+		Config: &config.Params{
+			Retry:          &config.Retry{Attempts: 2, Backoff: time.Minute, MaxBackoff: time.Minute},
+			IgnoreLocation: true,
+			CertsFormat:    "csv",
+		},
+		Log:    logrus.New(),
+		Output: &buf,
+	}
+
+	// Without credentials to try, the login fails.
+	msg := Msg{Asset: asset.Asset{Serial: "FOO", IPAddresses: []string{"127.0.0.1"}, Action: asset.ActionCerts}}
+
+	retry := b.msgHandler(msg)
+	if retry == nil {
+		t.Fatal("Expected the certs asset queued for retry once the login failed.")
+	}
+
+	if buf.Len() != 0 {
+		t.Fatalf("Expected no report written before the last attempt, got: %s", buf.String())
+	}
+
+	retry = b.msgHandler(*retry)
+	if retry != nil {
+		t.Fatal("Expected no retry once the asset is out of attempts.")
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(records) != 1 || records[0][0] != "FOO" || records[0][len(records[0])-1] == "" {
+		t.Fatalf("Expected a single report of the login failure, got: %v", records)
+	}
+}
@@ -0,0 +1,132 @@
+package butler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/bmc-toolbox/bmcbutler/pkg/asset"
+	"github.com/bmc-toolbox/bmcbutler/pkg/audit"
+	"github.com/bmc-toolbox/bmcbutler/pkg/butler/configure"
+	"github.com/bmc-toolbox/bmcbutler/pkg/metrics"
+	"github.com/bmc-toolbox/bmcbutler/pkg/resource"
+	"github.com/bmc-toolbox/bmcbutler/pkg/tracing"
+	"github.com/bmc-toolbox/bmclib/devices"
+	"github.com/bmc-toolbox/bmclogin"
+)
+
+// setupAsset sets up the bmc connection,
+// gets any Asset setup templated data rendered,
+// applies the one time setup configuration using bmclib,
+// an error is returned if any resource failed to apply.
+func (b *Butler) setupAsset(config []byte, asset *asset.Asset, span *tracing.Span) (err error) {
+	component := "setupAsset"
+
+	if b.Config.DryRun {
+		b.Log.WithFields(logrus.Fields{
+			"component": component,
+		}).Info("Dry run, asset setup will be skipped.")
+		return nil
+	}
+
+	defer b.timeTrack(time.Now(), "setupAsset", asset)
+	defer metrics.MeasureRuntime([]string{"butler", "setup_runtime"}, time.Now())
+
+	b.Log.WithFields(logrus.Fields{
+		"component": component,
+		"Serial":    asset.Serial,
+		"IPAddress": asset.IPAddresses,
+	}).Debug("Connecting to asset...")
+
+	bmcConn := bmclogin.Params{
+		IpAddresses:     asset.IPAddresses,
+		Credentials:     b.credentials(asset),
+		CheckCredential: true,
+		Retries:         1,
+		StopChan:        b.StopChan,
+	}
+
+	client, loginInfo, err := login(span, bmcConn)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrLoginFailed, err)
+	}
+
+	asset.IPAddress = loginInfo.ActiveIpAddress
+
+	err = b.limitAsset(asset, client)
+	if err != nil {
+		return err
+	}
+
+	var applied *configure.Applied
+
+	switch clientType := client.(type) {
+	case devices.Bmc:
+		bmc := client.(devices.Bmc)
+		defer bmc.Close(context.TODO())
+
+		asset.Type = "server"
+		asset.HardwareType = bmc.HardwareType()
+		asset.Vendor = bmc.Vendor()
+
+		resourceInstance := resource.Resource{Log: b.Log, Asset: asset, Secrets: b.Secrets, Span: span}
+		renderedSetup, err := resourceInstance.LoadConfigResources(config)
+		if err != nil {
+			return err
+		}
+
+		if renderedSetup == nil {
+			return errors.New("No BMC setup configuration to be applied!")
+		}
+
+		s := configure.NewBmcSetup(bmc, asset, span, b.Config.Resources, renderedSetup, b.Config, b.StopChan, b.Log)
+		applied = s.Apply()
+	case devices.Cmc:
+		chassis := client.(devices.Cmc)
+		defer chassis.Close()
+
+		asset.Type = "chassis"
+		asset.HardwareType = chassis.HardwareType()
+		asset.Vendor = chassis.Vendor()
+
+		resourceInstance := resource.Resource{Log: b.Log, Asset: asset, Secrets: b.Secrets, Span: span}
+		renderedSetup, err := resourceInstance.LoadConfigResources(config)
+		if err != nil {
+			return err
+		}
+
+		if renderedSetup == nil || renderedSetup.SetupChassis == nil {
+			return errors.New("No CMC setupChassis configuration to be applied!")
+		}
+
+		s := configure.NewCmcSetup(
+			chassis,
+			asset,
+			b.Config.Resources,
+			renderedSetup.SetupChassis,
+			b.Config,
+			b.StopChan,
+			b.Log,
+		)
+		applied = s.Apply()
+	default:
+		b.Log.WithFields(logrus.Fields{
+			"component": component,
+			"Type":      fmt.Sprintf("%s", clientType),
+		}).Warn("Unknown device type.")
+		return fmt.Errorf("Unknown device type \"%s\"!", clientType)
+	}
+
+	b.recordApplied(audit.Hash(config), asset, applied)
+
+	failed := applied.Failed()
+	if len(failed) > 0 {
+		return fmt.Errorf("setup resources failed to apply: %s", strings.Join(failed, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,62 @@
+package butler
+
+import (
+	"github.com/bmc-toolbox/bmcbutler/pkg/tracing"
+	"github.com/bmc-toolbox/bmclogin"
+)
+
+// StartRun starts the root span of a run, the spans of assets received after are its children,
+// the span is returned to be ended once the run is done.
+func (b *Butler) StartRun(name string) *tracing.Span {
+	span := b.Tracer.Start(nil, name)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.runSpan = span
+	return span
+}
+
+// startAssetSpan starts the span of the action on the msg asset.
+func (b *Butler) startAssetSpan(msg *Msg) {
+	b.mu.Lock()
+	parent := b.runSpan
+	b.mu.Unlock()
+
+	span := b.Tracer.Start(parent, msg.Asset.Action)
+	span.SetAttribute("asset.serial", msg.Asset.Serial)
+	span.SetAttribute("asset.location", msg.Asset.Location)
+	if msg.JobID != "" {
+		span.SetAttribute("job.id", msg.JobID)
+	}
+	if msg.retry != nil {
+		span.SetAttribute("attempt", msg.retry.attempts+1)
+	}
+
+	msg.span = span
+}
+
+// endAssetSpan ends the span of the action on the msg asset, with the asset attributes known once logged in,
+// and the outcome.
+func endAssetSpan(msg *Msg, outcome string, err error) {
+	span, asset := msg.span, &msg.Asset
+	span.SetAttribute("asset.ip_address", asset.IPAddress)
+	span.SetAttribute("asset.vendor", asset.Vendor)
+	span.SetAttribute("asset.hardware_type", asset.HardwareType)
+	span.SetAttribute("asset.type", asset.Type)
+	span.SetAttribute("outcome", outcome)
+	span.End(err)
+}
+
+// login logs in to the asset BMC, traced as a child of the span of the action on the asset.
+func login(assetSpan *tracing.Span, params bmclogin.Params) (interface{}, bmclogin.LoginInfo, error) {
+	span := assetSpan.Child("bmclogin")
+
+	client, loginInfo, err := params.Login()
+
+	span.SetAttribute("ip_address", loginInfo.ActiveIpAddress)
+	span.SetAttribute("failed_credentials", len(loginInfo.FailedCredentials))
+	span.End(err)
+
+	return client, loginInfo, err
+}
@@ -0,0 +1,230 @@
+package checkpoint
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/bmc-toolbox/bmcbutler/pkg/butler"
+)
+
+// Asset statuses recorded in the checkpoint.
+const (
+	StatusPending = "pending" // Dispatched to butlers, or skipped since the run was interrupted.
+	StatusDone    = "done"    // Succeeded, or skipped since the asset is not managed.
+	StatusFailed  = "failed"
+)
+
+// The minimum interval between checkpoint file writes while the run progresses.
+const saveInterval = 2 * time.Second
+
+// Entry is the status recorded for an asset.
+type Entry struct {
+	Status  string    `json:"status"`
+	Error   string    `json:"error,omitempty"`
+	Updated time.Time `json:"updated"`
+}
+
+// Run declares the action a run carried out, and the filters it selected assets with,
+// a checkpoint is resumed only by a run declaring the same.
+type Run struct {
+	Action    string   `json:"action"`
+	Command   string   `json:"command,omitempty"`   // The command executed, for execute runs.
+	Resources []string `json:"resources,omitempty"` // The resources applied if limited with --resources, for configure runs.
+	All       bool     `json:"all,omitempty"`
+	Servers   bool     `json:"servers,omitempty"`
+	Chassis   bool     `json:"chassis,omitempty"`
+	Serials   string   `json:"serials,omitempty"`
+	IPs       string   `json:"ips,omitempty"`
+	Locations []string `json:"locations,omitempty"`
+}
+
+// mismatch returns what differs between the run and the one given, empty if they are the same.
+func (r Run) mismatch(other Run) string {
+	fields := []struct {
+		name        string
+		this, other string
+	}{
+		{"action", r.Action, other.Action},
+		{"command", r.Command, other.Command},
+		{"resources", strings.Join(r.Resources, ","), strings.Join(other.Resources, ",")},
+		{"all", fmt.Sprint(r.All), fmt.Sprint(other.All)},
+		{"servers", fmt.Sprint(r.Servers), fmt.Sprint(other.Servers)},
+		{"chassis", fmt.Sprint(r.Chassis), fmt.Sprint(other.Chassis)},
+		{"serials", r.Serials, other.Serials},
+		{"ips", r.IPs, other.IPs},
+		{"locations", strings.Join(r.Locations, ","), strings.Join(other.Locations, ",")},
+	}
+
+	for _, f := range fields {
+		if f.this != f.other {
+			return fmt.Sprintf("%s %q, expected %q", f.name, f.this, f.other)
+		}
+	}
+
+	return ""
+}
+
+// Checkpoint records the status of assets a run dispatched, keyed by serial or IP address,
+// it is persisted to File as the run progresses so an aborted run can be resumed.
+type Checkpoint struct {
+	Run
+	File        string           `json:"-"`
+	Assets      map[string]Entry `json:"assets"`
+	RetryFailed bool             `json:"-"` // Failed assets are dispatched again on resume.
+	Log         *logrus.Logger   `json:"-"`
+	mu          sync.Mutex
+	saved       time.Time
+}
+
+// New returns an empty checkpoint for the run, persisted to the file.
+func New(file string, run Run, log *logrus.Logger) *Checkpoint {
+	return &Checkpoint{Run: run, File: file, Assets: make(map[string]Entry), Log: log}
+}
+
+// Load returns the checkpoint read from the file, to resume the given run,
+// an error is returned if the checkpoint was written by a run of another action, command or filters.
+func Load(file string, run Run, log *logrus.Logger) (*Checkpoint, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	c := New(file, Run{}, log)
+	err = json.Unmarshal(data, c)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse checkpoint file %s: %s", file, err)
+	}
+
+	if mismatch := c.Run.mismatch(run); mismatch != "" {
+		return nil, fmt.Errorf("checkpoint file %s was written by another run, %s", file, mismatch)
+	}
+
+	if c.Assets == nil {
+		c.Assets = make(map[string]Entry)
+	}
+
+	return c, nil
+}
+
+// Skip returns true if the asset is not to be dispatched again,
+// since it's done, or it failed and failed assets are not retried.
+func (c *Checkpoint) Skip(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, exists := c.Assets[key]
+	if !exists {
+		return false
+	}
+
+	switch entry.Status {
+	case StatusDone:
+		return true
+	case StatusFailed:
+		return !c.RetryFailed
+	default:
+		return false
+	}
+}
+
+// Dispatched records the asset as pending.
+func (c *Checkpoint) Dispatched(key string) {
+	c.set(key, Entry{Status: StatusPending})
+}
+
+// Record records the status of the asset from the butler result,
+// the checkpoint is written to the file if it wasn't written recently.
+func (c *Checkpoint) Record(result butler.Result) {
+	entry := Entry{Error: result.Error}
+
+	// Assets skipped, or stopped, since the run was interrupted or the job cancelled are dispatched on resume,
+	// assets skipped since they're not managed are not.
+	switch {
+	case result.Success:
+		entry.Status = StatusDone
+	case result.Error == butler.ErrInterrupted.Error() || result.Error == butler.ErrCancelled.Error():
+		entry.Status = StatusPending
+	case result.Skipped:
+		entry.Status = StatusDone
+	default:
+		entry.Status = StatusFailed
+	}
+
+	c.set(result.Asset.Key(), entry)
+
+	c.mu.Lock()
+	due := time.Since(c.saved) >= saveInterval
+	c.mu.Unlock()
+
+	if !due {
+		return
+	}
+
+	err := c.Save()
+	if err != nil && c.Log != nil {
+		c.Log.Warn("Unable to write checkpoint file (", c.File, "): ", err)
+	}
+}
+
+// Counts returns the number of assets recorded with each status.
+func (c *Checkpoint) Counts() map[string]int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	counts := make(map[string]int)
+	for _, entry := range c.Assets {
+		counts[entry.Status]++
+	}
+
+	return counts
+}
+
+// Save writes the checkpoint to the file.
+func (c *Checkpoint) Save() error {
+	if c.File == "" {
+		return errors.New("no checkpoint file declared")
+	}
+
+	c.mu.Lock()
+	data, err := json.MarshalIndent(c, "", "  ")
+	c.saved = time.Now()
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	// Written to a temporary file first, so an interrupted write doesn't leave a truncated checkpoint.
+	tmp, err := ioutil.TempFile(filepath.Dir(c.File), filepath.Base(c.File)+".tmp")
+	if err != nil {
+		return err
+	}
+
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), c.File)
+}
+
+func (c *Checkpoint) set(key string, entry Entry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry.Updated = time.Now()
+	c.Assets[key] = entry
+}
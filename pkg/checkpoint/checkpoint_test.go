@@ -0,0 +1,74 @@
+package checkpoint
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bmc-toolbox/bmcbutler/pkg/asset"
+	"github.com/bmc-toolbox/bmcbutler/pkg/butler"
+)
+
+// Test a resumed run skips assets done, and dispatches failed assets again only if asked to,
+// checkpoints of runs of another action, command or filters are refused.
+func TestResume(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "checkpoint.json")
+
+	run := Run{Action: asset.ActionExecute, Command: "bmc-reset", Servers: true, Locations: []string{"ams4"}}
+	c := New(file, run, nil)
+	for _, key := range []string{"FOO", "BAR", "BAZ", "QUX", "192.0.2.1"} {
+		c.Dispatched(key)
+	}
+
+	c.Record(butler.Result{Asset: asset.Asset{Serial: "FOO"}, Success: true})
+	c.Record(butler.Result{Asset: asset.Asset{Serial: "BAR"}, Error: "login failed"})
+	c.Record(butler.Result{Asset: asset.Asset{Serial: "BAZ"}, Skipped: true, Error: butler.ErrInterrupted.Error()})
+	c.Record(butler.Result{Asset: asset.Asset{IPAddresses: []string{"192.0.2.1"}}, Skipped: true, Error: butler.ErrAssetLocationUnmanaged.Error()})
+
+	err = c.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	others := []Run{
+		{Action: asset.ActionConfigure, Servers: true, Locations: []string{"ams4"}},
+		{Action: asset.ActionExecute, Command: "powercycle", Servers: true, Locations: []string{"ams4"}},
+		{Action: asset.ActionExecute, Command: "bmc-reset", All: true, Locations: []string{"ams4"}},
+		{Action: asset.ActionExecute, Command: "bmc-reset", Servers: true},
+	}
+	for _, other := range others {
+		_, err = Load(file, other, nil)
+		if err == nil {
+			t.Fatalf("Expected an error resuming the checkpoint with another run: %+v", other)
+		}
+	}
+
+	resumed, err := Load(file, run, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]bool{"FOO": true, "BAR": true, "BAZ": false, "QUX": false, "192.0.2.1": true, "QUUX": false}
+	for key, skip := range expected {
+		if resumed.Skip(key) != skip {
+			t.Errorf("Expected %s skipped: %t", key, skip)
+		}
+	}
+
+	resumed.RetryFailed = true
+	if resumed.Skip("BAR") {
+		t.Error("Expected failed assets dispatched again with RetryFailed.")
+	}
+
+	counts := resumed.Counts()
+	if counts[StatusDone] != 2 || counts[StatusFailed] != 1 || counts[StatusPending] != 2 {
+		t.Fatalf("Unexpected checkpoint counts: %v", counts)
+	}
+}
@@ -20,88 +20,194 @@ import (
 
 // Params struct holds all bmcbutler configuration parameters
 type Params struct {
-	ButlersToSpawn   int                 `mapstructure:"butlersToSpawn"`
-	Credentials      []map[string]string `mapstructure:"credentials"`
-	CertSigner       *CertSigner         `mapstructure:"cert_signer"`
-	Inventory        *Inventory          `mapstructure:"inventory"`
-	Locations        []string            `mapstructure:"locations"`
-	Metrics          *Metrics            `mapstructure:"metrics"`
-	FilterParams     *FilterParams
-	CfgFile          string
-	Configure        bool // The user invoked the configure action?
-	DryRun           bool // If true, don't carry out any actions. Just log.
-	Execute          bool // The user invoked the execute action?
-	IgnoreLocation   bool
-	Resources        []string
-	Version          string
-	Debug            bool
-	Trace            bool
-	SecretsFromVault bool   `mapstructure:"secretsFromVault"`
-	Vault            *Vault `mapstructure:"vault"`
+	Audit            *Audit              `mapstructure:"audit" yaml:"audit"`
+	BmcCfgDir        string              `mapstructure:"bmcCfgDir" yaml:"bmcCfgDir"`
+	ButlersToSpawn   int                 `mapstructure:"butlersToSpawn" yaml:"butlersToSpawn"`
+	Credentials      []map[string]string `mapstructure:"credentials" yaml:"credentials"`
+	CertSigner       *CertSigner         `mapstructure:"cert_signer" yaml:"cert_signer"`
+	Concurrency      *Concurrency        `mapstructure:"concurrency" yaml:"concurrency"`
+	Firmware         *Firmware           `mapstructure:"firmware" yaml:"firmware"`
+	Inventory        *Inventory          `mapstructure:"inventory" yaml:"inventory"`
+	Locations        []string            `mapstructure:"locations" yaml:"locations"`
+	Maintenance      *Maintenance        `mapstructure:"maintenance" yaml:"maintenance"`
+	Metrics          *Metrics            `mapstructure:"metrics" yaml:"metrics"`
+	Notifications    []*Notification     `mapstructure:"notifications" yaml:"notifications"`
+	Retry            *Retry              `mapstructure:"retry" yaml:"retry"`
+	Tracing          *Tracing            `mapstructure:"tracing" yaml:"tracing"`
+	FilterParams     *FilterParams       `yaml:"-"`
+	CfgFile          string              `yaml:"-"`
+	Configure        bool                `yaml:"-"` // The user invoked the configure action?
+	DryRun           bool                `yaml:"-"` // If true, don't carry out any actions. Just log.
+	Execute          bool                `yaml:"-"` // The user invoked the execute action?
+	Setup            bool                `yaml:"-"` // The user invoked the setup action?
+	IgnoreLocation   bool                `yaml:"-"`
+	Resources        []string            `yaml:"-"`
+	CollectDir       string              `yaml:"-"` // If set, collected facts are written here, one file per asset.
+	CertsFormat      string              `yaml:"-"` // The cert report format, json or csv.
+	CertsRenew       bool                `yaml:"-"` // If set, certs failing validation are renewed.
+	CertsRenewBefore time.Duration       `yaml:"-"` // If set, overrides the renewBeforeExpiry of the cert configuration.
+	FirmwareFormat   string              `yaml:"-"` // The firmware report format, json or csv.
+	FirmwareListAll  bool                `yaml:"-"` // If set, compliant assets are listed in the firmware report too.
+	RotateUser       string              `yaml:"-"` // The BMC user account to rotate the password of.
+	RotateKey        string              `yaml:"-"` // The vault key the rotated user credential was loaded from.
+	RotatePerAsset   bool                `yaml:"-"` // If set, a unique password is generated for each asset.
+	RotateLength     int                 `yaml:"-"` // The length of generated passwords.
+	Version          string              `yaml:"-"`
+	Debug            bool                `yaml:"-"`
+	Trace            bool                `yaml:"-"`
+	SecretsFromVault bool                `mapstructure:"secretsFromVault" yaml:"secretsFromVault"`
+	Vault            *Vault              `mapstructure:"vault" yaml:"vault"`
 }
 
 // Inventory struct holds inventory configuration parameters.
 type Inventory struct {
-	Source string // dora, csv, enc
-	Enc    *Enc   `mapstructure:"enc"`
-	Dora   *Dora  `mapstructure:"dora"`
-	Csv    *Csv   `mapstrucure:"csv"`
+	Source string `yaml:"-"` // dora, csv, enc
+	Enc    *Enc   `mapstructure:"enc" yaml:"enc"`
+	Dora   *Dora  `mapstructure:"dora" yaml:"dora"`
+	Csv    *Csv   `mapstructure:"csv" yaml:"csv"`
 }
 
 // Enc declares config for a ENC as an inventory source
 type Enc struct {
-	Bin          string   `mapstructure:"bin"`
-	BMCNicPrefix []string `mapstructure:"bmcNicPrefix"`
+	Bin          string   `mapstructure:"bin" yaml:"bin"`
+	BMCNicPrefix []string `mapstructure:"bmcNicPrefix" yaml:"bmcNicPrefix"`
 }
 
 // Csv declares config for a CSV file as an inventory source
 type Csv struct {
-	File string `mapstructure:"file"`
+	File string `mapstructure:"file" yaml:"file"`
 }
 
 // Dora declares config for Dora as a inventory source.
 type Dora struct {
-	URL string `mapstructure:"url"`
+	URL string `mapstructure:"url" yaml:"url"`
 }
 
 // Metrics struct holds metrics emitter configuration parameters.
 type Metrics struct {
-	Client   string    // The metrics client.
-	Graphite *Graphite `mapstructure:"graphite"`
+	Client     string      `yaml:"-"` // The metrics client.
+	Graphite   *Graphite   `mapstructure:"graphite" yaml:"graphite"`
+	Prometheus *Prometheus `mapstructure:"prometheus" yaml:"prometheus"`
 }
 
 // Graphite struct holds attributes for the Graphite metrics emitter
 type Graphite struct {
-	Host          string        `mapstructure:"host"`
-	Port          int           `mapstructure:"port"`
-	Prefix        string        `mapstructure:"prefix"`
-	FlushInterval time.Duration `mapstructure:"flushInterval"`
+	Host          string        `mapstructure:"host" yaml:"host"`
+	Port          int           `mapstructure:"port" yaml:"port"`
+	Prefix        string        `mapstructure:"prefix" yaml:"prefix"`
+	FlushInterval time.Duration `mapstructure:"flushInterval" yaml:"flushInterval"`
+}
+
+// Prometheus struct holds attributes for the Prometheus metrics exposition,
+// metrics are served on listen, or pushed to a Pushgateway when the run is done.
+type Prometheus struct {
+	Listen  string `mapstructure:"listen" yaml:"listen"`   // e.g :9110, metrics are served on /metrics.
+	PushURL string `mapstructure:"pushUrl" yaml:"pushUrl"` // e.g http://pushgateway:9091
+	Job     string `mapstructure:"job" yaml:"job"`         // The Pushgateway job label, defaults to bmcbutler.
+	Prefix  string `mapstructure:"prefix" yaml:"prefix"`   // The metric names prefix, defaults to bmcbutler.
 }
 
 // CertSigner struct
 type CertSigner struct {
-	Client      string
-	FakeSigner  *FakeSigner  `mapstructure:"fake"`
-	LemurSigner *LemurSigner `mapstructure:"lemur"`
+	Client      string       `yaml:"-"`
+	FakeSigner  *FakeSigner  `mapstructure:"fake" yaml:"fake"`
+	LemurSigner *LemurSigner `mapstructure:"lemur" yaml:"lemur"`
 }
 
 // FakeSigner struct holds SSL/TLS cert signing attributes.
 type FakeSigner struct {
-	Client     string   `mapstructure:"client"`
-	Passphrase string   `mapstructure:"passphrase"`
-	Bin        string   `mapstructure:"bin"`
-	Args       []string `mapstructure:"args"`
+	Client     string   `mapstructure:"client" yaml:"client"`
+	Passphrase string   `mapstructure:"passphrase" yaml:"passphrase"`
+	Bin        string   `mapstructure:"bin" yaml:"bin"`
+	Args       []string `mapstructure:"args" yaml:"args"`
 }
 
 // LemurSigner struct holds SSL/TLS cert signing attributes.
 type LemurSigner struct {
-	Client        string `mapstructure:"client"`
-	Authority     string `mapstructure:"authority"`
-	ValidityYears string `mapstructure:"validity_years"`
-	Owner         string `mapstructure:"owner_email"`
-	Key           string `mapstructure:"auth_token"`
-	Bin           string `mapstructure:"bin"`
-	Endpoint      string `mapstructure:"endpoint"`
+	Client        string `mapstructure:"client" yaml:"client"`
+	Authority     string `mapstructure:"authority" yaml:"authority"`
+	ValidityYears string `mapstructure:"validity_years" yaml:"validity_years"`
+	Owner         string `mapstructure:"owner_email" yaml:"owner_email"`
+	Key           string `mapstructure:"auth_token" yaml:"auth_token"`
+	Bin           string `mapstructure:"bin" yaml:"bin"`
+	Endpoint      string `mapstructure:"endpoint" yaml:"endpoint"`
+}
+
+// Firmware declares the firmware repository and the firmware versions assets are expected to run.
+type Firmware struct {
+	Repository string           `mapstructure:"repository" yaml:"repository"` // Base URL BMCs fetch firmware files from.
+	Catalog    []*FirmwareEntry `mapstructure:"catalog" yaml:"catalog"`
+}
+
+// FirmwareEntry declares the firmware version for assets matching the vendor, hardwareType and model declared.
+type FirmwareEntry struct {
+	Vendor       string `mapstructure:"vendor" yaml:"vendor"`
+	HardwareType string `mapstructure:"hardwareType" yaml:"hardwareType"`
+	Model        string `mapstructure:"model" yaml:"model"`
+	Version      string `mapstructure:"version" yaml:"version"`
+	File         string `mapstructure:"file" yaml:"file"` // Path to the firmware file, relative to the repository.
+}
+
+// Audit declares where the journal of changes applied to BMCs is written.
+type Audit struct {
+	File   string `mapstructure:"file" yaml:"file"`     // Entries are appended to this file as JSON lines.
+	Syslog string `mapstructure:"syslog" yaml:"syslog"` // If declared, entries are sent to syslog too, local or udp://host:514, tcp://host:514
+	URL    string `mapstructure:"url" yaml:"url"`       // If declared, entries are POSTed as JSON to this URL too.
+}
+
+// Concurrency declares the maximum number of assets butlers work on at a time,
+// by location, vendor, hardware type, and per chassis.
+type Concurrency struct {
+	Locations     map[string]int `mapstructure:"locations" yaml:"locations"`
+	Vendors       map[string]int `mapstructure:"vendors" yaml:"vendors"`
+	HardwareTypes map[string]int `mapstructure:"hardwareTypes" yaml:"hardwareTypes"`
+	Chassis       int            `mapstructure:"chassis" yaml:"chassis"` // Blades of a chassis, the chassis itself counts as one.
+}
+
+// Notification declares a webhook called on run events, with the JSON payload rendered from a template.
+type Notification struct {
+	Name            string            `mapstructure:"name" yaml:"name"`
+	URL             string            `mapstructure:"url" yaml:"url"`
+	Events          []string          `mapstructure:"events" yaml:"events"`   // failure, reset, cert_renewed, summary
+	Payload         string            `mapstructure:"payload" yaml:"payload"` // A text/template, defaults to the event as JSON.
+	Headers         map[string]string `mapstructure:"headers" yaml:"headers"`
+	Timeout         time.Duration     `mapstructure:"timeout" yaml:"timeout"`                 // Of each request, defaults to 10s.
+	Retries         *int              `mapstructure:"retries" yaml:"retries"`                 // Requests that failed are retried with backoff, defaults to 3, 0 disables retries.
+	MinFailures     int               `mapstructure:"minFailures" yaml:"minFailures"`         // The summary is sent once this number of assets failed.
+	MinFailureRatio float64           `mapstructure:"minFailureRatio" yaml:"minFailureRatio"` // The summary is sent once the ratio of failed assets is above this.
+}
+
+// NotificationEvents are the run events notifications may be sent for.
+var NotificationEvents = []string{"failure", "reset", "cert_renewed", "summary"}
+
+// defaultNotificationRetries are the retries of notification requests, unless declared.
+const defaultNotificationRetries = 3
+
+// RequestRetries returns the retries of notification requests that failed,
+// an explicit 0 is honoured, the default is used if retries is not declared.
+func (n *Notification) RequestRetries() int {
+	if n.Retries == nil {
+		return defaultNotificationRetries
+	}
+
+	return *n.Retries
+}
+
+// Tracing declares where trace spans of runs, assets and the operations carried out on them are exported,
+// to an OTLP/HTTP collector endpoint, or written to a file.
+type Tracing struct {
+	Exporter    string            `yaml:"-"`                                      // The span exporter, otlp or file.
+	Endpoint    string            `mapstructure:"endpoint" yaml:"endpoint"`       // e.g http://otel-collector:4318, spans are POSTed to /v1/traces.
+	Headers     map[string]string `mapstructure:"headers" yaml:"headers"`         // Sent with OTLP requests.
+	File        string            `mapstructure:"file" yaml:"file"`               // Spans are appended to this file as OTLP JSON lines.
+	ServiceName string            `mapstructure:"serviceName" yaml:"serviceName"` // Defaults to bmcbutler.
+}
+
+// Retry declares how assets that failed with errors deemed transient are retried.
+type Retry struct {
+	Attempts   int           `mapstructure:"attempts" yaml:"attempts"`     // The attempts made on an asset, including the first one.
+	Backoff    time.Duration `mapstructure:"backoff" yaml:"backoff"`       // The delay before the first retry, doubled for each retry after.
+	MaxBackoff time.Duration `mapstructure:"maxBackoff" yaml:"maxBackoff"` // The maximum delay between retries.
 }
 
 // FilterParams struct holds various asset filter arguments that may be passed via cli args.
@@ -115,9 +221,10 @@ type FilterParams struct {
 
 // Vault struct declares vault config attributes
 type Vault struct {
-	TokenFromFile string `mapstructure:"tokenFromFile"`
-	TokenFromEnv  bool   `mapstructure:"tokenFromEnv"`
-	SecretsPath   string `mapstructure:"secretsPath"`
-	HostAddress   string `mapstructure:"hostAddress"`
-	Token         string `mapstructure:"token"`
+	TokenFromFile string `mapstructure:"tokenFromFile" yaml:"tokenFromFile"`
+	TokenFromEnv  bool   `mapstructure:"tokenFromEnv" yaml:"tokenFromEnv"`
+	SecretsPath   string `mapstructure:"secretsPath" yaml:"secretsPath"`
+	RotatePath    string `mapstructure:"rotatePath" yaml:"rotatePath"` // Rotated credentials are written here, defaults to secretsPath.
+	HostAddress   string `mapstructure:"hostAddress" yaml:"hostAddress"`
+	Token         string `mapstructure:"token" yaml:"token"`
 }
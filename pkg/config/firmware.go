@@ -0,0 +1,45 @@
+package config
+
+import "strings"
+
+// Lookup returns the catalog entry for the asset, nil if none matches.
+// All attributes declared in an entry are required to match,
+// the most specific entry wins - model over hardwareType over vendor.
+func (f *Firmware) Lookup(vendor, hardwareType, model string) *FirmwareEntry {
+	if f == nil {
+		return nil
+	}
+
+	var match *FirmwareEntry
+	var matchScore int
+
+	for _, e := range f.Catalog {
+		score := 0
+
+		for _, attr := range []struct {
+			declared, value string
+			weight          int
+		}{
+			{e.Vendor, vendor, 1},
+			{e.HardwareType, hardwareType, 2},
+			{e.Model, model, 4},
+		} {
+			if attr.declared == "" {
+				continue
+			}
+
+			if !strings.EqualFold(attr.declared, attr.value) {
+				score = -1
+				break
+			}
+
+			score += attr.weight
+		}
+
+		if score > matchScore {
+			match, matchScore = e, score
+		}
+	}
+
+	return match
+}
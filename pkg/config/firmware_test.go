@@ -0,0 +1,42 @@
+package config
+
+import "testing"
+
+// Test the most specific catalog entry matching all declared attributes is returned.
+func TestFirmwareLookup(t *testing.T) {
+	firmware := &Firmware{
+		Catalog: []*FirmwareEntry{
+			{Vendor: "dell", Version: "1"},
+			{Vendor: "dell", HardwareType: "idrac9", Version: "2"},
+			{HardwareType: "idrac9", Model: "PowerEdge R640", Version: "3"},
+			{Vendor: "hp", HardwareType: "ilo5", Version: "4"},
+		},
+	}
+
+	cases := []struct {
+		vendor, hardwareType, model string
+		version                     string
+	}{
+		{"dell", "idrac8", "PowerEdge R630", "1"},
+		{"dell", "idrac9", "PowerEdge R740", "2"},
+		{"dell", "idrac9", "poweredge r640", "3"},
+		{"hp", "ilo5", "", "4"},
+		{"hp", "ilo4", "", ""},
+	}
+
+	for _, c := range cases {
+		var version string
+		if e := firmware.Lookup(c.vendor, c.hardwareType, c.model); e != nil {
+			version = e.Version
+		}
+
+		if version != c.version {
+			t.Fatalf("%s/%s/%s: expected version %q, got %q", c.vendor, c.hardwareType, c.model, c.version, version)
+		}
+	}
+
+	var undeclared *Firmware
+	if undeclared.Lookup("dell", "idrac9", "") != nil {
+		t.Fatal("Expected no entry without a firmware section.")
+	}
+}
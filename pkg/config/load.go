@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"io/ioutil"
 	"log"
+	"net/url"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -23,19 +25,29 @@ func (p *Params) Load(cfgFile string) {
 		log.Fatalf("failed to unmarshal config: %s", err.Error())
 	}
 
-	validators := []func() error{
+	for _, v := range p.validators() {
+		err := v()
+		if err != nil {
+			log.Fatalf("[Error] %s", err.Error())
+		}
+	}
+}
+
+// validators returns the configuration validators, in the order they're run by Load and Validate.
+func (p *Params) validators() []func() error {
+	return []func() error{
 		p.validateVaultCfg,
 		p.validateMetricsCfg,
 		p.validateInventoryCfg,
 		p.defaults,
 		p.validateCertSignerCfg,
-	}
-
-	for _, v := range validators {
-		err := v()
-		if err != nil {
-			log.Fatalf("[Error] %s", err.Error())
-		}
+		p.validateFirmwareCfg,
+		p.validateRetryCfg,
+		p.validateConcurrencyCfg,
+		p.validateMaintenanceCfg,
+		p.validateAuditCfg,
+		p.validateNotificationsCfg,
+		p.validateTracingCfg,
 	}
 }
 
@@ -69,8 +81,7 @@ func (p *Params) defaults() error {
 	}
 
 	if p.Credentials == nil {
-		log.Println("[Error] Expected BMC credentials to be declared in configuration")
-		os.Exit(1)
+		return fmt.Errorf("expected BMC credentials to be declared in configuration")
 	}
 
 	return nil
@@ -109,9 +120,15 @@ func (p *Params) validateInventoryCfg() error {
 // metrics config
 func (p *Params) validateMetricsCfg() error {
 	if p.Metrics != nil {
-		if p.Metrics.Graphite != nil {
+		switch {
+		case p.Metrics.Graphite != nil && p.Metrics.Prometheus != nil:
+			return fmt.Errorf("expected either graphite or prometheus metrics declared, not both")
+		case p.Metrics.Graphite != nil:
 			p.Metrics.Client = "graphite"
-		} else {
+		case p.Metrics.Prometheus != nil:
+			p.Metrics.Client = "prometheus"
+			return p.validatePrometheusCfg()
+		default:
 			log.Println("[WARN] Invalid metrics client declared in config.")
 		}
 	}
@@ -119,6 +136,220 @@ func (p *Params) validateMetricsCfg() error {
 	return nil
 }
 
+func (p *Params) validatePrometheusCfg() error {
+	prometheus := p.Metrics.Prometheus
+	if prometheus.Listen == "" && prometheus.PushURL == "" {
+		return fmt.Errorf("prometheus metrics expect listen and/or pushUrl to be declared")
+	}
+
+	if prometheus.PushURL != "" {
+		u, err := url.Parse(prometheus.PushURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("prometheus pushUrl is expected to be a http(s) URL, got %q", prometheus.PushURL)
+		}
+	}
+
+	if prometheus.Job == "" {
+		prometheus.Job = "bmcbutler"
+	}
+
+	if prometheus.Prefix == "" {
+		prometheus.Prefix = "bmcbutler"
+	}
+
+	return nil
+}
+
+// firmware config
+func (p *Params) validateFirmwareCfg() error {
+	if p.Firmware == nil {
+		return nil
+	}
+
+	if len(p.Firmware.Catalog) > 0 {
+		u, err := url.Parse(p.Firmware.Repository)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("firmware catalog declared, expected a valid firmware repository URL, got: %q", p.Firmware.Repository)
+		}
+	}
+
+	for i, e := range p.Firmware.Catalog {
+		if e.Vendor == "" && e.HardwareType == "" && e.Model == "" {
+			return fmt.Errorf("firmware catalog entry %d expects one or more of vendor, hardwareType, model", i+1)
+		}
+
+		if e.Version == "" || e.File == "" {
+			return fmt.Errorf("firmware catalog entry %d expects a version and file", i+1)
+		}
+	}
+
+	return nil
+}
+
+// retry config
+func (p *Params) validateRetryCfg() error {
+	if p.Retry == nil {
+		return nil
+	}
+
+	if p.Retry.Attempts < 0 || p.Retry.Backoff < 0 || p.Retry.MaxBackoff < 0 {
+		return fmt.Errorf("retry attempts, backoff and maxBackoff are expected to be positive")
+	}
+
+	if p.Retry.Backoff == 0 {
+		p.Retry.Backoff = time.Minute
+	}
+
+	if p.Retry.MaxBackoff == 0 {
+		p.Retry.MaxBackoff = 10 * time.Minute
+	}
+
+	if p.Retry.MaxBackoff < p.Retry.Backoff {
+		return fmt.Errorf("retry maxBackoff %s is expected to be no less than backoff %s", p.Retry.MaxBackoff, p.Retry.Backoff)
+	}
+
+	return nil
+}
+
+// concurrency config
+func (p *Params) validateConcurrencyCfg() error {
+	if p.Concurrency == nil {
+		return nil
+	}
+
+	for name, limits := range map[string]map[string]int{
+		"locations":     p.Concurrency.Locations,
+		"vendors":       p.Concurrency.Vendors,
+		"hardwareTypes": p.Concurrency.HardwareTypes,
+	} {
+		for k, limit := range limits {
+			if limit < 1 {
+				return fmt.Errorf("concurrency %s limit for %q is expected to be 1 or more, got %d", name, k, limit)
+			}
+		}
+	}
+
+	if p.Concurrency.Chassis < 0 {
+		return fmt.Errorf("concurrency chassis limit is expected to be positive, got %d", p.Concurrency.Chassis)
+	}
+
+	return nil
+}
+
+// maintenance window config
+func (p *Params) validateMaintenanceCfg() error {
+	if p.Maintenance == nil {
+		return nil
+	}
+
+	for location, w := range p.Maintenance.Windows {
+		if w == nil || len(w.Ranges) == 0 {
+			return fmt.Errorf("maintenance window for %q is expected to declare ranges", location)
+		}
+
+		err := w.parse()
+		if err != nil {
+			return fmt.Errorf("maintenance window for %q: %s", location, err)
+		}
+	}
+
+	return nil
+}
+
+// audit journal config
+func (p *Params) validateAuditCfg() error {
+	if p.Audit == nil {
+		return nil
+	}
+
+	if p.Audit.File == "" {
+		return fmt.Errorf("audit configuration expects the journal file to be declared")
+	}
+
+	if p.Audit.Syslog != "" && p.Audit.Syslog != "local" {
+		u, err := url.Parse(p.Audit.Syslog)
+		if err != nil || (u.Scheme != "udp" && u.Scheme != "tcp") || u.Host == "" {
+			return fmt.Errorf("audit syslog is expected to be local, or udp://host:port, tcp://host:port, got %q", p.Audit.Syslog)
+		}
+	}
+
+	if p.Audit.URL != "" {
+		u, err := url.Parse(p.Audit.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("audit url is expected to be a http(s) URL, got %q", p.Audit.URL)
+		}
+	}
+
+	return nil
+}
+
+// tracing config
+func (p *Params) validateTracingCfg() error {
+	if p.Tracing == nil {
+		return nil
+	}
+
+	switch {
+	case p.Tracing.Endpoint != "" && p.Tracing.File != "":
+		return fmt.Errorf("expected either a tracing endpoint or file declared, not both")
+	case p.Tracing.Endpoint != "":
+		u, err := url.Parse(p.Tracing.Endpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("tracing endpoint is expected to be a http(s) URL, got %q", p.Tracing.Endpoint)
+		}
+		p.Tracing.Exporter = "otlp"
+	case p.Tracing.File != "":
+		p.Tracing.Exporter = "file"
+	default:
+		return fmt.Errorf("tracing configuration expects an endpoint or file to be declared")
+	}
+
+	if p.Tracing.ServiceName == "" {
+		p.Tracing.ServiceName = "bmcbutler"
+	}
+
+	return nil
+}
+
+// notifications config
+func (p *Params) validateNotificationsCfg() error {
+	for i, n := range p.Notifications {
+		if n.Name == "" {
+			n.Name = fmt.Sprintf("notification %d", i+1)
+		}
+
+		u, err := url.Parse(n.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%s url is expected to be a http(s) URL, got %q", n.Name, n.URL)
+		}
+
+		if len(n.Events) == 0 {
+			return fmt.Errorf("%s is expected to declare events, any of: %s", n.Name, strings.Join(NotificationEvents, ", "))
+		}
+
+		for _, e := range n.Events {
+			var known bool
+			for _, k := range NotificationEvents {
+				known = known || e == k
+			}
+
+			if !known {
+				return fmt.Errorf("%s declares unknown event %q, expected any of: %s", n.Name, e, strings.Join(NotificationEvents, ", "))
+			}
+		}
+
+		if n.Timeout < 0 || n.RequestRetries() < 0 || n.MinFailures < 0 || n.MinFailureRatio < 0 || n.MinFailureRatio > 1 {
+			return fmt.Errorf("%s timeout, retries and minFailures are expected to be positive, minFailureRatio between 0 and 1", n.Name)
+		}
+
+		if n.Timeout == 0 {
+			n.Timeout = 10 * time.Second
+		}
+	}
+
+	return nil
+}
+
 // vault config
 func (p *Params) validateVaultCfg() error {
 	if !p.SecretsFromVault {
@@ -137,6 +368,10 @@ func (p *Params) validateVaultCfg() error {
 		return fmt.Errorf("bmcbutler vault configuration expects the vault path for secrets")
 	}
 
+	if p.Vault.RotatePath == "" {
+		p.Vault.RotatePath = p.Vault.SecretsPath
+	}
+
 	err := p.loadVaultToken()
 	if err != nil {
 		return err
@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"io/ioutil"
 	"testing"
 )
 
@@ -47,3 +48,49 @@ func TestLoad(t *testing.T) {
 		t.Errorf("Expected ButlersToSpawn: 1, got %d", cfg.ButlersToSpawn)
 	}
 }
+
+func TestDecodeStrict(t *testing.T) {
+	data := []byte("butlersToSpawn: 2\nlocations: ['ams4']\nbutlerstospawn: 3\nmetrics:\n  graphite:\n    hots: graphite.example.foo\n")
+
+	errs := DecodeStrict(data, &Params{})
+	if len(errs) != 2 {
+		t.Fatalf("Expected 2 errors, got %d: %v", len(errs), errs)
+	}
+
+	if errs[0].Error() != "line 3: field butlerstospawn not found in type config.Params" {
+		t.Errorf("Unexpected error: %s", errs[0])
+	}
+
+	if errs[1].Error() != "line 6: field hots not found in type config.Graphite" {
+		t.Errorf("Unexpected error: %s", errs[1])
+	}
+}
+
+func TestDecodeStrictSample(t *testing.T) {
+	data, err := ioutil.ReadFile("../../samples/bmcbutler.yml")
+	if err != nil {
+		t.Fatalf("Error reading sample config: %s", err)
+	}
+
+	if errs := DecodeStrict(data, &Params{}); len(errs) > 0 {
+		t.Errorf("Expected sample config to decode without errors, got: %v", errs)
+	}
+}
+
+// Test notification retries default to 3 unless declared, an explicit 0 is honoured.
+func TestNotificationRetries(t *testing.T) {
+	none := 0
+	p := &Params{Notifications: []*Notification{
+		{URL: "http://example.com", Events: []string{"failure"}},
+		{URL: "http://example.com", Events: []string{"failure"}, Retries: &none},
+	}}
+
+	if err := p.validateNotificationsCfg(); err != nil {
+		t.Fatal(err)
+	}
+
+	if p.Notifications[0].RequestRetries() != 3 || p.Notifications[1].RequestRetries() != 0 {
+		t.Fatalf("Expected 3 retries by default and 0 when declared, got %d and %d",
+			p.Notifications[0].RequestRetries(), p.Notifications[1].RequestRetries())
+	}
+}
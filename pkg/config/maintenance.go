@@ -0,0 +1,200 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Maintenance declares the configuration resources that disrupt BMCs,
+// and the windows these may be applied in, by location.
+type Maintenance struct {
+	Disruptive []string                      `mapstructure:"disruptive" yaml:"disruptive"` // e.g network, https_cert, bios
+	Windows    map[string]*MaintenanceWindow `mapstructure:"windows" yaml:"windows"`       // Locations without a window are not restricted.
+}
+
+// MaintenanceWindow declares the weekday/time ranges disruptive resources may be applied in.
+type MaintenanceWindow struct {
+	Timezone string   `mapstructure:"timezone" yaml:"timezone"` // e.g Europe/Amsterdam, defaults to UTC.
+	Ranges   []string `mapstructure:"ranges" yaml:"ranges"`     // e.g "Mon-Fri 22:00-06:00", "Sat,Sun 00:00-24:00"
+	tz       *time.Location
+	ranges   []weekRange
+}
+
+// weekRange is a time range on the weekdays declared,
+// a range ending before it starts ends on the day after.
+type weekRange struct {
+	days       [7]bool
+	start, end int // Minutes since midnight.
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Disrupts returns true if the resource is declared disruptive.
+func (m *Maintenance) Disrupts(resource string) bool {
+	if m == nil {
+		return false
+	}
+
+	for _, r := range m.Disruptive {
+		if r == resource {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Allowed returns true if disruptive resources may be applied to assets in the location at the given time,
+// if not, the reason is returned.
+func (m *Maintenance) Allowed(location string, t time.Time) (bool, string) {
+	if m == nil {
+		return true, ""
+	}
+
+	// Keys are lowercased when the configuration is read.
+	w, declared := m.Windows[strings.ToLower(location)]
+	if !declared || w == nil {
+		return true, ""
+	}
+
+	if w.tz == nil {
+		err := w.parse()
+		if err != nil {
+			return false, fmt.Sprintf("invalid %s maintenance window: %s", location, err)
+		}
+	}
+
+	if w.open(t) {
+		return true, ""
+	}
+
+	return false, fmt.Sprintf("outside the %s maintenance window (%s %s)", location, strings.Join(w.Ranges, ", "), w.tz)
+}
+
+// open returns true if the time is in any of the window ranges.
+func (w *MaintenanceWindow) open(t time.Time) bool {
+	t = t.In(w.tz)
+	day := t.Weekday()
+	previous := (day + 6) % 7
+	minute := t.Hour()*60 + t.Minute()
+
+	for _, r := range w.ranges {
+		if r.start < r.end {
+			if r.days[day] && minute >= r.start && minute < r.end {
+				return true
+			}
+			continue
+		}
+
+		// The range goes past midnight.
+		if r.days[day] && minute >= r.start || r.days[previous] && minute < r.end {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parse parses the window timezone and ranges.
+func (w *MaintenanceWindow) parse() error {
+	tz, err := time.LoadLocation(w.Timezone)
+	if err != nil {
+		return fmt.Errorf("unknown timezone %q", w.Timezone)
+	}
+
+	var ranges []weekRange
+	for _, s := range w.Ranges {
+		r, err := parseWeekRange(s)
+		if err != nil {
+			return err
+		}
+
+		ranges = append(ranges, r)
+	}
+
+	w.tz, w.ranges = tz, ranges
+	return nil
+}
+
+// parseWeekRange parses a range declared as <days> <HH:MM>-<HH:MM>,
+// days are separated by commas, or declared as a range, e.g Mon-Fri or Fri-Mon.
+func parseWeekRange(s string) (r weekRange, err error) {
+	fields := strings.Fields(s)
+	if len(fields) != 2 {
+		return r, fmt.Errorf("invalid maintenance window range %q, expected <days> <HH:MM>-<HH:MM>", s)
+	}
+
+	for _, d := range strings.Split(strings.ToLower(fields[0]), ",") {
+		bounds := strings.SplitN(d, "-", 2)
+
+		first, known := weekdays[bounds[0]]
+		if !known {
+			return r, fmt.Errorf("invalid maintenance window range %q, unknown day %q", s, bounds[0])
+		}
+
+		last := first
+		if len(bounds) == 2 {
+			last, known = weekdays[bounds[1]]
+			if !known {
+				return r, fmt.Errorf("invalid maintenance window range %q, unknown day %q", s, bounds[1])
+			}
+		}
+
+		for day := first; ; day = (day + 1) % 7 {
+			r.days[day] = true
+			if day == last {
+				break
+			}
+		}
+	}
+
+	times := strings.SplitN(fields[1], "-", 2)
+	if len(times) != 2 {
+		return r, fmt.Errorf("invalid maintenance window range %q, expected <HH:MM>-<HH:MM>", s)
+	}
+
+	r.start, err = parseMinutes(times[0])
+	if err == nil {
+		r.end, err = parseMinutes(times[1])
+	}
+
+	if err != nil {
+		return r, fmt.Errorf("invalid maintenance window range %q: %s", s, err)
+	}
+
+	if r.start == r.end {
+		return r, fmt.Errorf("invalid maintenance window range %q, expected the range to end after it starts", s)
+	}
+
+	return r, nil
+}
+
+// parseMinutes parses a HH:MM time as minutes since midnight, 24:00 is the end of the day.
+func parseMinutes(s string) (int, error) {
+	parts := strings.SplitN(s, ":", 2)
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", s)
+	}
+
+	hour, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", s)
+	}
+
+	minute, err := strconv.Atoi(parts[1])
+	if err != nil || hour < 0 || minute < 0 || minute > 59 || hour > 24 || hour == 24 && minute > 0 {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", s)
+	}
+
+	return hour*60 + minute, nil
+}
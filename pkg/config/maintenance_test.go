@@ -0,0 +1,66 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+// Test disruptive resources are allowed within the location window only, ranges may go past midnight.
+func TestMaintenanceAllowed(t *testing.T) {
+	maintenance := &Maintenance{
+		Disruptive: []string{"network", "bios"},
+		Windows: map[string]*MaintenanceWindow{
+			"ams4": {Timezone: "Europe/Amsterdam", Ranges: []string{"Mon-Fri 22:00-06:00", "Sat,Sun 00:00-24:00"}},
+			"lhr4": {Ranges: []string{"Fri-Mon 02:00-04:00"}},
+		},
+	}
+
+	p := &Params{Maintenance: maintenance}
+	err := p.validateMaintenanceCfg()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !maintenance.Disrupts("bios") || maintenance.Disrupts("ntp") {
+		t.Fatal("Expected only the declared resources to be disruptive.")
+	}
+
+	cases := []struct {
+		location string
+		time     string
+		allowed  bool
+	}{
+		{"ams4", "2020-06-10T12:00:00Z", false}, // Wednesday 14:00 in Amsterdam.
+		{"ams4", "2020-06-10T20:30:00Z", true},  // Wednesday 22:30.
+		{"ams4", "2020-06-11T03:59:00Z", true},  // Thursday 05:59, the Wednesday range goes past midnight.
+		{"ams4", "2020-06-11T04:00:00Z", false}, // Thursday 06:00.
+		{"ams4", "2020-06-13T12:00:00Z", true},  // Saturday.
+		{"AMS4", "2020-06-13T12:00:00Z", true},
+		{"lhr4", "2020-06-15T03:00:00Z", true},  // Monday, Fri-Mon wraps around the week.
+		{"lhr4", "2020-06-16T03:00:00Z", false}, // Tuesday.
+		{"fra4", "2020-06-10T12:00:00Z", true},  // No window declared.
+	}
+
+	for _, c := range cases {
+		now, _ := time.Parse(time.RFC3339, c.time)
+		allowed, reason := maintenance.Allowed(c.location, now)
+		if allowed != c.allowed || allowed != (reason == "") {
+			t.Errorf("Expected allowed %t for %s at %s, got %t %q", c.allowed, c.location, c.time, allowed, reason)
+		}
+	}
+}
+
+// Test invalid windows are reported.
+func TestValidateMaintenanceCfg(t *testing.T) {
+	for _, ranges := range []string{"Mon-Fri", "Mon-Fry 22:00-06:00", "Mon 22:00", "Mon 25:00-06:00", "Mon 06:00-06:00"} {
+		p := &Params{Maintenance: &Maintenance{Windows: map[string]*MaintenanceWindow{"ams4": {Ranges: []string{ranges}}}}}
+		if p.validateMaintenanceCfg() == nil {
+			t.Errorf("Expected an error for range %q", ranges)
+		}
+	}
+
+	p := &Params{Maintenance: &Maintenance{Windows: map[string]*MaintenanceWindow{"ams4": {Timezone: "Mars/Olympus", Ranges: []string{"Mon 22:00-06:00"}}}}}
+	if p.validateMaintenanceCfg() == nil {
+		t.Error("Expected an error for an unknown timezone.")
+	}
+}
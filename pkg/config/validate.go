@@ -0,0 +1,56 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Validate strictly decodes the given config file and runs the configuration validators,
+// unlike Load, all problems found are returned instead of exiting on the first one.
+// If the config file can't be read or parsed, err is returned and the configuration is not usable.
+func (p *Params) Validate(cfgFile string) (errs []error, err error) {
+	data, err := ioutil.ReadFile(cfgFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read bmcbutler config file: %s", err)
+	}
+
+	errs = append(errs, DecodeStrict(data, &Params{})...)
+
+	err = p.unmarshalConfig(cfgFile)
+	if err != nil {
+		return errs, fmt.Errorf("failed to unmarshal config: %s", err)
+	}
+
+	for _, v := range p.validators() {
+		err := v()
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errs, nil
+}
+
+// DecodeStrict unmarshals the given yaml data into out,
+// returning an error for each unknown key or type mismatch,
+// the errors include the line number of the offending yaml.
+func DecodeStrict(data []byte, out interface{}) (errs []error) {
+	err := yaml.UnmarshalStrict(data, out)
+	if err == nil {
+		return nil
+	}
+
+	var typeErr *yaml.TypeError
+	if !errors.As(err, &typeErr) {
+		return []error{err}
+	}
+
+	for _, e := range typeErr.Errors {
+		errs = append(errs, errors.New(e))
+	}
+
+	return errs
+}
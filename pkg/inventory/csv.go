@@ -4,6 +4,7 @@ package inventory
 // to use this source, set source: csv in bmcbutler.yml
 
 import (
+	"fmt"
 	"os"
 	"strings"
 
@@ -31,27 +32,24 @@ type CsvAsset struct {
 	Type       string `csv:"type"`   // optional
 }
 
-func (c *Csv) readCsv() []*CsvAsset {
-	log := c.Log
-
+func (c *Csv) readCsv() ([]*CsvAsset, error) {
 	var csvAssets []*CsvAsset
 	csvFile, err := os.Open(c.Config.Inventory.Csv.File)
 	if err != nil {
-		log.Error("Error: ", err)
-		os.Exit(1)
+		return nil, err
 	}
+	defer csvFile.Close()
 
 	err = gocsv.UnmarshalFile(csvFile, &csvAssets)
 	if err != nil {
-		log.Error("Error: ", err)
-		os.Exit(1)
+		return nil, fmt.Errorf("unable to read csv inventory %s: %s", c.Config.Inventory.Csv.File, err)
 	}
 
-	return csvAssets
+	return csvAssets, nil
 }
 
 // Looks at c.Config.FilterParams and returns the appropriate function that will retrieve assets.
-func (c *Csv) AssetRetrieve() func() {
+func (c *Csv) AssetRetrieve() func() error {
 	// Setup the asset types we want to retrieve data for.
 	switch {
 	case c.Config.FilterParams.Chassis:
@@ -74,9 +72,14 @@ func (c *Csv) AssetRetrieve() func() {
 }
 
 // Iterates over assets and passes these over the inventory channel.
-func (c *Csv) AssetIterBySerial() {
+func (c *Csv) AssetIterBySerial() error {
+	defer close(c.AssetsChan)
+
 	log := c.Log
-	csvAssets := c.readCsv()
+	csvAssets, err := c.readCsv()
+	if err != nil {
+		return err
+	}
 
 	serials := c.Config.FilterParams.Serials
 	assets := make([]asset.Asset, 0)
@@ -103,16 +106,19 @@ func (c *Csv) AssetIterBySerial() {
 	}
 
 	c.AssetsChan <- assets
-	close(c.AssetsChan)
+	return nil
 }
 
 // AssetIterByIP reads in list of ips passed in via cli,
 // attempts to lookup any attributes for the IP in the inventory,
 // and sends an asset for each attribute over the asset channel
-func (c *Csv) AssetIterByIP() {
+func (c *Csv) AssetIterByIP() error {
 	defer close(c.AssetsChan)
 
-	csvAssets := c.readCsv()
+	csvAssets, err := c.readCsv()
+	if err != nil {
+		return err
+	}
 
 	ips := c.Config.FilterParams.Ips
 
@@ -142,11 +148,17 @@ func (c *Csv) AssetIterByIP() {
 	}
 
 	c.AssetsChan <- assets
+	return nil
 }
 
 // AssetIter reads in assets and passes them to the inventory channel.
-func (c *Csv) AssetIter() {
-	csvAssets := c.readCsv()
+func (c *Csv) AssetIter() error {
+	defer close(c.AssetsChan)
+
+	csvAssets, err := c.readCsv()
+	if err != nil {
+		return err
+	}
 
 	assets := make([]asset.Asset, 0)
 	for _, item := range csvAssets {
@@ -168,5 +180,5 @@ func (c *Csv) AssetIter() {
 	}
 
 	c.AssetsChan <- assets
-	close(c.AssetsChan)
+	return nil
 }
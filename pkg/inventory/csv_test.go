@@ -0,0 +1,32 @@
+package inventory
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/bmc-toolbox/bmcbutler/pkg/asset"
+	"github.com/bmc-toolbox/bmcbutler/pkg/config"
+)
+
+// Test a csv inventory that can't be read is returned as an error, with the inventory channel closed.
+func TestCsvAssetIterError(t *testing.T) {
+	assetsChan := make(chan []asset.Asset, 1)
+	c := Csv{
+		Log:        logrus.New(),
+		AssetsChan: assetsChan,
+		Config: &config.Params{
+			Inventory:    &config.Inventory{Csv: &config.Csv{File: "/nonexistent/inventory.csv"}},
+			FilterParams: &config.FilterParams{All: true},
+		},
+	}
+
+	err := c.AssetRetrieve()()
+	if err == nil {
+		t.Fatal("Expected an error for a csv inventory that doesn't exist.")
+	}
+
+	if _, ok := <-assetsChan; ok {
+		t.Fatal("Expected the inventory channel closed.")
+	}
+}
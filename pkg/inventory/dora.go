@@ -23,7 +23,7 @@ import (
 
 	"github.com/bmc-toolbox/bmcbutler/pkg/asset"
 	"github.com/bmc-toolbox/bmcbutler/pkg/config"
-	metrics "github.com/bmc-toolbox/gin-go-metrics"
+	"github.com/bmc-toolbox/bmcbutler/pkg/metrics"
 	"github.com/sirupsen/logrus"
 )
 
@@ -81,12 +81,15 @@ func (d *Dora) setLocation(doraInventoryAssets []asset.Asset) (err error) {
 
 	queryURL += strings.Join(ips, ",")
 	resp, err := http.Get(queryURL)
-	if err != nil || resp.StatusCode != 200 {
+	if err == nil && resp.StatusCode != 200 {
+		resp.Body.Close()
+		err = fmt.Errorf("unexpected dora response status: %s", resp.Status)
+	}
+	if err != nil {
 		log.WithFields(logrus.Fields{
-			"component":  component,
-			"url":        queryURL,
-			"Error":      err,
-			"StatusCode": resp.StatusCode,
+			"component": component,
+			"url":       queryURL,
+			"Error":     err,
 		}).Warn("Unable to query Dora for IP location info.")
 		return err
 	}
@@ -117,7 +120,7 @@ func (d *Dora) setLocation(doraInventoryAssets []asset.Asset) (err error) {
 	return err
 }
 
-func (d *Dora) AssetRetrieve() func() {
+func (d *Dora) AssetRetrieve() func() error {
 	// Setup the asset types we want to retrieve data for.
 	switch {
 	case d.Config.FilterParams.Chassis:
@@ -140,8 +143,8 @@ func (d *Dora) AssetRetrieve() func() {
 
 // AssetIterBySerial is an iterator method,
 // to retrieve assets from Dora by the given serial numbers,
-// assets are then sent over the inventory channel.
-func (d *Dora) AssetIterBySerial() {
+// assets are then sent over the inventory channel, an error is returned if dora can't be queried.
+func (d *Dora) AssetIterBySerial() error {
 	serials := d.Config.FilterParams.Serials
 	apiURL := d.Config.Inventory.Dora.URL
 
@@ -166,33 +169,9 @@ func (d *Dora) AssetIterBySerial() {
 		queryURL += strings.ToLower(serials)
 		assets := make([]asset.Asset, 0)
 
-		resp, err := http.Get(queryURL)
-		if err != nil {
-			log.WithFields(logrus.Fields{
-				"component": component,
-				"url":       queryURL,
-				"Error":     err,
-			}).Fatal("Failed to query dora for serial(s).")
-		}
-
-		body, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			log.WithFields(logrus.Fields{
-				"component": component,
-				"url":       queryURL,
-				"Error":     err,
-			}).Fatal("Failed to query dora for serial(s).")
-		}
-		resp.Body.Close()
-
-		var doraAssets DoraAsset
-		err = json.Unmarshal(body, &doraAssets)
+		doraAssets, err := queryDora(queryURL)
 		if err != nil {
-			log.WithFields(logrus.Fields{
-				"component": component,
-				"url":       queryURL,
-				"Error":     err,
-			}).Fatal("Unable to unmarshal data returned from dora.")
+			return fmt.Errorf("failed to query dora for serial(s): %s", err)
 		}
 
 		if len(doraAssets.Data) == 0 {
@@ -231,15 +210,18 @@ func (d *Dora) AssetIterBySerial() {
 				"component": component,
 				"Error":     err,
 			}).Warn("Unable to determine location of assets.")
-			return
+			return nil
 		}
 
 		d.AssetsChan <- assets
 	}
+
+	return nil
 }
 
-// Stuffs assets into an array, writes that to the channel.
-func (d *Dora) AssetIter() {
+// Stuffs assets into an array, writes that to the channel,
+// an error is returned if dora can't be queried.
+func (d *Dora) AssetIter() error {
 	apiURL := d.Config.Inventory.Dora.URL
 	component := "retrieveInventoryAssetsDora"
 
@@ -264,33 +246,9 @@ func (d *Dora) AssetIter() {
 		for {
 			assets := make([]asset.Asset, 0)
 
-			resp, err := http.Get(queryURL)
-			if err != nil || resp.StatusCode != 200 {
-				log.WithFields(logrus.Fields{
-					"component": component,
-					"url":       queryURL,
-					"Error":     err,
-				}).Fatal("Error querying Dora for assets.")
-			}
-
-			body, err := ioutil.ReadAll(resp.Body)
-			if err != nil {
-				log.WithFields(logrus.Fields{
-					"component": component,
-					"url":       queryURL,
-					"Error":     err,
-				}).Fatal("Error querying Dora for assets.")
-			}
-			resp.Body.Close()
-
-			var doraAssets DoraAsset
-			err = json.Unmarshal(body, &doraAssets)
+			doraAssets, err := queryDora(queryURL)
 			if err != nil {
-				log.WithFields(logrus.Fields{
-					"component": component,
-					"url":       queryURL,
-					"Error":     err,
-				}).Fatal("Error unmarshaling data returned from Dora.")
+				return fmt.Errorf("error querying dora for assets: %s", err)
 			}
 
 			metrics.IncrCounter(
@@ -352,4 +310,31 @@ func (d *Dora) AssetIter() {
 			queryURL = fmt.Sprintf("%s%s", apiURL, doraAssets.Links.Next)
 		}
 	}
+
+	return nil
+}
+
+// queryDora returns the assets dora returned for the query url.
+func queryDora(queryURL string) (doraAssets DoraAsset, err error) {
+	resp, err := http.Get(queryURL)
+	if err != nil {
+		return doraAssets, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return doraAssets, fmt.Errorf("%s: unexpected response status: %s", queryURL, resp.Status)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return doraAssets, fmt.Errorf("%s: %s", queryURL, err)
+	}
+
+	err = json.Unmarshal(body, &doraAssets)
+	if err != nil {
+		return doraAssets, fmt.Errorf("%s: unable to unmarshal data returned: %s", queryURL, err)
+	}
+
+	return doraAssets, nil
 }
@@ -11,7 +11,7 @@ import (
 
 	"github.com/bmc-toolbox/bmcbutler/pkg/asset"
 	"github.com/bmc-toolbox/bmcbutler/pkg/config"
-	metrics "github.com/bmc-toolbox/gin-go-metrics"
+	"github.com/bmc-toolbox/bmcbutler/pkg/metrics"
 	"github.com/sirupsen/logrus"
 )
 
@@ -98,7 +98,7 @@ func AttributesExtrasAsMap(attributeExtras *AttributesExtras) (extras map[string
 	return extras
 }
 
-func (e *Enc) AssetRetrieve() func() {
+func (e *Enc) AssetRetrieve() func() error {
 	// Setup the asset types we want to retrieve data for.
 	switch {
 	case e.Config.FilterParams.Chassis:
@@ -164,7 +164,7 @@ func (e *Enc) SetChassisInstalled(serials string) {
 }
 
 // nolint: gocyclo
-func (e *Enc) encQueryBySerial(serials string) (assets []asset.Asset) {
+func (e *Enc) encQueryBySerial(serials string) (assets []asset.Asset, err error) {
 	log := e.Log
 	component := "encQueryBySerial"
 
@@ -179,7 +179,8 @@ func (e *Enc) encQueryBySerial(serials string) (assets []asset.Asset) {
 			"Error":     err,
 			"cmd":       fmt.Sprintf("%s %s", encBin, strings.Join(cmdArgs, " ")),
 			"output":    fmt.Sprintf("%s", out),
-		}).Fatal("Inventory query failed, lookup command returned error.")
+		}).Error("Inventory query failed, lookup command returned error.")
+		return nil, fmt.Errorf("inventory query failed, lookup command returned error: %s", err)
 	}
 
 	cmdResp := AssetAttributes{}
@@ -190,7 +191,8 @@ func (e *Enc) encQueryBySerial(serials string) (assets []asset.Asset) {
 			"Error":     err,
 			"cmd":       fmt.Sprintf("%s %s", encBin, strings.Join(cmdArgs, " ")),
 			"output":    fmt.Sprintf("%s", out),
-		}).Fatal("JSON Unmarshal command response returned error.")
+		}).Error("JSON Unmarshal command response returned error.")
+		return nil, fmt.Errorf("unable to unmarshal inventory lookup command response: %s", err)
 	}
 
 	if len(cmdResp.Data) == 0 {
@@ -199,7 +201,7 @@ func (e *Enc) encQueryBySerial(serials string) (assets []asset.Asset) {
 			"Serial(s)": serials,
 		}).Warn("No assets returned by inventory for given serial(s).")
 
-		return []asset.Asset{}
+		return []asset.Asset{}, nil
 	}
 
 	missingSerials := strings.Split(serials, ",")
@@ -237,11 +239,11 @@ func (e *Enc) encQueryBySerial(serials string) (assets []asset.Asset) {
 
 	metrics.IncrCounter([]string{"inventory", "assets_fetched_enc"}, int64(len(assets)))
 
-	return assets
+	return assets, nil
 }
 
 // nolint: gocyclo
-func (e *Enc) encQueryByIP(ips string) (assets []asset.Asset) {
+func (e *Enc) encQueryByIP(ips string) (assets []asset.Asset, err error) {
 	log := e.Log
 	component := "encQueryByIP"
 
@@ -268,7 +270,7 @@ func (e *Enc) encQueryByIP(ips string) (assets []asset.Asset) {
 		}).Warn("Inventory query failed, lookup command returned error.")
 
 		populateAssetsWithNoAttributes()
-		return assets
+		return assets, nil
 	}
 
 	cmdResp := AssetAttributes{}
@@ -279,7 +281,8 @@ func (e *Enc) encQueryByIP(ips string) (assets []asset.Asset) {
 			"Error":     err,
 			"cmd":       fmt.Sprintf("%s %s", encBin, strings.Join(cmdArgs, " ")),
 			"output":    fmt.Sprintf("%s", out),
-		}).Fatal("JSON Unmarshal command response returned error.")
+		}).Error("JSON Unmarshal command response returned error.")
+		return nil, fmt.Errorf("unable to unmarshal inventory lookup command response: %s", err)
 	}
 
 	if len(cmdResp.Data) == 0 {
@@ -289,7 +292,7 @@ func (e *Enc) encQueryByIP(ips string) (assets []asset.Asset) {
 		}).Debug("No assets returned by inventory for given IP(s).")
 
 		populateAssetsWithNoAttributes()
-		return assets
+		return assets, nil
 	}
 
 	// missing IPs are IPs we looked up using the enc and got no data for.
@@ -329,13 +332,13 @@ func (e *Enc) encQueryByIP(ips string) (assets []asset.Asset) {
 
 	metrics.IncrCounter([]string{"inventory", "assets_fetched_enc"}, int64(len(assets)))
 
-	return assets
+	return assets, nil
 }
 
 // encQueryByOffset returns a slice of assets and if the query reached the end of assets.
 // assetType is one of 'servers/chassis'
 // location is a comma delimited list of locations
-func (e *Enc) encQueryByOffset(assetType string, offset int, limit int, location string) (assets []asset.Asset, endOfAssets bool) {
+func (e *Enc) encQueryByOffset(assetType string, offset int, limit int, location string) (assets []asset.Asset, endOfAssets bool, err error) {
 	component := "EncQueryByOffset"
 	log := e.Log
 
@@ -373,7 +376,8 @@ func (e *Enc) encQueryByOffset(assetType string, offset int, limit int, location
 			"Error":     err,
 			"cmd":       fmt.Sprintf("%s %s", encBin, strings.Join(cmdArgs, " ")),
 			"output":    fmt.Sprintf("%s", out),
-		}).Fatal("Inventory query failed, lookup command returned error.")
+		}).Error("Inventory query failed, lookup command returned error.")
+		return nil, false, fmt.Errorf("inventory query failed, lookup command returned error: %s", err)
 	}
 
 	cmdResp := AssetAttributes{}
@@ -384,13 +388,14 @@ func (e *Enc) encQueryByOffset(assetType string, offset int, limit int, location
 			"Error":     err,
 			"cmd":       fmt.Sprintf("%s %s", encBin, strings.Join(cmdArgs, " ")),
 			"output":    fmt.Sprintf("%s", out),
-		}).Fatal("JSON Unmarshal command response returned error.")
+		}).Error("JSON Unmarshal command response returned error.")
+		return nil, false, fmt.Errorf("unable to unmarshal inventory lookup command response: %s", err)
 	}
 
 	endOfAssets = cmdResp.EndOfAssets
 
 	if len(cmdResp.Data) == 0 {
-		return []asset.Asset{}, endOfAssets
+		return []asset.Asset{}, endOfAssets, nil
 	}
 
 	for serial, attributes := range cmdResp.Data {
@@ -413,12 +418,13 @@ func (e *Enc) encQueryByOffset(assetType string, offset int, limit int, location
 
 	metrics.IncrCounter([]string{"inventory", "assets_fetched_enc"}, int64(len(assets)))
 
-	return assets, endOfAssets
+	return assets, endOfAssets, nil
 }
 
 // AssetIter fetches assets and sends them over the asset channel.
-// Iter stuffs assets into an array of Assets, writes that to the channel.
-func (e *Enc) AssetIter() {
+// Iter stuffs assets into an array of Assets, writes that to the channel,
+// an error is returned if the inventory query fails.
+func (e *Enc) AssetIter() error {
 	var interrupt bool
 
 	go func() { <-e.StopChan; interrupt = true }()
@@ -432,9 +438,10 @@ func (e *Enc) AssetIter() {
 		offset := 0
 
 		for {
-			var endOfAssets bool
-
-			assets, endOfAssets := e.encQueryByOffset(assetType, offset, limit, locations)
+			assets, endOfAssets, err := e.encQueryByOffset(assetType, offset, limit, locations)
+			if err != nil {
+				return err
+			}
 
 			e.Log.WithFields(logrus.Fields{
 				"component": "inventory",
@@ -460,25 +467,37 @@ func (e *Enc) AssetIter() {
 			}
 		}
 	}
+
+	return nil
 }
 
 // Reads the list of serials passed by the user via CLI.
 // Queries ENC for the serials, then passes them to the assets channel.
-func (e *Enc) AssetIterBySerial() {
+func (e *Enc) AssetIterBySerial() error {
 	defer close(e.AssetsChan)
 
 	serials := e.Config.FilterParams.Serials
-	assets := e.encQueryBySerial(serials)
+	assets, err := e.encQueryBySerial(serials)
+	if err != nil {
+		return err
+	}
+
 	e.AssetsChan <- assets
+	return nil
 }
 
 // Reads the list of IPs passed by the user via CLI.
 // Queries ENC for attributes related to those, then passes them to the assets channel.
 // If no attributes for a given IP are returned, an asset with just the IP is returned.
-func (e *Enc) AssetIterByIP() {
+func (e *Enc) AssetIterByIP() error {
 	defer close(e.AssetsChan)
 
 	ips := e.Config.FilterParams.Ips
-	assets := e.encQueryByIP(ips)
+	assets, err := e.encQueryByIP(ips)
+	if err != nil {
+		return err
+	}
+
 	e.AssetsChan <- assets
+	return nil
 }
@@ -82,7 +82,10 @@ func TestEncQueryBySerial(t *testing.T) {
 		Config: &config.Params{Inventory: &config.Inventory{Source: assetLookup}},
 	}
 
-	assets := enc.encQueryBySerial(strings.Join(serials, ","))
+	assets, err := enc.encQueryBySerial(strings.Join(serials, ","))
+	if err != nil {
+		t.Fatal(err)
+	}
 	if len(assets) < 2 {
 		t.Fatalf("Expected two assets to be returned, got %d", len(assets))
 	}
@@ -17,13 +17,13 @@ type IPList struct {
 	Config    *config.Params       // bmcbutler config + CLI params passed by the user.
 }
 
-func (i *IPList) AssetRetrieve() func() {
+func (i *IPList) AssetRetrieve() func() error {
 	return i.AssetIter
 }
 
 // AssetIter is an iterator method that sends assets to configure
 // over the inventory channel.
-func (i *IPList) AssetIter() {
+func (i *IPList) AssetIter() error {
 	ips := strings.Split(i.Config.FilterParams.Ips, ",")
 
 	assets := make([]asset.Asset, 0)
@@ -33,4 +33,5 @@ func (i *IPList) AssetIter() {
 
 	i.Channel <- assets
 	close(i.Channel)
+	return nil
 }
@@ -0,0 +1,118 @@
+package metrics
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	ginmetrics "github.com/bmc-toolbox/gin-go-metrics"
+
+	"github.com/bmc-toolbox/bmcbutler/pkg/config"
+)
+
+// Metrics clients, selected by the metrics section declared in the configuration.
+const (
+	ClientGraphite   = "graphite"
+	ClientPrometheus = "prometheus"
+)
+
+// The metrics client set up, metrics are dropped until one is.
+var client string
+
+// Label is a metric label, e.g the vendor of the asset the metric was counted for.
+// Graphite has no labels, label values are appended to the metric key.
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Setup sets up the metrics client declared in the configuration.
+func Setup(c *config.Metrics) error {
+	switch c.Client {
+	case ClientGraphite:
+		err := ginmetrics.Setup(c.Client, c.Graphite.Host, c.Graphite.Port, c.Graphite.Prefix, c.Graphite.FlushInterval)
+		if err != nil {
+			return err
+		}
+	case ClientPrometheus:
+		err := setupPrometheus(c.Prometheus)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("no supported metrics client declared in config")
+	}
+
+	client = c.Client
+	return nil
+}
+
+// IncrCounter increments the counter.
+func IncrCounter(key []string, value int64) {
+	IncrCounterWithLabels(key, value, nil)
+}
+
+// IncrCounterWithLabels increments the counter with the given labels.
+func IncrCounterWithLabels(key []string, value int64, labels []Label) {
+	switch client {
+	case ClientGraphite:
+		ginmetrics.IncrCounter(graphiteKey(key, labels), value)
+	case ClientPrometheus:
+		registry.add(key, labels, value)
+	}
+}
+
+// UpdateGauge sets the gauge to the value.
+func UpdateGauge(key []string, value int64) {
+	switch client {
+	case ClientGraphite:
+		ginmetrics.UpdateGauge(key, value)
+	case ClientPrometheus:
+		registry.set(key, value)
+	}
+}
+
+// UpdateTimer records the duration.
+func UpdateTimer(key []string, value time.Duration) {
+	switch client {
+	case ClientGraphite:
+		ginmetrics.UpdateTimer(key, value)
+	case ClientPrometheus:
+		registry.observe(key, value)
+	}
+}
+
+// MeasureRuntime sets the gauge to the milliseconds elapsed since start.
+func MeasureRuntime(key []string, start time.Time) {
+	UpdateGauge(key, int64(time.Since(start)/time.Millisecond))
+}
+
+// Close flushes metrics to Graphite, or pushes them to the Pushgateway if declared,
+// with printStats the Graphite metrics are logged.
+func Close(printStats bool) {
+	switch client {
+	case ClientGraphite:
+		ginmetrics.Close(printStats)
+	case ClientPrometheus:
+		closePrometheus()
+	}
+}
+
+// graphiteKey returns the key with label values appended, dots in values are replaced,
+// empty values are appended as unknown.
+func graphiteKey(key []string, labels []Label) []string {
+	if len(labels) == 0 {
+		return key
+	}
+
+	k := append([]string{}, key...)
+	for _, l := range labels {
+		v := strings.Replace(l.Value, ".", "_", -1)
+		if v == "" {
+			v = "unknown"
+		}
+		k = append(k, v)
+	}
+
+	return k
+}
@@ -0,0 +1,249 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/bmc-toolbox/bmcbutler/pkg/config"
+)
+
+// Prometheus metric types.
+const (
+	typeCounter = "counter"
+	typeGauge   = "gauge"
+	typeSummary = "summary"
+)
+
+var (
+	registry       *Registry
+	prometheusCfg  *config.Prometheus
+	invalidNameRe  = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+	labelEscaper   = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	pushHTTPClient = &http.Client{Timeout: 30 * time.Second}
+)
+
+// Registry holds metrics exposed in the Prometheus text format.
+type Registry struct {
+	prefix   string
+	mu       sync.Mutex
+	families map[string]*family
+}
+
+// family is the series of a metric, by their labels.
+type family struct {
+	kind   string
+	series map[string]*series
+}
+
+// series is a metric with a set of label values.
+type series struct {
+	value float64 // The counter or gauge value, the sum of observed seconds for summaries.
+	count int64   // The number of observations, for summaries.
+}
+
+// NewRegistry returns a registry exposing metrics with names prefixed with the prefix.
+func NewRegistry(prefix string) *Registry {
+	return &Registry{prefix: prefix, families: make(map[string]*family)}
+}
+
+// setupPrometheus sets up the registry, and serves it on the listen address if declared.
+func setupPrometheus(c *config.Prometheus) error {
+	registry = NewRegistry(c.Prefix)
+	prometheusCfg = c
+
+	if c.Listen == "" {
+		return nil
+	}
+
+	listener, err := net.Listen("tcp", c.Listen)
+	if err != nil {
+		return fmt.Errorf("unable to serve prometheus metrics: %s", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", registry)
+
+	go func() {
+		err := http.Serve(listener, mux)
+		if err != nil {
+			log.WithFields(log.Fields{"component": "metrics", "Error": err}).Error("Prometheus metrics endpoint stopped.")
+		}
+	}()
+
+	return nil
+}
+
+// closePrometheus pushes the metrics to the Pushgateway if declared.
+func closePrometheus() {
+	if prometheusCfg.PushURL == "" {
+		return
+	}
+
+	err := registry.Push(prometheusCfg.PushURL, prometheusCfg.Job)
+	if err != nil {
+		log.WithFields(log.Fields{"component": "metrics", "Error": err}).Error("Unable to push metrics to the Pushgateway.")
+	}
+}
+
+// name returns the metric name of the key, prefixed, with characters not valid in metric names replaced.
+func (r *Registry) name(key []string, suffix string) string {
+	parts := append([]string{r.prefix}, key...)
+	if suffix != "" {
+		parts = append(parts, suffix)
+	}
+
+	return invalidNameRe.ReplaceAllString(strings.Join(parts, "_"), "_")
+}
+
+// get returns the series of the metric with the labels, registering it if it doesn't exist.
+func (r *Registry) get(name string, kind string, labels []Label) *series {
+	f, exists := r.families[name]
+	if !exists {
+		f = &family{kind: kind, series: make(map[string]*series)}
+		r.families[name] = f
+	}
+
+	sorted := append([]Label{}, labels...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	id := formatLabels(sorted)
+	s, exists := f.series[id]
+	if !exists {
+		s = &series{}
+		f.series[id] = s
+	}
+
+	return s
+}
+
+func (r *Registry) add(key []string, labels []Label, value int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.get(r.name(key, "total"), typeCounter, labels).value += float64(value)
+}
+
+func (r *Registry) set(key []string, value int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.get(r.name(key, ""), typeGauge, nil).value = float64(value)
+}
+
+func (r *Registry) observe(key []string, value time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s := r.get(r.name(key, "seconds"), typeSummary, nil)
+	s.value += value.Seconds()
+	s.count++
+}
+
+// Write writes the metrics in the Prometheus text exposition format, sorted by name and labels.
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	names := make([]string, 0, len(r.families))
+	for name := range r.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	for _, name := range names {
+		f := r.families[name]
+		fmt.Fprintf(&buf, "# TYPE %s %s\n", name, f.kind)
+
+		ids := make([]string, 0, len(f.series))
+		for id := range f.series {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+
+		for _, id := range ids {
+			s := f.series[id]
+			if f.kind == typeSummary {
+				fmt.Fprintf(&buf, "%s_sum%s %s\n", name, id, formatValue(s.value))
+				fmt.Fprintf(&buf, "%s_count%s %d\n", name, id, s.count)
+				continue
+			}
+
+			fmt.Fprintf(&buf, "%s%s %s\n", name, id, formatValue(s.value))
+		}
+	}
+
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// ServeHTTP serves the metrics in the Prometheus text exposition format.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	err := r.Write(w)
+	if err != nil {
+		log.WithFields(log.Fields{"component": "metrics", "Error": err}).Warn("Unable to write metrics.")
+	}
+}
+
+// Push replaces the metrics of the job, and this host, on the Pushgateway.
+func (r *Registry) Push(pushURL string, job string) error {
+	var buf bytes.Buffer
+	err := r.Write(&buf)
+	if err != nil {
+		return err
+	}
+
+	hostname, _ := os.Hostname()
+	target := fmt.Sprintf("%s/metrics/job/%s/instance/%s", strings.TrimSuffix(pushURL, "/"), url.PathEscape(job), url.PathEscape(hostname))
+
+	req, err := http.NewRequest(http.MethodPut, target, &buf)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+
+	resp, err := pushHTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected Pushgateway response status: %s", resp.Status)
+	}
+
+	return nil
+}
+
+// formatLabels returns the labels as {name="value",...}, empty if there are none.
+func formatLabels(labels []Label) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	pairs := make([]string, len(labels))
+	for i, l := range labels {
+		pairs[i] = invalidNameRe.ReplaceAllString(l.Name, "_") + `="` + labelEscaper.Replace(l.Value) + `"`
+	}
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatValue(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,78 @@
+package metrics
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test metrics are exposed in the Prometheus text format, sorted, with label values escaped.
+func TestRegistryWrite(t *testing.T) {
+	r := NewRegistry("bmcbutler")
+
+	labels := []Label{{Name: "vendor", Value: "dell"}, {Name: "location", Value: `a"ms`}}
+	r.add([]string{"butler", "resource"}, labels, 1)
+	r.add([]string{"butler", "resource"}, []Label{labels[1], labels[0]}, 2)
+	r.add([]string{"butler", "configure_success"}, nil, 1)
+	r.set([]string{"inventory", "assets-fetched"}, 5)
+	r.observe([]string{"butler", "configure"}, 1500*time.Millisecond)
+
+	var buf bytes.Buffer
+	err := r.Write(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `# TYPE bmcbutler_butler_configure_seconds summary
+bmcbutler_butler_configure_seconds_sum 1.5
+bmcbutler_butler_configure_seconds_count 1
+# TYPE bmcbutler_butler_configure_success_total counter
+bmcbutler_butler_configure_success_total 1
+# TYPE bmcbutler_butler_resource_total counter
+bmcbutler_butler_resource_total{location="a\"ms",vendor="dell"} 3
+# TYPE bmcbutler_inventory_assets_fetched gauge
+bmcbutler_inventory_assets_fetched 5
+`
+	if buf.String() != expected {
+		t.Fatalf("Expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
+
+// Test metrics are pushed to the job on the Pushgateway.
+func TestRegistryPush(t *testing.T) {
+	var path, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		data, _ := ioutil.ReadAll(req.Body)
+		if req.Method != http.MethodPut {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		path, body = req.URL.Path, string(data)
+	}))
+	defer server.Close()
+
+	r := NewRegistry("bmcbutler")
+	r.add([]string{"butler", "asset_recvd"}, nil, 2)
+
+	err := r.Push(server.URL+"/", "bmcbutler")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(path, "/metrics/job/bmcbutler/instance/") || !strings.Contains(body, "bmcbutler_butler_asset_recvd_total 2\n") {
+		t.Fatalf("Expected metrics pushed to the job, got %s:\n%s", path, body)
+	}
+
+	unavailable := httptest.NewServer(http.NotFoundHandler())
+	defer unavailable.Close()
+
+	err = r.Push(unavailable.URL, "bmcbutler")
+	if err == nil {
+		t.Fatal("Expected an error on an unexpected response status")
+	}
+}
@@ -0,0 +1,316 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/bmc-toolbox/bmcbutler/pkg/asset"
+	"github.com/bmc-toolbox/bmcbutler/pkg/config"
+)
+
+// Events notifications are sent for.
+const (
+	EventFailure     = "failure"      // The action failed on an asset, once retries are exhausted.
+	EventReset       = "reset"        // A BMC was reset for configuration to take effect.
+	EventCertRenewed = "cert_renewed" // The HTTPS cert of a BMC was renewed.
+	EventSummary     = "summary"      // A run is done.
+)
+
+const (
+	// Events queued for each target, once full further events are dropped, so butlers never wait on notifications.
+	queueSize = 100
+	// How long Close waits for queued events to be sent.
+	closeTimeout = 30 * time.Second
+)
+
+// The delay before the first retry of a request, doubled for each retry after.
+var retryBackoff = time.Second
+
+// Event is a run event, the payload of notifications is rendered from it.
+type Event struct {
+	Event        string    `json:"event"`
+	Time         time.Time `json:"time"`
+	Hostname     string    `json:"hostname"`
+	Action       string    `json:"action"`
+	Serial       string    `json:"serial,omitempty"`
+	IPAddress    string    `json:"ip_address,omitempty"`
+	Vendor       string    `json:"vendor,omitempty"`
+	HardwareType string    `json:"hardware_type,omitempty"`
+	Type         string    `json:"type,omitempty"`
+	Location     string    `json:"location,omitempty"`
+	Error        string    `json:"error,omitempty"`
+	Summary      *Summary  `json:"summary,omitempty"` // For summary events.
+}
+
+// Summary is the outcome of a run.
+type Summary struct {
+	Assets       int      `json:"assets"`
+	Succeeded    int      `json:"succeeded"`
+	Failed       int      `json:"failed"`
+	LoginFailed  int      `json:"login_failed"`
+	Skipped      int      `json:"skipped"`
+	FailureRatio float64  `json:"failure_ratio"` // Failed assets to assets done.
+	Halted       bool     `json:"halted"`
+	Interrupted  bool     `json:"interrupted"`
+	Duration     float64  `json:"duration_seconds"`
+	Locations    []string `json:"locations,omitempty"` // The locations the run was limited to.
+}
+
+// target is a webhook notifications are sent to, with a queue of rendered payloads.
+type target struct {
+	config  *config.Notification
+	events  map[string]bool
+	payload *template.Template
+	client  *http.Client
+	queue   chan []byte
+}
+
+// Notifier renders the payloads of events and sends them to the webhooks declared for them.
+type Notifier struct {
+	Log      *logrus.Logger
+	targets  []*target
+	hostname string
+	wg       sync.WaitGroup
+	mu       sync.RWMutex // Guards closed, so events aren't queued once queues are closed.
+	closed   bool
+}
+
+// templateFuncs are available to payload templates, json encodes a value as JSON, e.g {{ json .Error }}
+var templateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// New returns a notifier sending to the webhooks declared, nil if none are declared.
+func New(notifications []*config.Notification, log *logrus.Logger) (*Notifier, error) {
+	if len(notifications) == 0 {
+		return nil, nil
+	}
+
+	hostname, _ := os.Hostname()
+	n := &Notifier{Log: log, hostname: hostname}
+
+	for _, c := range notifications {
+		t := &target{
+			config: c,
+			events: make(map[string]bool),
+			client: &http.Client{Timeout: c.Timeout},
+			queue:  make(chan []byte, queueSize),
+		}
+
+		for _, e := range c.Events {
+			t.events[e] = true
+		}
+
+		if c.Payload != "" {
+			var err error
+			t.payload, err = template.New(c.Name).Funcs(templateFuncs).Option("missingkey=error").Parse(c.Payload)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s payload template: %s", c.Name, err)
+			}
+		}
+
+		n.targets = append(n.targets, t)
+	}
+
+	for _, t := range n.targets {
+		n.wg.Add(1)
+		go n.send(t)
+	}
+
+	return n, nil
+}
+
+// AssetEvent returns the event for the asset.
+func AssetEvent(event string, a *asset.Asset, err error) Event {
+	e := Event{
+		Event:        event,
+		Action:       a.Action,
+		Serial:       a.Serial,
+		IPAddress:    a.IPAddress,
+		Vendor:       a.Vendor,
+		HardwareType: a.HardwareType,
+		Type:         a.Type,
+		Location:     a.Location,
+	}
+
+	if err != nil {
+		e.Error = err.Error()
+	}
+
+	return e
+}
+
+// Notify queues the event for the webhooks declared for it, it never blocks,
+// events are dropped if a webhook queue is full.
+func (n *Notifier) Notify(e Event) {
+	if n == nil {
+		return
+	}
+
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	if e.Hostname == "" {
+		e.Hostname = n.hostname
+	}
+
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	if n.closed {
+		return
+	}
+
+	for _, t := range n.targets {
+		if !t.events[e.Event] || !t.summaryMatches(e.Summary) {
+			continue
+		}
+
+		payload, err := t.render(e)
+		if err != nil {
+			n.Log.WithFields(logrus.Fields{
+				"component":    "notify",
+				"Notification": t.config.Name,
+				"Event":        e.Event,
+				"Error":        err,
+			}).Error("Unable to render notification payload.")
+			continue
+		}
+
+		select {
+		case t.queue <- payload:
+		default:
+			n.Log.WithFields(logrus.Fields{
+				"component":    "notify",
+				"Notification": t.config.Name,
+				"Event":        e.Event,
+				"Serial":       e.Serial,
+			}).Warn("Notification queue full, event dropped.")
+		}
+	}
+}
+
+// summaryMatches returns true unless the run summary is below the failure thresholds of the target.
+func (t *target) summaryMatches(s *Summary) bool {
+	if s == nil {
+		return true
+	}
+
+	return s.Failed >= t.config.MinFailures && s.FailureRatio >= t.config.MinFailureRatio
+}
+
+// render returns the target payload for the event, the event as JSON if no template is declared.
+func (t *target) render(e Event) ([]byte, error) {
+	if t.payload == nil {
+		return json.Marshal(e)
+	}
+
+	var buf bytes.Buffer
+	err := t.payload.Execute(&buf, e)
+	if err != nil {
+		return nil, err
+	}
+
+	if !json.Valid(buf.Bytes()) {
+		return nil, fmt.Errorf("payload is not valid JSON: %s", buf.String())
+	}
+
+	return buf.Bytes(), nil
+}
+
+// send posts the payloads queued for the target, retrying requests that failed with backoff.
+func (n *Notifier) send(t *target) {
+	defer n.wg.Done()
+
+	for payload := range t.queue {
+		backoff := retryBackoff
+
+		var err error
+		retries := t.config.RequestRetries()
+		for attempt := 0; attempt <= retries; attempt++ {
+			if attempt > 0 {
+				time.Sleep(backoff)
+				backoff *= 2
+			}
+
+			err = t.post(payload)
+			if err == nil {
+				break
+			}
+		}
+
+		if err != nil {
+			n.Log.WithFields(logrus.Fields{
+				"component":    "notify",
+				"Notification": t.config.Name,
+				"Attempts":     retries + 1,
+				"Error":        err,
+			}).Error("Unable to send notification.")
+		}
+	}
+}
+
+// post sends the payload to the target webhook.
+func (t *target) post(payload []byte) error {
+	req, err := http.NewRequest(http.MethodPost, t.config.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range t.config.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected response status: %s", resp.Status)
+	}
+
+	return nil
+}
+
+// Close stops queueing events, and waits for queued events to be sent, for up to 30 seconds.
+func (n *Notifier) Close() {
+	if n == nil {
+		return
+	}
+
+	n.mu.Lock()
+	if n.closed {
+		n.mu.Unlock()
+		return
+	}
+
+	n.closed = true
+	for _, t := range n.targets {
+		close(t.queue)
+	}
+	n.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() { n.wg.Wait(); close(done) }()
+
+	select {
+	case <-done:
+	case <-time.After(closeTimeout):
+		n.Log.WithFields(logrus.Fields{"component": "notify"}).Warn("Notifications still queued were not sent.")
+	}
+}
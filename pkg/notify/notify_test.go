@@ -0,0 +1,126 @@
+package notify
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/bmc-toolbox/bmcbutler/pkg/asset"
+	"github.com/bmc-toolbox/bmcbutler/pkg/config"
+)
+
+// Test events are sent to the webhooks declared for them, with the templated payload,
+// summaries below the failure thresholds are not sent, and failed requests are retried.
+func TestNotify(t *testing.T) {
+	retryBackoff = time.Millisecond
+
+	var mu sync.Mutex
+	payloads := make(map[string][]map[string]interface{})
+	var requests int
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		// The first request fails, and is retried.
+		requests++
+		if requests == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+
+		var payload map[string]interface{}
+		err := json.NewDecoder(r.Body).Decode(&payload)
+		if err != nil || r.Header.Get("X-Token") != "secret" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		payloads[r.URL.Path] = append(payloads[r.URL.Path], payload)
+	}))
+	defer server.Close()
+
+	retries := 2
+	notifier, err := New([]*config.Notification{
+		{
+			Name:    "chat",
+			URL:     server.URL + "/chat",
+			Events:  []string{EventFailure, EventReset},
+			Payload: `{"text": {{ json (printf "%s failed on %s: %s" .Action .Serial .Error) }}}`,
+			Headers: map[string]string{"x-token": "secret"},
+			Timeout: time.Second,
+			Retries: &retries,
+		},
+		{
+			Name:            "oncall",
+			URL:             server.URL + "/oncall",
+			Events:          []string{EventSummary},
+			Headers:         map[string]string{"x-token": "secret"},
+			Timeout:         time.Second,
+			Retries:         &retries,
+			MinFailureRatio: 0.2,
+		},
+	}, logrus.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := &asset.Asset{Serial: "FOO", Action: asset.ActionConfigure, Location: "ams4"}
+	notifier.Notify(AssetEvent(EventFailure, a, errors.New("login failed")))
+	notifier.Notify(AssetEvent(EventCertRenewed, a, nil))
+	notifier.Notify(Event{Event: EventSummary, Summary: &Summary{Assets: 10, Failed: 1, FailureRatio: 0.1}})
+	notifier.Notify(Event{Event: EventSummary, Summary: &Summary{Assets: 10, Failed: 5, FailureRatio: 0.5}})
+	notifier.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	chat := payloads["/chat"]
+	if len(chat) != 1 || chat[0]["text"] != "configure failed on FOO: login failed" {
+		t.Fatalf("Expected the failure sent with the templated payload, got %v", chat)
+	}
+
+	oncall := payloads["/oncall"]
+	if len(oncall) != 1 || oncall[0]["summary"].(map[string]interface{})["failed"] != float64(5) {
+		t.Fatalf("Expected only the summary above the failure ratio sent, got %v", oncall)
+	}
+}
+
+// Test Notify doesn't block when the webhook doesn't respond, and events are dropped once the queue is full.
+func TestNotifyNeverBlocks(t *testing.T) {
+	block := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { <-block }))
+	defer server.Close()
+	defer close(block)
+
+	log := logrus.New()
+	log.Out = ioutil.Discard
+
+	notifier, err := New([]*config.Notification{
+		{Name: "slow", URL: server.URL, Events: []string{EventFailure}, Timeout: time.Minute},
+	}, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < queueSize*2; i++ {
+			notifier.Notify(AssetEvent(EventFailure, &asset.Asset{Serial: "FOO"}, nil))
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected Notify not to block.")
+	}
+}
@@ -0,0 +1,452 @@
+package report
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bmc-toolbox/bmcbutler/pkg/butler"
+	"github.com/bmc-toolbox/bmcbutler/pkg/butler/configure"
+	"github.com/bmc-toolbox/bmcbutler/pkg/checkpoint"
+)
+
+// Formats the run report can be written in.
+const (
+	FormatJSON  = "json"
+	FormatJUnit = "junit"
+)
+
+// Process exit codes of a run.
+const (
+	ExitSuccess     = 0 // All assets succeeded, or were skipped since they're not managed.
+	ExitFailed      = 2 // One or more assets failed.
+	ExitLoginFailed = 3 // One or more assets failed, all of them since they could not be logged into.
+	ExitInterrupted = 4 // The run was aborted by an interrupt.
+	ExitHalted      = 5 // The run was halted since failures crossed the limits, or a rollout stage failed.
+)
+
+// The number of assets that have to be done before the failure ratio limit is enforced.
+const minFailureRatioAssets = 10
+
+// Limits halt a run once the failures cross them, zero values are not enforced.
+type Limits struct {
+	MaxFailures     int     // Halt once more than this number of assets failed.
+	MaxFailureRatio float64 // Halt once the ratio of failed assets to assets done is above this, e.g 0.1
+}
+
+// exceeded returns true if the run failures crossed the limits.
+func (l Limits) exceeded(run *Run) bool {
+	if l.MaxFailures > 0 && run.Failed > l.MaxFailures {
+		return true
+	}
+
+	done := run.Succeeded + run.Failed
+	if l.MaxFailureRatio > 0 && done >= minFailureRatioAssets && float64(run.Failed)/float64(done) > l.MaxFailureRatio {
+		return true
+	}
+
+	return false
+}
+
+// Run is the report of a configure/execute run, with the outcome for each asset.
+type Run struct {
+	Action      string        `json:"action"`
+	Start       time.Time     `json:"start"`
+	End         time.Time     `json:"end"`
+	Assets      int           `json:"assets"`
+	Succeeded   int           `json:"succeeded"`
+	Failed      int           `json:"failed"`
+	LoginFailed int           `json:"login_failed"` // Assets that failed since they could not be logged into.
+	Skipped     int           `json:"skipped"`
+	Deferred    int           `json:"deferred"` // Assets with disruptive resources skipped outside their maintenance window.
+	Halted      bool          `json:"halted"`   // No new assets were dispatched once failures crossed the limits, or a rollout stage failed.
+	Results     []AssetResult `json:"results"`
+}
+
+// AssetResult is the outcome of the run action on an asset.
+type AssetResult struct {
+	Serial       string                     `json:"serial"`
+	IPAddress    string                     `json:"ip_address"` // The IP address the login worked with.
+	Vendor       string                     `json:"vendor"`
+	HardwareType string                     `json:"hardware_type"`
+	Type         string                     `json:"type"`
+	Location     string                     `json:"location"`
+	User         string                     `json:"user,omitempty"` // The user of the credentials the login worked with.
+	Success      bool                       `json:"success"`
+	Skipped      bool                       `json:"skipped"`
+	Error        string                     `json:"error,omitempty"`
+	LoginFailed  bool                       `json:"login_failed"`
+	Output       string                     `json:"output,omitempty"`
+	Resources    []configure.ResourceResult `json:"resources,omitempty"`
+	Reset        bool                       `json:"reset"`
+	Attempts     int                        `json:"attempts"`
+	Duration     float64                    `json:"duration_seconds"`
+}
+
+// Collector collects butler results received over a channel into a run report.
+type Collector struct {
+	run        *Run
+	checkpoint *checkpoint.Checkpoint
+	mu         sync.Mutex    // Guards run while results are collected.
+	progress   chan struct{} // Closed, and replaced, when a result is collected.
+	halt       chan struct{}
+	haltOnce   sync.Once
+	done       chan struct{}
+}
+
+// Collect collects the results received over the resultChan, until it's closed,
+// the run is halted once failures cross the limits,
+// the status of each asset is recorded in the checkpoint if declared.
+func Collect(action string, resultChan <-chan butler.Result, limits Limits, runCheckpoint *checkpoint.Checkpoint) *Collector {
+	c := &Collector{
+		run:        &Run{Action: action, Start: time.Now(), Results: []AssetResult{}},
+		checkpoint: runCheckpoint,
+		progress:   make(chan struct{}),
+		halt:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+
+	go func() {
+		defer close(c.done)
+		for result := range resultChan {
+			c.mu.Lock()
+			c.run.add(result)
+			exceeded := limits.exceeded(c.run)
+			close(c.progress)
+			c.progress = make(chan struct{})
+			c.mu.Unlock()
+
+			if c.checkpoint != nil {
+				c.checkpoint.Record(result)
+			}
+
+			if exceeded {
+				c.Stop()
+			}
+		}
+	}()
+
+	return c
+}
+
+// Halt returns a channel that's closed once failures crossed the limits, or the run was stopped,
+// no new assets are expected to be dispatched after.
+func (c *Collector) Halt() <-chan struct{} {
+	return c.halt
+}
+
+// Stop halts the run, no new assets are expected to be dispatched after.
+func (c *Collector) Stop() {
+	c.haltOnce.Do(func() {
+		c.mu.Lock()
+		c.run.Halted = true
+		c.mu.Unlock()
+
+		close(c.halt)
+	})
+}
+
+// Results returns the number of results collected so far.
+func (c *Collector) Results() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.run.Results)
+}
+
+// WaitResults waits until n results were collected, and returns the results from the from-th result on,
+// it returns early if the stopChan or the result channel is closed.
+func (c *Collector) WaitResults(from int, n int, stopChan <-chan struct{}) []AssetResult {
+	for {
+		c.mu.Lock()
+		collected := len(c.run.Results)
+		progress := c.progress
+		if collected >= n {
+			if from > collected {
+				from = collected
+			}
+
+			results := append([]AssetResult{}, c.run.Results[from:]...)
+			c.mu.Unlock()
+			return results
+		}
+		c.mu.Unlock()
+
+		select {
+		case <-progress:
+		case <-stopChan:
+			n = 0
+		case <-c.done:
+			n = 0
+		}
+	}
+}
+
+// Checkpoint returns the checkpoint the run is recorded in, nil if none was declared.
+func (c *Collector) Checkpoint() *checkpoint.Checkpoint {
+	return c.checkpoint
+}
+
+// Wait waits for the result channel to be closed, and returns the run report.
+func (c *Collector) Wait() *Run {
+	<-c.done
+	c.run.End = time.Now()
+
+	return c.run
+}
+
+// add adds the butler result to the run.
+func (r *Run) add(result butler.Result) {
+	a := result.Asset
+
+	ip := a.IPAddress
+	if ip == "" {
+		ip = strings.Join(a.IPAddresses, ",")
+	}
+
+	r.Results = append(r.Results, AssetResult{
+		Serial:       a.Serial,
+		IPAddress:    ip,
+		Vendor:       a.Vendor,
+		HardwareType: a.HardwareType,
+		Type:         a.Type,
+		Location:     a.Location,
+		User:         result.User,
+		Success:      result.Success,
+		Skipped:      result.Skipped,
+		Error:        result.Error,
+		LoginFailed:  result.LoginFailed,
+		Output:       result.Output,
+		Resources:    result.Resources,
+		Reset:        result.Reset,
+		Attempts:     result.Attempts,
+		Duration:     result.End.Sub(result.Start).Seconds(),
+	})
+
+	r.Assets++
+	switch {
+	case result.Skipped:
+		r.Skipped++
+	case result.Success:
+		r.Succeeded++
+	default:
+		r.Failed++
+		if result.LoginFailed {
+			r.LoginFailed++
+		}
+	}
+
+	for _, resource := range result.Resources {
+		if resource.Skipped {
+			r.Deferred++
+			break
+		}
+	}
+}
+
+// ExitCode returns the process exit code for the run.
+func (r *Run) ExitCode(interrupted bool) int {
+	switch {
+	case interrupted:
+		return ExitInterrupted
+	case r.Halted:
+		return ExitHalted
+	case r.Failed == 0:
+		return ExitSuccess
+	case r.Failed == r.LoginFailed:
+		return ExitLoginFailed
+	default:
+		return ExitFailed
+	}
+}
+
+// WriteFile writes the run report to the file in the given format.
+func (r *Run) WriteFile(file string, format string) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case FormatJSON:
+		err = r.WriteJSON(f)
+	case FormatJUnit:
+		err = r.WriteJUnit(f)
+	default:
+		err = fmt.Errorf("unknown report format: %s", format)
+	}
+
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// WriteJSON writes the run report as a JSON document.
+func (r *Run) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(r)
+}
+
+// junitTestSuite is the JUnit XML test suite the run is reported as, with a test case per asset.
+type junitTestSuite struct {
+	XMLName   xml.Name        `xml:"testsuite"`
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      float64         `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      float64       `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+// WriteJUnit writes the run report as a JUnit XML test suite,
+// assets are test cases named by serial and classed by location.
+func (r *Run) WriteJUnit(w io.Writer) error {
+	suite := junitTestSuite{
+		Name:      "bmcbutler." + r.Action,
+		Tests:     r.Assets,
+		Failures:  r.Failed,
+		Skipped:   r.Skipped,
+		Time:      r.End.Sub(r.Start).Seconds(),
+		Timestamp: r.Start.UTC().Format(time.RFC3339),
+	}
+
+	for _, a := range r.Results {
+		testCase := junitTestCase{
+			Name:      a.identifier(),
+			ClassName: "bmcbutler." + r.Action + "." + a.Location,
+			Time:      a.Duration,
+			SystemOut: a.Output,
+		}
+
+		switch {
+		case a.Skipped:
+			testCase.Skipped = &junitSkipped{Message: a.Error}
+		case !a.Success:
+			testCase.Failure = &junitFailure{Message: a.Error, Text: a.failedResources()}
+		}
+
+		// Disruptive resources deferred outside the maintenance window are not failures.
+		if skipped := a.skippedResources(); skipped != "" {
+			if testCase.SystemOut != "" {
+				testCase.SystemOut += "\n"
+			}
+			testCase.SystemOut += skipped
+		}
+
+		suite.TestCases = append(suite.TestCases, testCase)
+	}
+
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+
+	err = encoder.Encode(suite)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+// WriteSummary writes a summary table of the run, listing assets that failed or were skipped.
+func (r *Run) WriteSummary(w io.Writer) error {
+	writer := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+
+	if r.Failed+r.Skipped > 0 {
+		fmt.Fprintln(writer, "SERIAL\tIP ADDRESS\tVENDOR\tSTATUS\tRESET\tDURATION\tERROR")
+		for _, a := range r.Results {
+			if a.Success {
+				continue
+			}
+
+			status := "failed"
+			if a.Skipped {
+				status = "skipped"
+			}
+
+			fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%t\t%.1fs\t%s\n",
+				a.Serial, a.IPAddress, a.Vendor, status, a.Reset, a.Duration, a.Error)
+		}
+	}
+
+	fmt.Fprintf(writer, "%s: %d assets, %d succeeded, %d failed (%d login), %d skipped in %s.\n",
+		r.Action, r.Assets, r.Succeeded, r.Failed, r.LoginFailed, r.Skipped, r.End.Sub(r.Start).Round(time.Second))
+
+	if r.Deferred > 0 {
+		fmt.Fprintf(writer, "%d assets had disruptive resources deferred outside their maintenance window.\n", r.Deferred)
+	}
+
+	if r.Halted {
+		fmt.Fprintln(writer, "Halted, failures crossed the limits or a rollout stage failed, no new assets were dispatched.")
+	}
+
+	return writer.Flush()
+}
+
+// identifier returns the asset serial, or its IP address for assets without a serial.
+func (a *AssetResult) identifier() string {
+	if a.Serial != "" {
+		return a.Serial
+	}
+
+	return a.IPAddress
+}
+
+// failedResources returns the resources that failed to apply, one per line with their error.
+func (a *AssetResult) failedResources() string {
+	var failed []string
+	for _, r := range a.Resources {
+		if !r.Success {
+			failed = append(failed, r.Resource+": "+r.Error)
+		}
+	}
+
+	return strings.Join(failed, "\n")
+}
+
+// skippedResources returns the resources that were not applied, one per line with the reason.
+func (a *AssetResult) skippedResources() string {
+	var skipped []string
+	for _, r := range a.Resources {
+		if r.Skipped {
+			skipped = append(skipped, r.Resource+" skipped: "+r.Reason)
+		}
+	}
+
+	return strings.Join(skipped, "\n")
+}
@@ -0,0 +1,230 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bmc-toolbox/bmcbutler/pkg/asset"
+	"github.com/bmc-toolbox/bmcbutler/pkg/butler"
+	"github.com/bmc-toolbox/bmcbutler/pkg/butler/configure"
+)
+
+// testRun returns the run report of a configure run with a succeeded, a failed and a skipped asset,
+// the succeeded asset had a disruptive resource deferred.
+func testRun(t *testing.T) *Run {
+	start := time.Now()
+	resultChan := make(chan butler.Result)
+	collector := Collect(asset.ActionConfigure, resultChan, Limits{}, nil)
+
+	resultChan <- butler.Result{
+		Asset:   asset.Asset{Serial: "FOO", IPAddress: "192.0.2.1", Vendor: "dell", Location: "ams2"},
+		Success: true,
+		User:    "Administrator",
+		Resources: []configure.ResourceResult{
+			{Resource: "ntp", Success: true},
+			{Resource: "bios", Skipped: true, Reason: "outside the ams2 maintenance window"},
+		},
+		Start: start,
+		End:   start.Add(2 * time.Second),
+	}
+
+	resultChan <- butler.Result{
+		Asset: asset.Asset{Serial: "BAR", IPAddress: "192.0.2.2", Vendor: "hp", Location: "ams2"},
+		Error: "resources failed to apply: syslog",
+		User:  "Administrator",
+		Resources: []configure.ResourceResult{
+			{Resource: "ntp", Success: true},
+			{Resource: "syslog", Error: "connection reset"},
+		},
+		Reset: true,
+		Start: start,
+		End:   start.Add(3 * time.Second),
+	}
+
+	resultChan <- butler.Result{
+		Asset:   asset.Asset{IPAddresses: []string{"192.0.2.3"}},
+		Skipped: true,
+		Error:   butler.ErrInterrupted.Error(),
+	}
+
+	close(resultChan)
+
+	run := collector.Wait()
+	if run.Assets != 3 || run.Succeeded != 1 || run.Failed != 1 || run.Skipped != 1 || run.Deferred != 1 {
+		t.Fatalf("Unexpected run counts: %+v", run)
+	}
+
+	return run
+}
+
+// Test the JSON report holds the outcome of each asset.
+func TestWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	err := testRun(t).WriteJSON(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var run Run
+	err = json.Unmarshal(buf.Bytes(), &run)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	failed := run.Results[1]
+	if failed.Serial != "BAR" || failed.User != "Administrator" || !failed.Reset || failed.Duration != 3 ||
+		len(failed.Resources) != 2 || failed.Resources[1].Error != "connection reset" {
+		t.Fatalf("Unexpected failed asset result: %+v", failed)
+	}
+
+	if run.Results[2].IPAddress != "192.0.2.3" {
+		t.Fatalf("Expected the inventory IP address for assets not logged into, got %q", run.Results[2].IPAddress)
+	}
+}
+
+// Test the JUnit report has a test case per asset, with failed resources listed in the failure.
+func TestWriteJUnit(t *testing.T) {
+	var buf bytes.Buffer
+	err := testRun(t).WriteJUnit(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var suite junitTestSuite
+	err = xml.Unmarshal(buf.Bytes(), &suite)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if suite.Name != "bmcbutler.configure" || suite.Tests != 3 || suite.Failures != 1 || suite.Skipped != 1 || len(suite.TestCases) != 3 {
+		t.Fatalf("Unexpected test suite: %+v", suite)
+	}
+
+	failed := suite.TestCases[1]
+	if failed.Name != "BAR" || failed.Failure == nil || failed.Failure.Text != "syslog: connection reset" {
+		t.Fatalf("Unexpected failed test case: %+v", failed)
+	}
+
+	if suite.TestCases[0].Failure != nil || suite.TestCases[2].Skipped == nil || suite.TestCases[2].Name != "192.0.2.3" {
+		t.Fatalf("Unexpected test cases: %+v", suite.TestCases)
+	}
+
+	if suite.TestCases[0].SystemOut != "bios skipped: outside the ams2 maintenance window" {
+		t.Fatalf("Expected the deferred resource listed, got %q", suite.TestCases[0].SystemOut)
+	}
+}
+
+// Test the summary lists assets that didn't succeed, along with the run counts.
+func TestWriteSummary(t *testing.T) {
+	var buf bytes.Buffer
+	err := testRun(t).WriteSummary(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	summary := buf.String()
+	if strings.Contains(summary, "FOO") || !strings.Contains(summary, "BAR") || !strings.Contains(summary, "skipped") {
+		t.Fatalf("Expected failed and skipped assets listed, got:\n%s", summary)
+	}
+
+	if !strings.Contains(summary, "configure: 3 assets, 1 succeeded, 1 failed (0 login), 1 skipped") {
+		t.Fatalf("Expected the run counts, got:\n%s", summary)
+	}
+}
+
+// Test the exit code tells apart runs that succeeded, failed, failed to login only, or were aborted.
+func TestExitCode(t *testing.T) {
+	cases := []struct {
+		run         Run
+		interrupted bool
+		code        int
+	}{
+		{Run{Succeeded: 2, Skipped: 1}, false, ExitSuccess},
+		{Run{Succeeded: 1, Failed: 2, LoginFailed: 1}, false, ExitFailed},
+		{Run{Succeeded: 1, Failed: 2, LoginFailed: 2}, false, ExitLoginFailed},
+		{Run{Succeeded: 1, Failed: 2, Halted: true}, false, ExitHalted},
+		{Run{Succeeded: 1, Failed: 2, Halted: true}, true, ExitInterrupted},
+	}
+
+	for _, c := range cases {
+		if code := c.run.ExitCode(c.interrupted); code != c.code {
+			t.Errorf("Expected exit code %d for run %+v, interrupted: %t, got %d", c.code, c.run, c.interrupted, code)
+		}
+	}
+}
+
+// Test the run is halted once failures cross the limits.
+func TestCollectLimits(t *testing.T) {
+	cases := []struct {
+		limits    Limits
+		succeeded int
+		failed    int
+		halted    bool
+	}{
+		{Limits{}, 0, 20, false},
+		{Limits{MaxFailures: 2}, 5, 2, false},
+		{Limits{MaxFailures: 2}, 5, 3, true},
+		{Limits{MaxFailureRatio: 0.5}, 0, 9, false}, // The ratio is not enforced before 10 assets are done.
+		{Limits{MaxFailureRatio: 0.5}, 5, 5, false},
+		{Limits{MaxFailureRatio: 0.5}, 4, 6, true},
+	}
+
+	for _, c := range cases {
+		resultChan := make(chan butler.Result)
+		collector := Collect(asset.ActionExecute, resultChan, c.limits, nil)
+
+		for i := 0; i < c.succeeded; i++ {
+			resultChan <- butler.Result{Success: true}
+		}
+
+		for i := 0; i < c.failed; i++ {
+			resultChan <- butler.Result{Error: "login failed", LoginFailed: true}
+		}
+
+		close(resultChan)
+		run := collector.Wait()
+
+		var halted bool
+		select {
+		case <-collector.Halt():
+			halted = true
+		default:
+		}
+
+		if run.Halted != c.halted || halted != c.halted {
+			t.Errorf("Expected halted: %t with limits %+v, %d succeeded, %d failed", c.halted, c.limits, c.succeeded, c.failed)
+		}
+	}
+}
+
+// Test results of a stage are waited for, and a stopped run is halted.
+func TestWaitResults(t *testing.T) {
+	resultChan := make(chan butler.Result)
+	collector := Collect(asset.ActionConfigure, resultChan, Limits{}, nil)
+
+	go func() {
+		resultChan <- butler.Result{Asset: asset.Asset{Serial: "FOO"}, Success: true}
+		resultChan <- butler.Result{Asset: asset.Asset{Serial: "BAR"}, Error: "login failed"}
+	}()
+
+	results := collector.WaitResults(0, 2, nil)
+	if len(results) != 2 || results[1].Serial != "BAR" {
+		t.Fatalf("Expected the results of the stage, got: %+v", results)
+	}
+
+	collector.Stop()
+	collector.Stop()
+
+	close(resultChan)
+	if run := collector.Wait(); !run.Halted {
+		t.Fatal("Expected a stopped run halted.")
+	}
+
+	if results = collector.WaitResults(2, 3, nil); len(results) != 0 {
+		t.Fatalf("Expected no results once the result channel is closed, got: %+v", results)
+	}
+}
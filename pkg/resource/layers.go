@@ -0,0 +1,290 @@
+package resource
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/bmc-toolbox/bmcbutler/pkg/asset"
+
+	"github.com/bmc-toolbox/bmclib/cfgresources"
+)
+
+// The files of the BMC configuration hierarchy under bmcCfgDir, in order of precedence, lowest first,
+// configuration.yml is kept as the base layer of the hierarchy.
+const (
+	BaseFile     = "configuration.yml"
+	CommonFile   = "common.yml"
+	LocationDir  = "location" // location/<location>.yml
+	VendorDir    = "vendor"   // vendor/<vendor>.yml
+	HardwareDir  = "hardware" // hardware/<hardwareType>.yml
+	SerialDir    = "serial"   // serial/<serial>.yml
+	layerFileExt = ".yml"
+)
+
+// overlayDirs are the overlay directories, in order of precedence, lowest first.
+var overlayDirs = []string{LocationDir, VendorDir, HardwareDir, SerialDir}
+
+// Layer is a template of the BMC configuration hierarchy.
+type Layer struct {
+	File     string // The path of the template, relative to bmcCfgDir, e.g vendor/dell.yml
+	Template []byte
+}
+
+// Layers is the BMC configuration hierarchy read from bmcCfgDir,
+// the layers applicable to an asset are deep merged in order of precedence.
+type Layers struct {
+	Dir    string
+	layers map[string]Layer // By lower cased path relative to Dir.
+	single []Layer          // If declared, the only layer, for any asset.
+}
+
+// ReadLayers reads the BMC configuration hierarchy under dir,
+// overlays are keyed by their lower cased file name, as asset attributes are matched lower cased.
+// An error is returned if neither configuration.yml nor common.yml exist,
+// or if overlay file names only differ by case.
+func ReadLayers(dir string) (*Layers, error) {
+	l := &Layers{Dir: dir, layers: make(map[string]Layer)}
+
+	for _, file := range []string{BaseFile, CommonFile} {
+		template, err := ReadYamlTemplate(filepath.Join(dir, file))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		l.layers[file] = Layer{File: file, Template: template}
+	}
+
+	if len(l.layers) == 0 {
+		return nil, fmt.Errorf("no BMC configuration found in %s, expected %s and/or %s", dir, BaseFile, CommonFile)
+	}
+
+	for _, overlay := range overlayDirs {
+		files, err := filepath.Glob(filepath.Join(dir, overlay, "*"+layerFileExt))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, file := range files {
+			template, err := ReadYamlTemplate(file)
+			if err != nil {
+				return nil, err
+			}
+
+			name := overlay + "/" + filepath.Base(file)
+			key := strings.ToLower(name)
+			if existing, exists := l.layers[key]; exists {
+				return nil, fmt.Errorf("overlays %s and %s apply to the same assets, file names are matched case insensitively", existing.File, name)
+			}
+
+			l.layers[key] = Layer{File: name, Template: template}
+		}
+	}
+
+	return l, nil
+}
+
+// NewLayers returns a hierarchy of a single template, e.g a template passed on the command line.
+func NewLayers(file string, template []byte) *Layers {
+	name := filepath.Base(file)
+
+	layer := Layer{File: name, Template: template}
+
+	return &Layers{
+		Dir:    filepath.Dir(file),
+		layers: map[string]Layer{strings.ToLower(name): layer},
+		single: []Layer{layer},
+	}
+}
+
+// Files returns the paths of the templates in the hierarchy as named on disk, relative to Dir, sorted.
+func (l *Layers) Files() []string {
+	files := make([]string, 0, len(l.layers))
+	for _, layer := range l.layers {
+		files = append(files, layer.File)
+	}
+	sort.Strings(files)
+
+	return files
+}
+
+// Template returns the template at the path relative to Dir, matched case insensitively.
+func (l *Layers) Template(file string) []byte {
+	return l.layers[strings.ToLower(file)].Template
+}
+
+// For returns the layers applicable to the asset, in order of precedence, lowest first,
+// overlays are matched on the lower cased asset location, vendor, hardware type and serial.
+func (l *Layers) For(a *asset.Asset) (layers []Layer) {
+	if l.single != nil {
+		return l.single
+	}
+
+	files := []string{BaseFile, CommonFile}
+
+	for i, attribute := range []string{a.Location, a.Vendor, a.HardwareType, a.Serial} {
+		if attribute != "" {
+			files = append(files, overlayDirs[i]+"/"+strings.ToLower(attribute)+layerFileExt)
+		}
+	}
+
+	for _, file := range files {
+		if layer, exists := l.layers[file]; exists {
+			layers = append(layers, layer)
+		}
+	}
+
+	return layers
+}
+
+// Hash returns the sha256 of the layers applicable to the asset.
+func (l *Layers) Hash(a *asset.Asset) string {
+	h := sha256.New()
+	for _, layer := range l.For(a) {
+		fmt.Fprintf(h, "%s\n%d\n", layer.File, len(layer.Template))
+		h.Write(layer.Template)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// MergeLayers renders the layers applicable to the asset, and deep merges them in order of precedence,
+// maps are merged key by key, other values (lists, scalars) of a higher layer replace the lower one,
+// a null value removes the key. It returns the merged yml, and the file each value of it came from, by path (e.g ntp.server1).
+func (r *Resource) MergeLayers(l *Layers) (yamlData []byte, sources map[string]string, err error) {
+	sources = make(map[string]string)
+	layers := l.For(r.Asset)
+
+	var merged yaml.MapSlice
+	for _, layer := range layers {
+		rendered, err := r.RenderYamlTemplate(layer.Template)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %s", layer.File, err)
+		}
+
+		var values yaml.MapSlice
+		err = yaml.Unmarshal(rendered, &values)
+
+		// A single layer is used as rendered, as configuration.yml was before overlays,
+		// yml errors are left to be reported once unmarshalled into config resources.
+		if len(layers) == 1 {
+			if err == nil {
+				mergeValues(nil, values, "", layer.File, sources)
+			}
+			return rendered, sources, nil
+		}
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: unable to unmarshal rendered template: %s", layer.File, err)
+		}
+
+		merged = mergeValues(merged, values, "", layer.File, sources)
+	}
+
+	// Nothing is declared, as with an empty configuration.yml.
+	if len(merged) == 0 {
+		return []byte{}, sources, nil
+	}
+
+	yamlData, err = yaml.Marshal(merged)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return yamlData, sources, nil
+}
+
+// LoadLayers gets the layers applicable to the asset rendered, merged, and unmarshals the resulting yml.
+func (r *Resource) LoadLayers(l *Layers) (config *cfgresources.ResourcesConfig, err error) {
+	yamlData, _, err := r.MergeLayers(l)
+	if err != nil {
+		return nil, err
+	}
+
+	err = yaml.Unmarshal(yamlData, &config)
+	if err != nil {
+		return nil, fmt.Errorf("unable to unmarshal config resources template: %s", err)
+	}
+
+	return config, nil
+}
+
+// mergeValues deep merges the overlay values into the base values, recording the file values came from.
+func mergeValues(base yaml.MapSlice, overlay yaml.MapSlice, path string, file string, sources map[string]string) yaml.MapSlice {
+	for _, item := range overlay {
+		key := joinPath(path, item.Key)
+
+		idx := -1
+		for i := range base {
+			if base[i].Key == item.Key {
+				idx = i
+				break
+			}
+		}
+
+		if item.Value == nil {
+			if idx >= 0 {
+				base = append(base[:idx], base[idx+1:]...)
+			}
+			forgetSources(sources, key)
+			continue
+		}
+
+		if values, ok := item.Value.(yaml.MapSlice); ok && idx >= 0 {
+			if baseValues, ok := base[idx].Value.(yaml.MapSlice); ok {
+				base[idx].Value = mergeValues(baseValues, values, key, file, sources)
+				continue
+			}
+		}
+
+		forgetSources(sources, key)
+		recordSources(sources, item.Value, key, file)
+
+		if idx >= 0 {
+			base[idx].Value = item.Value
+		} else {
+			base = append(base, item)
+		}
+	}
+
+	return base
+}
+
+// recordSources records the file of the value at the path, and of the values nested in it.
+func recordSources(sources map[string]string, value interface{}, path string, file string) {
+	values, ok := value.(yaml.MapSlice)
+	if !ok || len(values) == 0 {
+		sources[path] = file
+		return
+	}
+
+	for _, item := range values {
+		recordSources(sources, item.Value, joinPath(path, item.Key), file)
+	}
+}
+
+// forgetSources removes the sources of the value at the path, and of the values nested in it.
+func forgetSources(sources map[string]string, path string) {
+	for p := range sources {
+		if p == path || strings.HasPrefix(p, path+".") {
+			delete(sources, p)
+		}
+	}
+}
+
+func joinPath(path string, key interface{}) string {
+	if path == "" {
+		return fmt.Sprint(key)
+	}
+
+	return path + "." + fmt.Sprint(key)
+}
@@ -0,0 +1,126 @@
+package resource
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/bmc-toolbox/bmcbutler/pkg/asset"
+)
+
+// Test the layers applicable to the asset are deep merged in order of precedence, with the file each value came from,
+// overlays are matched case insensitively.
+func TestMergeLayers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "layers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"configuration.yml": "ntp:\n  enable: true\n  server1: ntp0.example.com\n  timezone: UTC\nsyslog:\n  server: syslog.example.com\n",
+		"common.yml":        "ldap:\n  enable: true\n",
+		"location/ams4.yml": "ntp:\n  server1: ntp0.<%= location %>.example.com\n",
+		"vendor/Dell.yml":   "syslog: ~\nntp:\n  timezone: CET\n",
+		"vendor/hp.yml":     "ntp:\n  timezone: PST\n",
+		"serial/abc123.yml": "ldap:\n  enable: false\n",
+		"vendor/notes.txt":  "not a layer",
+	}
+
+	for name, content := range files {
+		err = os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	layers, err := ReadLayers(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := &asset.Asset{Serial: "ABC123", Vendor: "Dell", Location: "ams4"}
+	r := Resource{Log: logrus.New(), Asset: a}
+
+	merged, sources, err := r.MergeLayers(layers)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "ntp:\n  enable: true\n  server1: ntp0.ams4.example.com\n  timezone: CET\nldap:\n  enable: false\n"
+	if string(merged) != expected {
+		t.Fatalf("Expected merged configuration:\n%s\ngot:\n%s", expected, merged)
+	}
+
+	expectedSources := map[string]string{
+		"ntp.enable":   "configuration.yml",
+		"ntp.server1":  "location/ams4.yml",
+		"ntp.timezone": "vendor/Dell.yml",
+		"ldap.enable":  "serial/abc123.yml",
+	}
+	if len(sources) != len(expectedSources) {
+		t.Fatalf("Expected sources %v, got %v", expectedSources, sources)
+	}
+	for path, file := range expectedSources {
+		if sources[path] != file {
+			t.Fatalf("Expected sources %v, got %v", expectedSources, sources)
+		}
+	}
+
+	config, err := r.LoadLayers(layers)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if config.Ntp == nil || config.Ntp.Timezone != "CET" || config.Syslog != nil || config.Ldap == nil || config.Ldap.Enable {
+		t.Fatalf("Expected the merged configuration resources, got %+v", config)
+	}
+
+	if layers.Hash(a) == layers.Hash(&asset.Asset{Serial: "ABC123", Vendor: "HP", Location: "ams4"}) {
+		t.Fatal("Expected the hash to differ for assets with different layers")
+	}
+
+	err = ioutil.WriteFile(filepath.Join(dir, "vendor/dell.yml"), []byte("ntp:\n  timezone: UTC\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = ReadLayers(dir)
+	if err == nil {
+		t.Fatal("Expected an error for overlay file names that only differ by case")
+	}
+}
+
+// Test a single template is used as rendered, and an error is returned if no base configuration is found.
+func TestSingleLayer(t *testing.T) {
+	template := []byte("# comments are kept\nntp:\n  server1: ntp0.<%= location %>.example.com\n")
+	r := Resource{Log: logrus.New(), Asset: &asset.Asset{Vendor: "Dell", Location: "ams4"}}
+
+	merged, sources, err := r.MergeLayers(NewLayers("/tmp/test.yml", template))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(merged) != "# comments are kept\nntp:\n  server1: ntp0.ams4.example.com\n" || sources["ntp.server1"] != "test.yml" {
+		t.Fatalf("Expected the template as rendered, got %s %v", merged, sources)
+	}
+
+	dir, err := ioutil.TempDir("", "layers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	_, err = ReadLayers(dir)
+	if err == nil {
+		t.Fatal("Expected an error without configuration.yml or common.yml")
+	}
+}
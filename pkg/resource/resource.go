@@ -15,6 +15,7 @@
 package resource
 
 import (
+	"fmt"
 	"io/ioutil"
 	"os"
 	"strings"
@@ -25,6 +26,7 @@ import (
 
 	"github.com/bmc-toolbox/bmcbutler/pkg/asset"
 	"github.com/bmc-toolbox/bmcbutler/pkg/secrets"
+	"github.com/bmc-toolbox/bmcbutler/pkg/tracing"
 
 	"github.com/bmc-toolbox/bmclib/cfgresources"
 )
@@ -35,6 +37,14 @@ type Resource struct {
 	Log     *logrus.Logger
 	Asset   *asset.Asset
 	Secrets *secrets.Store
+	// RedactSecrets renders lookup_secret values as placeholders,
+	// when secrets are not loaded from vault.
+	RedactSecrets bool
+	// Span is the span of the action on the asset, rendering is traced as its child,
+	// nil unless tracing is declared.
+	Span *tracing.Span
+	// secret keys looked up while rendering the template.
+	lookups []string
 }
 
 // ReadYamlTemplate reads the given config .yml file, returns it as a slice of bytes.
@@ -55,9 +65,9 @@ func ReadYamlTemplate(yamlFile string) (yamlTemplate []byte, err error) {
 }
 
 // RenderYamlTemplate renders templated values in the given config .yml, returns it as a slice of bytes.
-func (r *Resource) RenderYamlTemplate(yamlTemplate []byte) (yamlData []byte) {
-	log := r.Log
-	component := "RenderYamlTemplate"
+func (r *Resource) RenderYamlTemplate(yamlTemplate []byte) (yamlData []byte, err error) {
+	span := r.Span.Child("render_template")
+	defer func() { span.End(err) }()
 
 	// Rendering templated data.
 	ctx := plush.NewContext()
@@ -72,39 +82,40 @@ func (r *Resource) RenderYamlTemplate(yamlTemplate []byte) (yamlData []byte) {
 	ctx.Set("extra", r.Asset.Extra)
 
 	// r.Secrets is non nil if the bmcbutler.yml declares secretsFromVault: true.
-	if r.Secrets != nil {
+	switch {
+	case r.Secrets != nil:
 		ctx.Set("lookup_secret", func(s string) string {
-			secret, _ := r.Secrets.Get(s)
+			r.lookups = append(r.lookups, s)
+			secret, _ := r.Secrets.GetForAsset(r.Asset.Serial, s)
 			return secret
 		})
+	case r.RedactSecrets:
+		ctx.Set("lookup_secret", func(s string) string {
+			r.lookups = append(r.lookups, s)
+			return fmt.Sprintf("redacted:%s", s)
+		})
 	}
 
 	// Render, plush is awesome!
 	s, err := plush.Render(string(yamlTemplate), ctx)
 	if err != nil {
-		log.WithFields(logrus.Fields{
-			"component": component,
-			"Error":     err,
-		}).Fatal("Error rendering configuration yml template.")
+		return []byte{}, fmt.Errorf("error rendering configuration yml template: %s", err)
 	}
 
-	return []byte(s)
+	return []byte(s), nil
 }
 
 // LoadConfigResources gets the template rendered and unmarshals the resulting yml.
-func (r *Resource) LoadConfigResources(yamlTemplate []byte) (config *cfgresources.ResourcesConfig) {
-	component := "LoadConfigResources"
-	log := r.Log
-
-	yamlData := r.RenderYamlTemplate(yamlTemplate)
+func (r *Resource) LoadConfigResources(yamlTemplate []byte) (config *cfgresources.ResourcesConfig, err error) {
+	yamlData, err := r.RenderYamlTemplate(yamlTemplate)
+	if err != nil {
+		return nil, err
+	}
 
-	err := yaml.Unmarshal(yamlData, &config)
+	err = yaml.Unmarshal(yamlData, &config)
 	if err != nil {
-		log.WithFields(logrus.Fields{
-			"component": component,
-			"Error":     err,
-		}).Fatal("Unable to Unmarshal config resources template.")
+		return nil, fmt.Errorf("unable to unmarshal config resources template: %s", err)
 	}
 
-	return config
+	return config, nil
 }
@@ -38,6 +38,8 @@ func TestRenderYamlTemplate(t *testing.T) {
 			HardwareType: "002",
 			Type:         "Server",
 		},
+		// The sample configuration looks up secrets from vault.
+		RedactSecrets: true,
 	}
 
 	resourceConfig := "../../samples/cfg/configuration.yml"
@@ -49,7 +51,11 @@ func TestRenderYamlTemplate(t *testing.T) {
 	}
 
 	// render as plush template
-	rendered := r.RenderYamlTemplate(configBytes)
+	rendered, err := r.RenderYamlTemplate(configBytes)
+	if err != nil {
+		t.Fatalf("Error rendering config template: %s", err)
+	}
+
 	if !strings.Contains(string(rendered), "cn=acme,cn=bmcUsers") {
 		t.Fatal("Expected string not found in rendered template")
 	}
@@ -73,9 +79,15 @@ func TestLoadConfigResources(t *testing.T) {
 			HardwareType: "002",
 			Type:         "Server",
 		},
+		// The sample configuration looks up secrets from vault.
+		RedactSecrets: true,
+	}
+
+	configResources, err := r.LoadConfigResources(configBytes)
+	if err != nil {
+		t.Fatalf("Error loading config resources: %s", err)
 	}
 
-	configResources := r.LoadConfigResources(configBytes)
 	if fmt.Sprintf("%T", configResources) != "*cfgresources.ResourcesConfig" {
 		t.Fatal("Expected return type does not match *cfgresources.ResourcesConfig")
 	}
@@ -84,3 +96,62 @@ func TestLoadConfigResources(t *testing.T) {
 		t.Fatal("Expected string not found in LdapGroup config resource")
 	}
 }
+
+// Test lookup_secret values are rendered as placeholders when secrets are redacted.
+func TestRenderYamlTemplateRedactSecrets(t *testing.T) {
+	r := Resource{
+		Log:           logrus.New(),
+		Asset:         &asset.Asset{Serial: "FOOBAR", Vendor: "ACME"},
+		RedactSecrets: true,
+	}
+
+	rendered, err := r.RenderYamlTemplate([]byte(`password: <%= lookup_secret("Administrator") %>`))
+	if err != nil {
+		t.Fatalf("Error rendering config template: %s", err)
+	}
+
+	if string(rendered) != "password: redacted:Administrator" {
+		t.Fatalf("Expected secret placeholder in rendered template, got: %s", rendered)
+	}
+}
+
+// Test template errors are returned to the caller.
+func TestLoadConfigResourcesErrors(t *testing.T) {
+	r := Resource{
+		Log:   logrus.New(),
+		Asset: &asset.Asset{Serial: "FOOBAR", Vendor: "ACME"},
+	}
+
+	if _, err := r.LoadConfigResources([]byte(`<%= if ( vendor == "acme" { %>`)); err == nil {
+		t.Fatal("Expected template error, got nil")
+	}
+
+	if _, err := r.LoadConfigResources([]byte("ldapGroups:\n  - groups: []\n")); err == nil {
+		t.Fatal("Expected unmarshal error, got nil")
+	}
+}
+
+// Test unknown keys in the rendered template are reported with the template line, along with the rendered line.
+func TestValidateConfigResources(t *testing.T) {
+	r := Resource{
+		Log:           logrus.New(),
+		Asset:         &asset.Asset{Serial: "FOOBAR", Vendor: "ACME"},
+		RedactSecrets: true,
+	}
+
+	template := []byte("<%= if (vendor == \"acme\") { %>\n# acme\n<% } %>\nsyslog:\n  sever: <%= vendor %>.example.com\nuser:\n  - name: foo\n    password: <%= lookup_secret(\"foo\") %>\n")
+
+	_, lookups, errs := r.ValidateConfigResources(template)
+	if len(errs) != 1 {
+		t.Fatalf("Expected 1 error, got %d: %v", len(errs), errs)
+	}
+
+	expected := `line 5: field sever not found in type cfgresources.Syslog (rendered line 5: "sever: acme.example.com")`
+	if errs[0].Error() != expected {
+		t.Errorf("Unexpected error, got: %s, want: %s", errs[0], expected)
+	}
+
+	if len(lookups) != 1 || lookups[0] != "foo" {
+		t.Errorf("Expected lookup_secret key foo to be recorded, got: %v", lookups)
+	}
+}
@@ -0,0 +1,121 @@
+package resource
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bmc-toolbox/bmclib/cfgresources"
+
+	"github.com/bmc-toolbox/bmcbutler/pkg/config"
+)
+
+// ValidateConfigResources renders the configuration template for the asset and strictly decodes it,
+// returning the configuration resources, the secrets looked up by the template and any problems found.
+// Line numbers in the returned errors are mapped back to the template where possible,
+// the offending rendered line is included.
+func (r *Resource) ValidateConfigResources(yamlTemplate []byte) (resources *cfgresources.ResourcesConfig, lookups []string, errs []error) {
+	r.lookups = []string{}
+
+	yamlData, err := r.RenderYamlTemplate(yamlTemplate)
+	if err != nil {
+		return nil, r.lookups, []error{err}
+	}
+
+	renderedLines := strings.Split(string(yamlData), "\n")
+	templateLines := strings.Split(string(yamlTemplate), "\n")
+
+	resources = &cfgresources.ResourcesConfig{}
+	for _, err := range config.DecodeStrict(yamlData, resources) {
+		errs = append(errs, withSourceLine(err, renderedLines, templateLines))
+	}
+
+	// Secrets the template looks up must exist in the secret store,
+	// they are looked up as rendering does, secrets unique to the asset taking precedence.
+	if r.Secrets != nil {
+		for _, lookup := range r.lookups {
+			if _, err := r.Secrets.GetForAsset(r.Asset.Serial, lookup); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	return resources, r.lookups, errs
+}
+
+// withSourceLine rewrites the line number of yaml errors ('line N:') to the template line,
+// and appends the rendered line the error refers to. Errors that can't be mapped back
+// to the template are reported with the rendered line number.
+func withSourceLine(err error, renderedLines, templateLines []string) error {
+	msg := err.Error()
+	if !strings.HasPrefix(msg, "line ") {
+		return err
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(msg, "line "), ":", 2)
+	n, convErr := strconv.Atoi(parts[0])
+	if convErr != nil || n < 1 || n > len(renderedLines) || len(parts) != 2 {
+		return err
+	}
+
+	rendered := strings.TrimSpace(renderedLines[n-1])
+
+	source := sourceLine(rendered, n, templateLines)
+	if source == 0 {
+		return fmt.Errorf("rendered line %d:%s (rendered: %q)", n, parts[1], rendered)
+	}
+
+	return fmt.Errorf("line %d:%s (rendered line %d: %q)", source, parts[1], n, rendered)
+}
+
+// sourceLine returns the template line number the rendered line most likely originates from,
+// lines are matched verbatim, or by their yaml key if the value is templated.
+// The match closest to the rendered line number wins, 0 is returned if none match.
+func sourceLine(rendered string, n int, templateLines []string) int {
+	key := yamlKey(rendered)
+
+	var match, verbatim int
+	closer := func(a, b int) bool {
+		return b == 0 || abs(a-n) < abs(b-n)
+	}
+
+	for i, line := range templateLines {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == rendered:
+			if closer(i+1, verbatim) {
+				verbatim = i + 1
+			}
+		case key != "" && yamlKey(line) == key:
+			if closer(i+1, match) {
+				match = i + 1
+			}
+		}
+	}
+
+	if verbatim != 0 {
+		return verbatim
+	}
+
+	return match
+}
+
+// yamlKey returns the key of a 'key: value' yaml line, empty if the key is templated or absent.
+func yamlKey(line string) string {
+	line = strings.TrimPrefix(strings.TrimSpace(line), "- ")
+
+	i := strings.Index(line, ":")
+	if i < 1 || strings.Contains(line[:i], "<%") {
+		return ""
+	}
+
+	return line[:i]
+}
+
+func abs(i int) int {
+	if i < 0 {
+		return -i
+	}
+
+	return i
+}
@@ -0,0 +1,117 @@
+package rollout
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/bmc-toolbox/bmcbutler/pkg/asset"
+)
+
+// Step is the number of assets a stage rolls out to, counted from the start of the rollout,
+// as a number of assets or a percentage of all assets.
+type Step struct {
+	Count   int
+	Percent float64
+}
+
+// Stage is a set of assets rolled out to, once the stages before it succeeded.
+type Stage struct {
+	Name   string
+	Assets []asset.Asset
+}
+
+// ParseSteps parses stage steps declared as assets counts or percentages separated by commas, e.g 1,5%,25%,100%
+func ParseSteps(spec string) ([]Step, error) {
+	var steps []Step
+	for _, s := range strings.Split(spec, ",") {
+		s = strings.TrimSpace(s)
+
+		if strings.HasSuffix(s, "%") {
+			percent, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+			if err != nil || percent <= 0 || percent > 100 {
+				return nil, fmt.Errorf("invalid stage %q, expected a percentage between 0 and 100", s)
+			}
+
+			steps = append(steps, Step{Percent: percent})
+			continue
+		}
+
+		count, err := strconv.Atoi(s)
+		if err != nil || count < 1 {
+			return nil, fmt.Errorf("invalid stage %q, expected a number of assets or a percentage", s)
+		}
+
+		steps = append(steps, Step{Count: count})
+	}
+
+	return steps, nil
+}
+
+// target returns the number of assets rolled out to once the step is done.
+func (s Step) target(total int) int {
+	if s.Count > 0 {
+		return s.Count
+	}
+
+	return int(math.Ceil(float64(total) * s.Percent / 100))
+}
+
+// ByStep splits the assets into stages, each stage rolls out to the assets up to its step,
+// stages without any assets left are dropped, assets beyond the last step are rolled out to in a last stage.
+func ByStep(assets []asset.Asset, steps []Step) (stages []Stage) {
+	var done int
+	for _, step := range steps {
+		target := step.target(len(assets))
+		if target > len(assets) {
+			target = len(assets)
+		}
+
+		if target <= done {
+			continue
+		}
+
+		stages = append(stages, Stage{Name: stepName(step), Assets: assets[done:target]})
+		done = target
+	}
+
+	if done < len(assets) {
+		stages = append(stages, Stage{Name: "100%", Assets: assets[done:]})
+	}
+
+	return stages
+}
+
+func stepName(step Step) string {
+	if step.Count > 0 {
+		return strconv.Itoa(step.Count)
+	}
+
+	return strconv.FormatFloat(step.Percent, 'f', -1, 64) + "%"
+}
+
+// ByLocation splits the assets into a stage for each of the locations, in order,
+// assets in locations not listed are returned apart.
+func ByLocation(assets []asset.Asset, locations []string) (stages []Stage, unlisted []asset.Asset) {
+	byLocation := make(map[string][]asset.Asset)
+	for _, a := range assets {
+		byLocation[a.Location] = append(byLocation[a.Location], a)
+	}
+
+	for _, l := range locations {
+		if len(byLocation[l]) > 0 {
+			stages = append(stages, Stage{Name: l, Assets: byLocation[l]})
+		}
+
+		delete(byLocation, l)
+	}
+
+	for _, a := range assets {
+		if _, left := byLocation[a.Location]; left {
+			unlisted = append(unlisted, a)
+		}
+	}
+
+	return stages, unlisted
+}
@@ -0,0 +1,67 @@
+package rollout
+
+import (
+	"testing"
+
+	"github.com/bmc-toolbox/bmcbutler/pkg/asset"
+)
+
+func testAssets(n int, location string) (assets []asset.Asset) {
+	for i := 0; i < n; i++ {
+		assets = append(assets, asset.Asset{Serial: location + string(rune('a'+i%26)), Location: location})
+	}
+
+	return assets
+}
+
+// Test stages roll out to the assets up to each step, counted from the start of the rollout.
+func TestByStep(t *testing.T) {
+	steps, err := ParseSteps("1, 5%,25%,100%")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stages := ByStep(testAssets(100, "ams4"), steps)
+
+	expected := []struct {
+		name   string
+		assets int
+	}{{"1", 1}, {"5%", 4}, {"25%", 20}, {"100%", 75}}
+
+	if len(stages) != len(expected) {
+		t.Fatalf("Expected %d stages, got %d", len(expected), len(stages))
+	}
+
+	for i, e := range expected {
+		if stages[i].Name != e.name || len(stages[i].Assets) != e.assets {
+			t.Errorf("Expected stage %d %s with %d assets, got %s with %d", i+1, e.name, e.assets, stages[i].Name, len(stages[i].Assets))
+		}
+	}
+
+	// Steps that don't add assets are dropped, assets beyond the last step get a last stage.
+	steps, _ = ParseSteps("1,5%,10")
+	stages = ByStep(testAssets(20, "ams4"), steps)
+	if len(stages) != 3 || len(stages[1].Assets) != 9 || stages[2].Name != "100%" || len(stages[2].Assets) != 10 {
+		t.Fatalf("Unexpected stages: %+v", stages)
+	}
+
+	for _, spec := range []string{"0", "1,foo", "150%", "-5%"} {
+		if _, err := ParseSteps(spec); err == nil {
+			t.Errorf("Expected an error for stages %q", spec)
+		}
+	}
+}
+
+// Test a stage is rolled out to each location in order, assets in other locations are left out.
+func TestByLocation(t *testing.T) {
+	assets := append(append(testAssets(2, "ams4"), testAssets(3, "fra4")...), testAssets(1, "lhr4")...)
+
+	stages, unlisted := ByLocation(assets, []string{"fra4", "ams4", "sin2"})
+	if len(stages) != 2 || stages[0].Name != "fra4" || len(stages[0].Assets) != 3 || stages[1].Name != "ams4" || len(stages[1].Assets) != 2 {
+		t.Fatalf("Unexpected stages: %+v", stages)
+	}
+
+	if len(unlisted) != 1 || unlisted[0].Location != "lhr4" {
+		t.Fatalf("Expected the assets in lhr4 left out, got: %+v", unlisted)
+	}
+}
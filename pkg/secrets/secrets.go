@@ -2,7 +2,9 @@ package secrets
 
 import (
 	"fmt"
+	"path"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/bmc-toolbox/bmcbutler/pkg/config"
@@ -11,24 +13,23 @@ import (
 
 // Store holds a copy of secrets from vault
 type Store struct {
-	data map[string]string
+	data  map[string]string
+	vault config.Vault
+	// The vault keys credentials were looked up with, indexed as the credentials they were set on.
+	credentialKeys []map[string]string
+	// Secrets unique to an asset, read from <vault.rotatePath>/<serial> on first use.
+	assets   map[string]map[string]string
+	assetsMu sync.Mutex
 }
 
 // Load connects to Vault and returns a secret Store populated with secrets
 func Load(c config.Vault) (*Store, error) {
-	s := &Store{data: make(map[string]string)}
-	v, err := vaultapi.NewClient(
-		&vaultapi.Config{
-			Address:    c.HostAddress,
-			Timeout:    20 * time.Second,
-			MaxRetries: 5,
-		},
-	)
+	s := &Store{data: make(map[string]string), vault: c, assets: make(map[string]map[string]string)}
+	v, err := newClient(c)
 	if err != nil {
 		return s, err
 	}
 
-	v.SetToken(c.Token)
 	secrets, err := v.Logical().Read(c.SecretsPath)
 	if err != nil {
 		return s, err
@@ -45,6 +46,54 @@ func Load(c config.Vault) (*Store, error) {
 	return s, nil
 }
 
+// Write sets the key to the given value in the secret at path,
+// other keys in the secret are kept.
+func Write(c config.Vault, path string, key string, value string) error {
+	v, err := newClient(c)
+	if err != nil {
+		return err
+	}
+
+	data := make(map[string]interface{})
+
+	secret, err := v.Logical().Read(path)
+	if err != nil {
+		return err
+	}
+
+	if secret != nil {
+		for k, v := range secret.Data {
+			data[k] = v
+		}
+	}
+
+	data[key] = value
+
+	_, err = v.Logical().Write(path, data)
+	if err != nil {
+		return fmt.Errorf("write on vault secrets path %s failed: %s", path, err)
+	}
+
+	return nil
+}
+
+func newClient(c config.Vault) (*vaultapi.Client, error) {
+	v, err := vaultapi.NewClient(
+		&vaultapi.Config{
+			Address:    c.HostAddress,
+			Timeout:    20 * time.Second,
+			MaxRetries: 5,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	v.SetToken(c.Token)
+
+	return v, nil
+}
+
 // Get retrieves a secret based on the given key
 func (s *Store) Get(k string) (string, error) {
 	value, exists := s.data[k]
@@ -79,8 +128,10 @@ func (s *Store) GetSignerToken(v string) (string, error) {
 // SetCredentials updates credentials that contain the lookup_secret keyword
 func (s *Store) SetCredentials(config []map[string]string) ([]map[string]string, error) {
 	lookupPrefix := "lookup_secret::"
+	s.credentialKeys = make([]map[string]string, len(config))
 	// config is a []map[string]string
-	for _, c := range config {
+	for i, c := range config {
+		s.credentialKeys[i] = make(map[string]string)
 		for k, v := range c {
 			if strings.HasPrefix(v, lookupPrefix) {
 
@@ -95,9 +146,130 @@ func (s *Store) SetCredentials(config []map[string]string) ([]map[string]string,
 				}
 
 				c[k] = secret
+				s.credentialKeys[i][k] = lookup
 			}
 		}
 	}
 
 	return config, nil
 }
+
+// CredentialKey returns the vault key the credential of the given user was looked up with,
+// false if none of the user credentials were looked up from vault.
+func (s *Store) CredentialKey(user string) (string, bool) {
+	for _, keys := range s.credentialKeys {
+		if key, exists := keys[user]; exists {
+			return key, true
+		}
+	}
+
+	return "", false
+}
+
+// AssetPath returns the vault path secrets unique to the asset are stored at.
+func (s *Store) AssetPath(serial string) string {
+	return path.Join(s.vault.RotatePath, serial)
+}
+
+// assetSecrets returns the secrets unique to the asset, if any.
+func (s *Store) assetSecrets(serial string) (map[string]string, error) {
+	if serial == "" {
+		return nil, nil
+	}
+
+	s.assetsMu.Lock()
+	defer s.assetsMu.Unlock()
+
+	if secrets, cached := s.assets[serial]; cached {
+		return secrets, nil
+	}
+
+	v, err := newClient(s.vault)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := v.Logical().Read(s.AssetPath(serial))
+	if err != nil {
+		return nil, err
+	}
+
+	secrets := make(map[string]string)
+	if secret != nil {
+		for k, v := range secret.Data {
+			if value, ok := v.(string); ok {
+				secrets[k] = value
+			}
+		}
+	}
+
+	s.assets[serial] = secrets
+
+	return secrets, nil
+}
+
+// GetForAsset retrieves a secret based on the given key,
+// a secret unique to the asset takes precedence over the one under vault.SecretsPath.
+func (s *Store) GetForAsset(serial string, k string) (string, error) {
+	secrets, err := s.assetSecrets(serial)
+	if err != nil {
+		return "", fmt.Errorf("unable to read secrets of asset %s: %s", serial, err)
+	}
+
+	if value, exists := secrets[k]; exists {
+		return value, nil
+	}
+
+	return s.Get(k)
+}
+
+// AssetCredentials returns the credentials to login to the asset with,
+// credentials looked up from vault are preceded by the ones unique to the asset, if any.
+func (s *Store) AssetCredentials(credentials []map[string]string, serial string) ([]map[string]string, error) {
+	secrets, err := s.assetSecrets(serial)
+	if err != nil || len(secrets) == 0 {
+		return credentials, err
+	}
+
+	var unique []map[string]string
+	for i, c := range credentials {
+		if i >= len(s.credentialKeys) {
+			break
+		}
+
+		for user := range c {
+			key, exists := s.credentialKeys[i][user]
+			if !exists {
+				continue
+			}
+
+			if value, exists := secrets[key]; exists {
+				unique = append(unique, map[string]string{user: value})
+			}
+		}
+	}
+
+	return append(unique, credentials...), nil
+}
+
+// WriteAssetSecret sets the key to the given value in the secrets unique to the asset.
+func (s *Store) WriteAssetSecret(serial string, key string, value string) error {
+	if serial == "" {
+		return fmt.Errorf("asset has no serial, secret %s can't be stored", key)
+	}
+
+	err := Write(s.vault, s.AssetPath(serial), key, value)
+	if err != nil {
+		return err
+	}
+
+	s.assetsMu.Lock()
+	defer s.assetsMu.Unlock()
+
+	if s.assets[serial] == nil {
+		s.assets[serial] = make(map[string]string)
+	}
+	s.assets[serial][key] = value
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package secrets
+
+import (
+	"reflect"
+	"testing"
+)
+
+// Test credentials unique to an asset precede the shared ones, other assets get the shared ones.
+func TestAssetCredentials(t *testing.T) {
+	s := &Store{
+		data:   map[string]string{"bmc_admin": "shared"},
+		assets: map[string]map[string]string{"FOO": {"bmc_admin": "unique"}, "BAR": {}},
+	}
+
+	credentials, err := s.SetCredentials([]map[string]string{{"Administrator": "lookup_secret::bmc_admin"}, {"root": "calvin"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, found := s.CredentialKey("Administrator")
+	if !found || key != "bmc_admin" {
+		t.Fatalf("Expected credential key bmc_admin, got %q", key)
+	}
+
+	if _, found := s.CredentialKey("root"); found {
+		t.Fatal("Expected no credential key for a credential not looked up from vault.")
+	}
+
+	unique, err := s.AssetCredentials(credentials, "FOO")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []map[string]string{{"Administrator": "unique"}, {"Administrator": "shared"}, {"root": "calvin"}}
+	if !reflect.DeepEqual(unique, expected) {
+		t.Fatalf("Expected %v, got %v", expected, unique)
+	}
+
+	shared, err := s.AssetCredentials(credentials, "BAR")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(shared, credentials) {
+		t.Fatalf("Expected the shared credentials %v, got %v", credentials, shared)
+	}
+
+	secret, err := s.GetForAsset("FOO", "bmc_admin")
+	if err != nil || secret != "unique" {
+		t.Fatalf("Expected the secret unique to the asset, got %q, %v", secret, err)
+	}
+}
@@ -0,0 +1,225 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OTLP span kind and status codes.
+const (
+	spanKindInternal = 1
+	statusCodeError  = 2
+)
+
+// The OTLP JSON encoding of spans, as sent to collectors in ExportTraceServiceRequest messages.
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+
+	otlpResource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	}
+
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+
+	otlpSpan struct {
+		TraceID           string          `json:"traceId"`
+		SpanID            string          `json:"spanId"`
+		ParentSpanID      string          `json:"parentSpanId,omitempty"`
+		Name              string          `json:"name"`
+		Kind              int             `json:"kind"`
+		StartTimeUnixNano string          `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+		Attributes        []otlpAttribute `json:"attributes,omitempty"`
+		Status            *otlpStatus     `json:"status,omitempty"`
+	}
+
+	otlpAttribute struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+
+	otlpValue struct {
+		StringValue *string  `json:"stringValue,omitempty"`
+		IntValue    *string  `json:"intValue,omitempty"` // 64 bit integers are encoded as strings.
+		DoubleValue *float64 `json:"doubleValue,omitempty"`
+		BoolValue   *bool    `json:"boolValue,omitempty"`
+	}
+
+	otlpStatus struct {
+		Code    int    `json:"code"`
+		Message string `json:"message,omitempty"`
+	}
+)
+
+// otlpEncode returns the spans encoded as an OTLP JSON ExportTraceServiceRequest.
+func otlpEncode(spans []*Span, serviceName string) ([]byte, error) {
+	encoded := make([]otlpSpan, len(spans))
+	for i, s := range spans {
+		encoded[i] = s.otlp()
+	}
+
+	request := otlpRequest{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: otlpResource{
+				Attributes: []otlpAttribute{otlpAttr("service.name", serviceName)},
+			},
+			ScopeSpans: []otlpScopeSpans{{
+				Scope: otlpScope{Name: "bmcbutler"},
+				Spans: encoded,
+			}},
+		}},
+	}
+
+	return json.Marshal(request)
+}
+
+// otlp returns the OTLP JSON encoding of the span.
+func (s *Span) otlp() otlpSpan {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	span := otlpSpan{
+		TraceID:           s.traceID,
+		SpanID:            s.spanID,
+		ParentSpanID:      s.parentID,
+		Name:              s.name,
+		Kind:              spanKindInternal,
+		StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+	}
+
+	for _, a := range s.attrs {
+		span.Attributes = append(span.Attributes, otlpAttr(a.key, a.value))
+	}
+
+	if s.err != "" {
+		span.Status = &otlpStatus{Code: statusCodeError, Message: s.err}
+	}
+
+	return span
+}
+
+// otlpAttr returns the attribute, values of types other than strings, ints, floats and bools are formatted as strings.
+func otlpAttr(key string, value interface{}) otlpAttribute {
+	var v otlpValue
+
+	switch value := value.(type) {
+	case string:
+		v.StringValue = &value
+	case int:
+		i := strconv.Itoa(value)
+		v.IntValue = &i
+	case int64:
+		i := strconv.FormatInt(value, 10)
+		v.IntValue = &i
+	case float64:
+		v.DoubleValue = &value
+	case bool:
+		v.BoolValue = &value
+	default:
+		str := fmt.Sprint(value)
+		v.StringValue = &str
+	}
+
+	return otlpAttribute{Key: key, Value: v}
+}
+
+// otlpExporter POSTs spans to an OTLP/HTTP collector, JSON encoded.
+type otlpExporter struct {
+	url         string
+	headers     map[string]string
+	serviceName string
+	client      *http.Client
+}
+
+func newOTLPExporter(endpoint string, headers map[string]string, serviceName string) *otlpExporter {
+	return &otlpExporter{
+		url:         strings.TrimSuffix(endpoint, "/") + "/v1/traces",
+		headers:     headers,
+		serviceName: serviceName,
+		client:      &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (e *otlpExporter) export(spans []*Span) error {
+	data, err := otlpEncode(spans, e.serviceName)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, e.url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected collector response status: %s", resp.Status)
+	}
+
+	return nil
+}
+
+func (e *otlpExporter) close() error {
+	return nil
+}
+
+// fileExporter appends spans to a file, each batch as an OTLP JSON line,
+// the format read by the OpenTelemetry collector otlpjsonfile receiver.
+type fileExporter struct {
+	file        *os.File
+	serviceName string
+}
+
+func newFileExporter(name string, serviceName string) (*fileExporter, error) {
+	file, err := os.OpenFile(name, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open tracing file: %s", err)
+	}
+
+	return &fileExporter{file: file, serviceName: serviceName}, nil
+}
+
+func (e *fileExporter) export(spans []*Span) error {
+	data, err := otlpEncode(spans, e.serviceName)
+	if err != nil {
+		return err
+	}
+
+	_, err = e.file.Write(append(data, '\n'))
+	return err
+}
+
+func (e *fileExporter) close() error {
+	return e.file.Close()
+}
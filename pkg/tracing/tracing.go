@@ -0,0 +1,253 @@
+package tracing
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/bmc-toolbox/bmcbutler/pkg/config"
+)
+
+const (
+	// Spans ended and not yet exported, once full further spans are dropped, so butlers never wait on the exporter.
+	queueSize = 2048
+	// Spans are exported in batches of up to this size...
+	batchSize = 512
+	// ... or once this interval elapsed since the last export.
+	flushInterval = 5 * time.Second
+	// How long Close waits for queued spans to be exported.
+	closeTimeout = 30 * time.Second
+)
+
+// exporter exports a batch of spans.
+type exporter interface {
+	export(spans []*Span) error
+	close() error
+}
+
+// Tracer starts spans, and exports them once ended.
+type Tracer struct {
+	Log         *logrus.Logger
+	serviceName string
+	exporter    exporter
+	queue       chan *Span
+	done        chan struct{}
+	mu          sync.RWMutex // Guards closed, so spans aren't queued once the queue is closed.
+	closed      bool
+}
+
+// Span is a timed operation, e.g the login to a BMC, with attributes describing it.
+type Span struct {
+	tracer   *Tracer
+	name     string
+	traceID  string
+	spanID   string
+	parentID string
+	start    time.Time
+	end      time.Time
+	mu       sync.Mutex // Guards the attributes, error and end.
+	attrs    []attribute
+	err      string
+}
+
+type attribute struct {
+	key   string
+	value interface{}
+}
+
+// New returns a tracer exporting spans as declared, nil if tracing isn't declared.
+func New(c *config.Tracing, log *logrus.Logger) (*Tracer, error) {
+	if c == nil {
+		return nil, nil
+	}
+
+	t := &Tracer{
+		Log:         log,
+		serviceName: c.ServiceName,
+		queue:       make(chan *Span, queueSize),
+		done:        make(chan struct{}),
+	}
+
+	switch c.Exporter {
+	case "otlp":
+		t.exporter = newOTLPExporter(c.Endpoint, c.Headers, t.serviceName)
+	case "file":
+		e, err := newFileExporter(c.File, t.serviceName)
+		if err != nil {
+			return nil, err
+		}
+		t.exporter = e
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter: %s", c.Exporter)
+	}
+
+	go t.run()
+
+	return t, nil
+}
+
+// Start starts a span, a child of the parent span, or the root span of a trace if parent is nil.
+func (t *Tracer) Start(parent *Span, name string) *Span {
+	if t == nil {
+		return nil
+	}
+
+	s := &Span{tracer: t, name: name, spanID: newID(8), start: time.Now()}
+	if parent != nil {
+		s.traceID = parent.traceID
+		s.parentID = parent.spanID
+	} else {
+		s.traceID = newID(16)
+	}
+
+	return s
+}
+
+// Child starts a span, a child of this span, nil if this span is nil.
+func (s *Span) Child(name string) *Span {
+	if s == nil {
+		return nil
+	}
+
+	return s.tracer.Start(s, name)
+}
+
+// SetAttribute sets an attribute of the span, values are expected to be strings, ints, floats or bools.
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, a := range s.attrs {
+		if a.key == key {
+			s.attrs[i].value = value
+			return
+		}
+	}
+
+	s.attrs = append(s.attrs, attribute{key: key, value: value})
+}
+
+// End ends the span, with an error status if err isn't nil, and queues it for export,
+// spans are exported once, further calls are ignored.
+func (s *Span) End(err error) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	if !s.end.IsZero() {
+		s.mu.Unlock()
+		return
+	}
+
+	s.end = time.Now()
+	if err != nil {
+		s.err = err.Error()
+	}
+	s.mu.Unlock()
+
+	s.tracer.enqueue(s)
+}
+
+// enqueue queues the span for export, it never blocks, spans are dropped if the queue is full.
+func (t *Tracer) enqueue(s *Span) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.closed {
+		return
+	}
+
+	select {
+	case t.queue <- s:
+	default:
+		t.Log.WithFields(logrus.Fields{"component": "tracing", "Span": s.name}).Warn("Span queue full, span dropped.")
+	}
+}
+
+// run exports queued spans in batches, until the queue is closed.
+func (t *Tracer) run() {
+	defer close(t.done)
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	var batch []*Span
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+
+		err := t.exporter.export(batch)
+		if err != nil {
+			t.Log.WithFields(logrus.Fields{
+				"component": "tracing",
+				"Spans":     len(batch),
+				"Error":     err,
+			}).Warn("Unable to export spans.")
+		}
+
+		batch = nil
+	}
+
+	for {
+		select {
+		case s, ok := <-t.queue:
+			if !ok {
+				flush()
+				return
+			}
+
+			batch = append(batch, s)
+			if len(batch) >= batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// Close stops queueing spans, and waits for queued spans to be exported, for up to 30 seconds.
+func (t *Tracer) Close() {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return
+	}
+
+	t.closed = true
+	close(t.queue)
+	t.mu.Unlock()
+
+	select {
+	case <-t.done:
+	case <-time.After(closeTimeout):
+		t.Log.WithFields(logrus.Fields{"component": "tracing"}).Warn("Spans still queued were not exported.")
+		return
+	}
+
+	err := t.exporter.close()
+	if err != nil {
+		t.Log.WithFields(logrus.Fields{"component": "tracing", "Error": err}).Warn("Unable to close span exporter.")
+	}
+}
+
+// newID returns a random ID of n bytes, hex encoded.
+func newID(n int) string {
+	id := make([]byte, n)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
@@ -0,0 +1,126 @@
+package tracing
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/bmc-toolbox/bmcbutler/pkg/config"
+)
+
+// Test spans are written to the file as OTLP JSON, children in the trace of their parent, with error status.
+func TestFileExporter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tracing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "traces.json")
+	tracer, err := New(&config.Tracing{Exporter: "file", File: file, ServiceName: "bmcbutler"}, logrus.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	run := tracer.Start(nil, "run")
+	asset := run.Child("configure")
+	asset.SetAttribute("asset.serial", "FOO")
+	asset.SetAttribute("attempt", 2)
+	login := asset.Child("bmclogin")
+	login.End(errors.New("no working credentials"))
+	asset.End(nil)
+	asset.End(errors.New("ended twice"))
+	run.End(nil)
+
+	tracer.Close()
+
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var request otlpRequest
+	err = json.Unmarshal(data, &request)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(request.ResourceSpans) != 1 || *request.ResourceSpans[0].Resource.Attributes[0].Value.StringValue != "bmcbutler" {
+		t.Fatalf("Expected the service name resource attribute, got %+v", request)
+	}
+
+	spans := request.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 3 {
+		t.Fatalf("Expected 3 spans, got %+v", spans)
+	}
+
+	l, a, r := spans[0], spans[1], spans[2]
+	if l.TraceID != r.TraceID || a.TraceID != r.TraceID || len(r.TraceID) != 32 || r.ParentSpanID != "" ||
+		a.ParentSpanID != r.SpanID || l.ParentSpanID != a.SpanID {
+		t.Fatalf("Expected spans in the trace of their parents, got %+v", spans)
+	}
+
+	if l.Status == nil || l.Status.Code != statusCodeError || l.Status.Message != "no working credentials" || a.Status != nil {
+		t.Fatalf("Expected the login span error status, got %+v", spans)
+	}
+
+	if len(a.Attributes) != 2 || *a.Attributes[0].Value.StringValue != "FOO" || *a.Attributes[1].Value.IntValue != "2" {
+		t.Fatalf("Expected the asset span attributes, got %+v", a.Attributes)
+	}
+}
+
+// Test spans are POSTed to the OTLP collector endpoint with the headers declared, and a nil tracer is a no-op.
+func TestOTLPExporter(t *testing.T) {
+	var mu sync.Mutex
+	var path, auth string
+	var received []otlpSpan
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request otlpRequest
+		err := json.NewDecoder(r.Body).Decode(&request)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		path, auth = r.URL.Path, r.Header.Get("Authorization")
+		for _, rs := range request.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				received = append(received, ss.Spans...)
+			}
+		}
+	}))
+	defer server.Close()
+
+	c := &config.Tracing{Exporter: "otlp", Endpoint: server.URL + "/", Headers: map[string]string{"authorization": "Bearer foo"}}
+	tracer, err := New(c, logrus.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tracer.Start(nil, "run").End(nil)
+	tracer.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if path != "/v1/traces" || auth != "Bearer foo" || len(received) != 1 || received[0].Name != "run" ||
+		!strings.HasPrefix(received[0].StartTimeUnixNano, "1") {
+		t.Fatalf("Expected the span sent to the collector, got %s %s %+v", path, auth, received)
+	}
+
+	var nilTracer *Tracer
+	span := nilTracer.Start(nil, "run").Child("configure")
+	span.SetAttribute("asset.serial", "FOO")
+	span.End(nil)
+	nilTracer.Close()
+}